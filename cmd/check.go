@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/lad/gerrit-code-review-mcp/handler"
+)
+
+// checkReport collects the outcome of the self-test steps run by `check`
+type checkReport struct {
+	out    io.Writer
+	failed bool
+}
+
+func (r *checkReport) ok(format string, args ...any) {
+	fmt.Fprintf(r.out, "[ OK ] %s\n", fmt.Sprintf(format, args...))
+}
+
+func (r *checkReport) warn(hint, format string, args ...any) {
+	fmt.Fprintf(r.out, "[WARN] %s\n", fmt.Sprintf(format, args...))
+	if hint != "" {
+		fmt.Fprintf(r.out, "       hint: %s\n", hint)
+	}
+}
+
+func (r *checkReport) fail(hint, format string, args ...any) {
+	r.failed = true
+	fmt.Fprintf(r.out, "[FAIL] %s\n", fmt.Sprintf(format, args...))
+	if hint != "" {
+		fmt.Fprintf(r.out, "       hint: %s\n", hint)
+	}
+}
+
+// runCheck validates the configuration against the Gerrit server and prints
+// a report with remediation hints to out. tools are checked against the
+// server's version. It returns the process exit code.
+func runCheck(ctx context.Context, out io.Writer, client *gerrit.Client, baseURL *url.URL, username, password string, tools []handler.Tool) int {
+	r := &checkReport{out: out}
+
+	// Base URL resolution
+	addrs, err := net.DefaultResolver.LookupHost(ctx, baseURL.Hostname())
+	if err != nil {
+		r.fail("check GERRIT_BASE_URL for typos and that this machine can resolve the host (VPN, proxy, /etc/hosts)",
+			"resolve %s: %v", baseURL.Hostname(), err)
+		return 1
+	}
+	r.ok("%s resolves to %s", baseURL.Hostname(), strings.Join(addrs, ", "))
+
+	// Server reachability and version
+	version, _, err := client.Config.GetVersion(ctx)
+	if err != nil {
+		r.fail("GERRIT_BASE_URL must point at the Gerrit web root (the URL you open in a browser, including any path prefix such as /r/)",
+			"get Gerrit version from %s: %v", baseURL, err)
+		return 1
+	}
+	r.ok("Gerrit version %s", version)
+	checkVersion(r, version, tools)
+
+	// Authentication
+	if username == "" {
+		r.warn("set GERRIT_USERNAME and GERRIT_PASSWORD to access private projects and use write tools",
+			"no credentials configured, using anonymous access")
+	} else {
+		checkAuthMethods(ctx, r, client, username, password)
+	}
+
+	// API visibility
+	changes, _, err := client.Changes.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions: gerrit.QueryOptions{Query: []string{"status:open"}, Limit: 1},
+	})
+	switch {
+	case err != nil:
+		r.fail("the account may lack read access; ask a Gerrit administrator to grant it",
+			"query open changes: %v", err)
+	case changes == nil || len(*changes) == 0:
+		r.warn("the account may not be able to see any project; check its group memberships",
+			"query for open changes returned no results")
+	default:
+		r.ok("change queries work (sample: %s)", (*changes)[0].Project)
+	}
+
+	// Capabilities and plugins, which need privileges that not every account has
+	if client.Authentication.HasAuth() {
+		var capabilities map[string]json.RawMessage
+		if err := getJSON(ctx, client, "accounts/self/capabilities", &capabilities); err != nil {
+			r.warn("", "list account capabilities: %v", err)
+		} else {
+			r.ok("account capabilities: %s", strings.Join(sortedKeys(capabilities), ", "))
+		}
+	}
+
+	var plugins map[string]json.RawMessage
+	if err := getJSON(ctx, client, "plugins/", &plugins); err != nil {
+		r.warn("listing plugins requires the View Plugins capability; plugin-backed features will be probed on use",
+			"list plugins: %v", err)
+	} else {
+		r.ok("installed plugins: %s", strings.Join(sortedKeys(plugins), ", "))
+	}
+
+	if r.failed {
+		return 1
+	}
+	return 0
+}
+
+// checkVersion reports the tools that need a newer Gerrit than the server's,
+// which won't be served. Tools for APIs that newer releases removed are
+// replaced by others and not reported.
+func checkVersion(r *checkReport, version string, tools []handler.Tool) {
+	caps := &handler.Capabilities{Version: version}
+	var unsupported []string
+	for _, tool := range tools {
+		if ok, reason := caps.Supports(handler.Requirement{MinVersion: tool.Requires.MinVersion}); !ok {
+			unsupported = append(unsupported, fmt.Sprintf("%s %s", tool.Tool.Name, reason))
+		}
+	}
+	if len(unsupported) > 0 {
+		r.warn("upgrade Gerrit to use them; until then the server hides them",
+			"Gerrit %s is too old for %d tools: %s", version, len(unsupported), strings.Join(unsupported, "; "))
+	}
+}
+
+// checkAuthMethods tries each authentication method on its own, reports
+// which ones the server accepts, and leaves the client on the first that works
+func checkAuthMethods(ctx context.Context, r *checkReport, client *gerrit.Client, username, password string) {
+	methods := []struct {
+		name string
+		set  func(username, password string)
+	}{
+		{"digest", client.Authentication.SetDigestAuth},
+		{"basic", client.Authentication.SetBasicAuth},
+		{"cookie", client.Authentication.SetCookieAuth},
+	}
+
+	var working []string
+	for _, m := range methods {
+		m.set(username, password)
+		ok, err := checkAuth(ctx, client)
+		switch {
+		case err != nil:
+			r.warn("", "%s auth as %s: %v", m.name, username, err)
+		case ok:
+			working = append(working, m.name)
+			r.ok("%s auth as %s", m.name, username)
+		default:
+			r.warn("", "%s auth as %s rejected", m.name, username)
+		}
+	}
+
+	if len(working) == 0 {
+		client.Authentication.ResetAuth()
+		r.fail("GERRIT_PASSWORD must be the HTTP password generated under Settings > HTTP Credentials, not your SSO or LDAP password",
+			"no authentication method accepted the credentials for %s", username)
+		return
+	}
+
+	for _, m := range methods {
+		if m.name == working[0] {
+			m.set(username, password)
+		}
+	}
+}
+
+// getJSON fetches a REST endpoint that go-gerrit has no typed method for
+func getJSON(ctx context.Context, client *gerrit.Client, path string, v any) error {
+	req, err := client.NewRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(req, v)
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/lad/gerrit-code-review-mcp/handler"
+)
+
+// fakeGerrit serves the REST endpoints `check` uses, accepting basic auth
+// as jane with password
+func fakeGerrit(t *testing.T, version, password string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if authenticated, ok := strings.CutPrefix(path, "/a/"); ok {
+			if username, got, ok := r.BasicAuth(); !ok || username != "jane" || got != password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			path = "/" + authenticated
+		}
+		body := map[string]string{
+			"/config/server/version":      strconv.Quote(version),
+			"/accounts/self":              `{"_account_id": 1000, "username": "jane"}`,
+			"/changes/":                   `[{"project": "demo"}]`,
+			"/accounts/self/capabilities": `{"createProject": true}`,
+			"/plugins/":                   `{"replication": {}}`,
+		}[path]
+		if body == "" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, ")]}'\n"+body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunCheck(t *testing.T) {
+	tools := handler.NewHandler(nil).Tools()
+	check := func(serverURL, password string) (int, string) {
+		t.Helper()
+		baseURL, _ := url.Parse(serverURL)
+		client, err := gerrit.NewClient(context.Background(), serverURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		code := runCheck(context.Background(), &out, client, baseURL, "jane", password, tools)
+		return code, out.String()
+	}
+
+	// A reachable server accepting the credentials
+	code, out := check(fakeGerrit(t, "3.9.1", "secret").URL, "secret")
+	for _, want := range []string{"[ OK ] Gerrit version 3.9.1", "[ OK ] basic auth as jane", "[ OK ] change queries work (sample: demo)", "[ OK ] installed plugins: replication"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if code != 0 || strings.Contains(out, "[FAIL]") || strings.Contains(out, "too old") {
+		t.Errorf("expected the check to pass, got %d:\n%s", code, out)
+	}
+
+	// Credentials no method accepts
+	code, out = check(fakeGerrit(t, "3.9.1", "secret").URL, "wrong")
+	if code != 1 || !strings.Contains(out, "[FAIL] no authentication method accepted the credentials for jane") {
+		t.Errorf("expected an authentication failure, got %d:\n%s", code, out)
+	}
+
+	// A server too old for some tools
+	code, out = check(fakeGerrit(t, "2.15.3", "secret").URL, "secret")
+	if code != 0 || !strings.Contains(out, "[WARN] Gerrit 2.15.3 is too old for") || !strings.Contains(out, "apply-gerrit-fix-suggestion needs Gerrit 2.16 or later") {
+		t.Errorf("expected a warning about the unsupported tools, got %d:\n%s", code, out)
+	}
+
+	// An unreachable server
+	unreachable := fakeGerrit(t, "3.9.1", "secret")
+	unreachable.Close()
+	code, out = check(unreachable.URL, "secret")
+	if code != 1 || !strings.Contains(out, "[FAIL] get Gerrit version from") {
+		t.Errorf("expected the version request to fail, got %d:\n%s", code, out)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// keyringService is the service name under which the password is looked up in the OS keyring
+const keyringService = "gerrit-code-review-mcp"
+
+// credentialHelperTimeout bounds how long an external credential helper may take
+const credentialHelperTimeout = 30 * time.Second
+
+// resolvePassword finds the Gerrit password so that it does not have to be
+// written into MCP client configuration in plain text. Sources, in order:
+//
+//   - GERRIT_PASSWORD
+//   - GERRIT_PASSWORD_FILE or GERRIT_TOKEN_FILE: a file containing the secret
+//   - GERRIT_CREDENTIAL_HELPER: a shell command printing the secret on stdout
+//   - GERRIT_KEYRING=true: the OS keyring (macOS Keychain or Secret Service)
+//
+// An empty password with no error means no source is configured.
+func resolvePassword(ctx context.Context, username string) (string, error) {
+	if password := os.Getenv("GERRIT_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	for _, env := range []string{"GERRIT_PASSWORD_FILE", "GERRIT_TOKEN_FILE"} {
+		if path := os.Getenv(env); path != "" {
+			password, err := readSecretFile(path)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", env, err)
+			}
+			return password, nil
+		}
+	}
+
+	if helper := os.Getenv("GERRIT_CREDENTIAL_HELPER"); helper != "" {
+		password, err := runCredentialHelper(ctx, helper)
+		if err != nil {
+			return "", fmt.Errorf("GERRIT_CREDENTIAL_HELPER: %w", err)
+		}
+		return password, nil
+	}
+
+	if os.Getenv("GERRIT_KEYRING") == "true" {
+		password, err := keyringPassword(ctx, username)
+		if err != nil {
+			return "", fmt.Errorf("GERRIT_KEYRING: %w", err)
+		}
+		return password, nil
+	}
+
+	return "", nil
+}
+
+// readSecretFile reads a secret from a file, ignoring a trailing newline
+func readSecretFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		log.Printf("Warning: %s is accessible by other users (mode %s), consider chmod 600", path, info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
+
+// runCredentialHelper executes a user supplied command through the shell and
+// returns the first line it prints. The helper inherits the environment, so
+// it can use GERRIT_BASE_URL and GERRIT_USERNAME to pick the right secret.
+func runCredentialHelper(ctx context.Context, helper string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialHelperTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", helper)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", helper)
+	}
+	return runSecretCommand(cmd)
+}
+
+// keyringPassword looks up the password stored for username in the OS keyring.
+// Store it beforehand with:
+//
+//	macOS: security add-generic-password -s gerrit-code-review-mcp -a <username> -w
+//	Linux: secret-tool store --label=gerrit service gerrit-code-review-mcp username <username>
+func keyringPassword(ctx context.Context, username string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialHelperTimeout)
+	defer cancel()
+
+	switch runtime.GOOS {
+	case "darwin":
+		return runSecretCommand(exec.CommandContext(ctx, "security", "find-generic-password", "-s", keyringService, "-a", username, "-w"))
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return runSecretCommand(exec.CommandContext(ctx, "secret-tool", "lookup", "service", keyringService, "username", username))
+	default:
+		return "", fmt.Errorf("OS keyring is not supported on %s, use GERRIT_CREDENTIAL_HELPER instead", runtime.GOOS)
+	}
+}
+
+func runSecretCommand(cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", cmd.Path, err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", cmd.Path, err)
+	}
+
+	secret, _, _ := strings.Cut(stdout.String(), "\n")
+	secret = strings.TrimRight(secret, "\r")
+	if secret == "" {
+		return "", fmt.Errorf("%s printed no secret", cmd.Path)
+	}
+	return secret, nil
+}
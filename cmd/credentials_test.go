@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestResolvePasswordFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GERRIT_PASSWORD", "")
+	t.Setenv("GERRIT_PASSWORD_FILE", path)
+
+	password, err := resolvePassword(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password != "s3cret" {
+		t.Fatalf("expected password from file, got %q", password)
+	}
+
+	// The plain environment variable takes precedence
+	t.Setenv("GERRIT_PASSWORD", "from-env")
+	if password, _ := resolvePassword(context.Background(), "user"); password != "from-env" {
+		t.Fatalf("expected GERRIT_PASSWORD to win, got %q", password)
+	}
+}
+
+func TestResolvePasswordFromHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper test uses a POSIX shell")
+	}
+
+	t.Setenv("GERRIT_PASSWORD", "")
+	t.Setenv("GERRIT_PASSWORD_FILE", "")
+	t.Setenv("GERRIT_TOKEN_FILE", "")
+	t.Setenv("GERRIT_CREDENTIAL_HELPER", "printf 'from-helper\\nignored\\n'")
+
+	password, err := resolvePassword(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password != "from-helper" {
+		t.Fatalf("expected first line of helper output, got %q", password)
+	}
+
+	t.Setenv("GERRIT_CREDENTIAL_HELPER", "exit 3")
+	if _, err := resolvePassword(context.Background(), "user"); err == nil {
+		t.Fatal("expected error from failing helper")
+	}
+}
@@ -2,10 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/andygrunwald/go-gerrit"
 	"github.com/lad/gerrit-code-review-mcp/handler"
@@ -18,49 +25,390 @@ func main() {
 
 	baseURL := os.Getenv("GERRIT_BASE_URL")
 	username := os.Getenv("GERRIT_USERNAME")
-	password := os.Getenv("GERRIT_PASSWORD")
 
 	if baseURL == "" {
 		log.Fatal("GERRIT_BASE_URL environment variable is required")
 	}
 
-	client, err := gerrit.NewClient(ctx, baseURL, nil)
+	parsedBaseURL, err := url.Parse(baseURL)
+	if err != nil || parsedBaseURL.Host == "" {
+		log.Fatalf("GERRIT_BASE_URL is not a valid URL: %s", baseURL)
+	}
+
+	hostAliases := splitList(os.Getenv("GERRIT_HOST_ALIASES"))
+
+	logLevel := mcp.LoggingLevelInfo
+	if level := os.Getenv("GERRIT_MCP_LOG_LEVEL"); level != "" {
+		logLevel, err = handler.ParseLogLevel(level)
+		if err != nil {
+			log.Fatalf("Invalid GERRIT_MCP_LOG_LEVEL: %v", err)
+		}
+	}
+	notifier := handler.NewNotifier(logLevel)
+
+	callTimeout := 2 * time.Minute
+	if timeout := os.Getenv("GERRIT_MCP_CALL_TIMEOUT"); timeout != "" {
+		callTimeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			log.Fatalf("Invalid GERRIT_MCP_CALL_TIMEOUT: %v", err)
+		}
+	}
+	tracker := handler.NewCallTracker(callTimeout)
+
+	maxCalls, err := intEnv("GERRIT_MCP_MAX_CONCURRENT_CALLS", 16)
 	if err != nil {
-		log.Fatalf("Failed to create Gerrit client: %v", err)
+		log.Fatal(err)
+	}
+	queueTimeout := 30 * time.Second
+	if timeout := os.Getenv("GERRIT_MCP_QUEUE_TIMEOUT"); timeout != "" {
+		queueTimeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			log.Fatalf("Invalid GERRIT_MCP_QUEUE_TIMEOUT: %v", err)
+		}
+	}
+	memoryBudget, err := intEnv("GERRIT_MCP_MEMORY_BUDGET_MB", 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	callMemoryBudget, err := intEnv("GERRIT_MCP_CALL_MEMORY_BUDGET_MB", 256)
+	if err != nil {
+		log.Fatal(err)
+	}
+	limiter := handler.NewCallLimiter(maxCalls, queueTimeout, int64(memoryBudget)<<20, int64(callMemoryBudget)<<20)
+
+	maxPatchFiles, err := intEnv("GERRIT_MCP_MAX_PATCH_FILES", handler.DefaultMaxPatchFiles)
+	if err != nil {
+		log.Fatal(err)
+	}
+	maxPatchLines, err := intEnv("GERRIT_MCP_MAX_PATCH_LINES", handler.DefaultMaxPatchLines)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	if len(username) > 0 {
-		err = setAuth(ctx, client, username, password)
+	quotaMaxWait := handler.DefaultQuotaMaxWait
+	if wait := os.Getenv("GERRIT_MCP_QUOTA_MAX_WAIT"); wait != "" {
+		quotaMaxWait, err = time.ParseDuration(wait)
 		if err != nil {
-			log.Fatalf("Could not authenticate against gerrit with user %s: %v", username, err)
+			log.Fatalf("Invalid GERRIT_MCP_QUOTA_MAX_WAIT: %v", err)
+		}
+	}
+	quota := handler.NewQuotaTracker(quotaMaxWait)
+
+	transport, err := gerritTransport()
+	if err != nil {
+		log.Fatal(err)
+	}
+	httpClient := &http.Client{Transport: notifier.Transport(quota.Transport(transport))}
+
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		password, err := resolvePassword(ctx, username)
+		if err != nil {
+			log.Fatalf("Could not read the Gerrit password: %v", err)
+		}
+		client, err := gerrit.NewClient(ctx, baseURL, httpClient)
+		if err != nil {
+			log.Fatalf("Failed to create Gerrit client: %v", err)
+		}
+		// Only the tools' requirements are looked at, so no client is needed
+		os.Exit(runCheck(ctx, os.Stdout, client, parsedBaseURL, username, password, handler.NewHandler(nil).Tools()))
+	}
+
+	anonymousFallback := os.Getenv("GERRIT_ANONYMOUS_FALLBACK") == "true"
+
+	// Connect on the first tool call rather than here, so that Gerrit being
+	// unreachable at launch doesn't take the whole server down
+	gerritAdapter := handler.NewLazyGerritClientAdapter(func(ctx context.Context) (*gerrit.Client, handler.ConnectionStatus, error) {
+		if creds, ok := handler.CredentialsFromContext(ctx); ok {
+			// Falling back to anonymous access would hide that the
+			// session's own credentials are wrong
+			return connect(ctx, baseURL, httpClient, creds.Username, creds.Password, false)
+		}
+
+		var password string
+		if len(username) > 0 {
+			var err error
+			password, err = resolvePassword(ctx, username)
+			if err != nil {
+				return nil, handler.ConnectionStatus{BaseURL: baseURL, Username: username}, fmt.Errorf("read the Gerrit password: %w", err)
+			}
+		}
+		return connect(ctx, baseURL, httpClient, username, password, anonymousFallback)
+	})
+	patchCacheSize, err := intEnv("GERRIT_MCP_PATCH_CACHE_SIZE", 64)
+	if err != nil {
+		log.Fatal(err)
+	}
+	patchCacheMB, err := intEnv("GERRIT_MCP_PATCH_CACHE_MB", 256)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var client handler.GerritClient = gerritAdapter
+	var cache *handler.CachingClient
+	if patchCacheSize > 0 {
+		cache = handler.NewCachingClient(gerritAdapter, patchCacheSize, int64(patchCacheMB)<<20)
+		client = cache
+	}
+	if window := os.Getenv("GERRIT_MCP_QUERY_COALESCE_WINDOW"); window != "" {
+		coalesceWindow, err := time.ParseDuration(window)
+		if err != nil {
+			log.Fatalf("Invalid GERRIT_MCP_QUERY_COALESCE_WINDOW: %v", err)
+		}
+		if coalesceWindow > 0 {
+			client = handler.NewCoalescingClient(client, coalesceWindow)
+		}
+	}
+	connectionStatus := gerritAdapter.Status
+	if path := os.Getenv("GERRIT_MCP_REPLAY"); path != "" {
+		replay, err := handler.LoadReplayClient(path)
+		if err != nil {
+			log.Fatalf("Invalid GERRIT_MCP_REPLAY: %v", err)
+		}
+		client, connectionStatus = replay, replay.Status
+	}
+
+	// Prefetched files and patches are kept in the patch cache
+	var prefetcher handler.Prefetcher
+	if cache != nil && os.Getenv("GERRIT_MCP_PREFETCH") == "true" && os.Getenv("GERRIT_MCP_REPLAY") == "" {
+		prefetcher = cache
+	}
+
+	var index *handler.ChangeIndex
+	if path := os.Getenv("GERRIT_MCP_INDEX"); path != "" {
+		index, err = handler.NewChangeIndex(client, path)
+		if err != nil {
+			log.Fatalf("Invalid GERRIT_MCP_INDEX: %v", err)
+		}
+		client = index
+	}
+
+	var config *handler.Config
+	configPath := os.Getenv("GERRIT_MCP_CONFIG")
+	if configPath != "" {
+		config, err = handler.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("Invalid GERRIT_MCP_CONFIG: %v", err)
+		}
+	}
+
+	var effortLog *handler.EffortLog
+	if path := os.Getenv("GERRIT_MCP_EFFORT_LOG"); path != "" {
+		effortLog, err = handler.NewEffortLog(path)
+		if err != nil {
+			log.Fatalf("Invalid GERRIT_MCP_EFFORT_LOG: %v", err)
 		}
-		log.Println("Gerrit client successfully authenticated and ready")
 	}
 
-	gerritAdapter := handler.NewGerritClientAdapter(client)
-	h := handler.NewHandler(gerritAdapter)
-	h.GetGerritChangePatch(ctx, mcp.CallToolRequest{})
+	h := handler.NewHandler(client,
+		handler.WithBaseURL(parsedBaseURL),
+		handler.WithHostAliases(hostAliases...),
+		handler.WithAllowedHosts(splitList(os.Getenv("GERRIT_MCP_ALLOWED_HOSTS"))...),
+		handler.WithConnectionStatus(connectionStatus),
+		handler.WithPatchLimits(maxPatchFiles, maxPatchLines),
+		handler.WithSecretScanning(os.Getenv("GERRIT_MCP_SCAN_SECRETS") == "true"),
+		handler.WithContentGuard(os.Getenv("GERRIT_MCP_GUARD_CONTENT") == "true"),
+		handler.WithConfig(config),
+		handler.WithEffortLog(effortLog),
+		handler.WithChangeIndex(index),
+		handler.WithQuotaTracker(quota),
+		handler.WithPrefetcher(prefetcher),
+	)
+
+	toolSet := &toolSet{handler: h}
+	if path := os.Getenv("GERRIT_MCP_EXTERNAL_TOOLS"); path != "" {
+		toolSet.externalTools, err = handler.LoadExternalTools(path)
+		if err != nil {
+			log.Fatalf("Invalid GERRIT_MCP_EXTERNAL_TOOLS: %v", err)
+		}
+	}
+	registry := handler.NewRegistry(toolSet.tools()...)
+	toolSet.registry = registry
+	toolSet.applyFilter(config)
+
+	registry.Use(
+		handler.ForAllTools(notifier.Middleware),
+		handler.ForAllTools(tracker.Middleware),
+		handler.ForAllTools(limiter.Middleware),
+		h.EnforceReadOnly,
+		h.EnforceProjectSettings,
+		h.RecordEffort,
+		h.ConditionalResults,
+		h.GuardUntrustedContent,
+		h.EnforceResponseBudgets,
+		h.Redact,
+	)
+
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runTool(ctx, registry.ServerTools(), os.Args[2:]))
+	}
+
+	hooks := &server.Hooks{}
+	hooks.AddAfterInitialize(notifier.AfterInitialize)
+	hooks.AddBeforeCallTool(tracker.BeforeCallTool)
+	hooks.AddOnUnregisterSession(h.Sessions().OnUnregisterSession)
+	hooks.AddOnUnregisterSession(notifier.OnUnregisterSession)
 
 	s := server.NewMCPServer(
 		"Gerrit Code Review",
 		"0.0.0",
 		server.WithRecovery(),
+		server.WithLogging(),
+		server.WithHooks(hooks),
+		server.WithToolCapabilities(true),
 	)
+	s.AddNotificationHandler("notifications/cancelled", tracker.HandleCancelled)
 
-	getGerritChangeTool := mcp.NewTool("get-gerrit-change",
-		mcp.WithDescription("Get Gerrit change"),
-		mcp.WithString("change_url",
-			mcp.Required(),
-			mcp.Description("URL of Gerrit change"),
-		),
-	)
+	s.AddTools(registry.ServerTools()...)
+	toolSet.server = s
+
+	if os.Getenv("GERRIT_MCP_REPLAY") == "" && os.Getenv("GERRIT_MCP_PROBE_CAPABILITIES") != "false" {
+		go toolSet.probeCapabilities(ctx, time.Minute)
+	}
+
+	// Warming only pays off if the fetched patches are kept
+	if cache != nil && os.Getenv("GERRIT_MCP_REPLAY") == "" {
+		go h.RunCacheWarming(ctx)
+	}
+
+	var reloader *configReloader
+	if configPath != "" {
+		reloader = &configReloader{path: configPath, tools: toolSet}
+		if info, err := os.Stat(configPath); err == nil {
+			reloader.modTime = info.ModTime()
+		}
+		go reloader.reloadOnSignal()
+
+		watchInterval := 5 * time.Second
+		if interval := os.Getenv("GERRIT_MCP_CONFIG_WATCH_INTERVAL"); interval != "" {
+			watchInterval, err = time.ParseDuration(interval)
+			if err != nil {
+				log.Fatalf("Invalid GERRIT_MCP_CONFIG_WATCH_INTERVAL: %v", err)
+			}
+		}
+		if watchInterval > 0 {
+			go reloader.watch(watchInterval)
+		}
+	}
+
+	if addr := os.Getenv("GERRIT_MCP_ADMIN_ADDR"); addr != "" {
+		admin := &handler.Admin{Cache: cache, Notifier: notifier}
+		if reloader != nil {
+			admin.Reload = reloader.reload
+		}
+		go func() {
+			log.Printf("Serving the admin API on %s", addr)
+			if err := http.ListenAndServe(addr, admin.Handler()); err != nil {
+				log.Fatalf("Admin API error: %v", err)
+			}
+		}()
+	}
+
+	listenAddr := os.Getenv("GERRIT_MCP_LISTEN_ADDR")
+	if listenAddr == "" {
+		listenAddr = "127.0.0.1:8080"
+	}
+
+	// Clients of the network transports must send their own credentials
+	// unless sharing the server's account was asked for
+	authenticate := handler.RequireCredentials
+	if os.Getenv("GERRIT_MCP_ALLOW_SHARED_ACCOUNT") == "true" {
+		authenticate = func(next http.Handler) http.Handler { return next }
+	}
 
-	s.AddTool(getGerritChangeTool, h.GetGerritChangePatch)
+	// Responses are compressed if the client accepts it, since patches
+	// make up most of the traffic to remote clients
+	compress := handler.CompressResponses
+	if os.Getenv("GERRIT_MCP_COMPRESSION") == "false" {
+		compress = func(next http.Handler) http.Handler { return next }
+	}
 
-	// Start the stdio server
-	if err := server.ServeStdio(s); err != nil {
-		fmt.Printf("Server error: %v\n", err)
+	switch transport := os.Getenv("GERRIT_MCP_TRANSPORT"); transport {
+	case "", "stdio":
+		// Start the stdio server, reading ahead so that cancellations reach
+		// the call in flight
+		cancellation := handler.NewStdioCancellation(s)
+		stdio := server.NewStdioServer(s)
+		stdio.SetContextFunc(cancellation.ContextFunc)
+		ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+		if err := stdio.Listen(ctx, cancellation.Reader(os.Stdin), os.Stdout); err != nil {
+			fmt.Printf("Server error: %v\n", err)
+		}
+	case "http":
+		log.Printf("Serving MCP over streamable HTTP on %s/mcp", listenAddr)
+		httpServer := server.NewStreamableHTTPServer(s, server.WithHTTPContextFunc(handler.CredentialsFromRequest))
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", authenticate(compress(httpServer)))
+		if err := http.ListenAndServe(listenAddr, mux); err != nil {
+			log.Fatalf("Server error: %v", err)
+		}
+	case "sse":
+		log.Printf("Serving MCP over SSE on %s/sse", listenAddr)
+		sseServer := server.NewSSEServer(s, server.WithSSEContextFunc(handler.CredentialsFromRequest))
+		if err := http.ListenAndServe(listenAddr, authenticate(compress(sseServer))); err != nil {
+			log.Fatalf("Server error: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown GERRIT_MCP_TRANSPORT %q, expected stdio, http or sse", transport)
+	}
+}
+
+// splitList splits a comma-separated environment variable, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// intEnv reads a non-negative integer environment variable
+func intEnv(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a non-negative integer", name, value)
+	}
+	return n, nil
+}
+
+// connect creates a Gerrit client and authenticates it
+func connect(ctx context.Context, baseURL string, httpClient *http.Client, username, password string, anonymousFallback bool) (*gerrit.Client, handler.ConnectionStatus, error) {
+	status := handler.ConnectionStatus{
+		BaseURL:  baseURL,
+		Username: username,
+		AuthMode: "anonymous",
+	}
+
+	client, err := gerrit.NewClient(ctx, baseURL, httpClient)
+	if err != nil {
+		return nil, status, fmt.Errorf("create Gerrit client: %w", err)
+	}
+	if len(username) == 0 {
+		return client, status, nil
+	}
+
+	err = setAuth(ctx, client, username, password)
+	switch {
+	case err == nil:
+		status.AuthMode = "authenticated"
+		log.Println("Gerrit client successfully authenticated and ready")
+	case errors.Is(err, gerrit.ErrAuthenticationFailed) && anonymousFallback:
+		// Public Gerrit instances allow anonymous reads, which beats
+		// failing every call until the credentials are fixed
+		client.Authentication.ResetAuth()
+		warning := fmt.Sprintf("authentication as %s failed (%v), running in anonymous read-only mode", username, err)
+		log.Printf("Warning: %s", warning)
+		status.ReadOnly = true
+		status.Warnings = append(status.Warnings, warning)
+	default:
+		return nil, status, fmt.Errorf("could not authenticate against gerrit with user %s: %w", username, err)
 	}
+	return client, status, nil
 }
 
 // checkAuth is used to check if the current credentials are valid.
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lad/gerrit-code-review-mcp/handler"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolSet builds the tools of the server and selects those to serve from
+// the environment, the configuration file and the server's capabilities
+type toolSet struct {
+	// mu serializes updates, so that a reload and a capability probe
+	// finishing together don't compute the served tools from each other's
+	// half-applied changes
+	mu            sync.Mutex
+	handler       *handler.Handler
+	registry      *handler.Registry
+	server        *server.MCPServer
+	externalTools []handler.Tool
+}
+
+// tools returns every known tool, which depends on the configuration
+func (t *toolSet) tools() []handler.Tool {
+	tools := append(t.handler.Tools(), t.handler.ExtensionTools()...)
+	return append(tools, t.externalTools...)
+}
+
+// update runs change on the registry and brings the served tools in line
+// with it. Sessions stay connected; they are told when the list changes.
+func (t *toolSet) update(change func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	before := make(map[string]bool)
+	for _, tool := range t.registry.ServerTools() {
+		before[tool.Tool.Name] = true
+	}
+
+	change()
+
+	// Add before deleting so that calls in flight never find a tool missing
+	after := t.registry.ServerTools()
+	t.server.AddTools(after...)
+	for _, tool := range after {
+		delete(before, tool.Tool.Name)
+	}
+	if len(before) > 0 {
+		removed := make([]string, 0, len(before))
+		for name := range before {
+			removed = append(removed, name)
+		}
+		t.server.DeleteTools(removed...)
+	}
+}
+
+// applyFilter enables and disables tools by the environment and cfg
+func (t *toolSet) applyFilter(cfg *handler.Config) {
+	enabled := splitList(os.Getenv("GERRIT_MCP_ENABLED_TOOLS"))
+	disabled := splitList(os.Getenv("GERRIT_MCP_DISABLED_TOOLS"))
+	if cfg != nil {
+		enabled = append(enabled, cfg.EnabledTools...)
+		disabled = append(disabled, cfg.DisabledTools...)
+	}
+	if unknown := t.registry.SetFilter(enabled, disabled); len(unknown) > 0 {
+		log.Printf("Warning: unknown tools or categories in the enabled or disabled tools: %s", strings.Join(unknown, ", "))
+	}
+	// Only the environment turns on opt-in tools, not the configuration file
+	if unknown := t.registry.SetOptIn(splitList(os.Getenv("GERRIT_MCP_OPT_IN_TOOLS"))); len(unknown) > 0 {
+		log.Printf("Warning: unknown tools or categories in GERRIT_MCP_OPT_IN_TOOLS: %s", strings.Join(unknown, ", "))
+	}
+}
+
+// configReloader re-reads the configuration file and applies it to the
+// running server, keeping the previous configuration if the file is invalid
+type configReloader struct {
+	mu      sync.Mutex
+	path    string
+	tools   *toolSet
+	modTime time.Time
+}
+
+// reload applies the configuration file
+func (r *configReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if info, err := os.Stat(r.path); err == nil {
+		r.modTime = info.ModTime()
+	}
+	cfg, err := handler.LoadConfig(r.path)
+	if err != nil {
+		return err
+	}
+
+	r.tools.update(func() {
+		r.tools.handler.SetConfig(cfg)
+		r.tools.registry.SetTools(r.tools.tools()...)
+		r.tools.applyFilter(cfg)
+	})
+
+	log.Printf("Reloaded %s", r.path)
+	return nil
+}
+
+// reloadLogged reloads and logs a failure instead of returning it
+func (r *configReloader) reloadLogged() {
+	if err := r.reload(); err != nil {
+		log.Printf("Warning: keeping the previous configuration: %v", err)
+	}
+}
+
+// watch reloads the configuration whenever the file's modification time changes
+func (r *configReloader) watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		info, err := os.Stat(r.path)
+		if err != nil {
+			continue
+		}
+		r.mu.Lock()
+		changed := !info.ModTime().Equal(r.modTime)
+		r.mu.Unlock()
+		if changed {
+			r.reloadLogged()
+		}
+	}
+}
+
+// probeCapabilities hides the tools the Gerrit server can't support. Gerrit
+// may be unreachable at startup, so failed probes are retried until one succeeds.
+func (t *toolSet) probeCapabilities(ctx context.Context, retry time.Duration) {
+	for {
+		caps, err := t.handler.ProbeCapabilities(ctx)
+		if err == nil {
+			t.update(func() { t.registry.SetCapabilities(caps) })
+			log.Printf("Probed Gerrit %s", caps.Version)
+			return
+		}
+		log.Printf("Warning: could not probe the Gerrit server's capabilities, serving all tools: %v", err)
+		time.Sleep(retry)
+	}
+}
+
+// reloadOnSignal reloads the configuration on SIGHUP
+func (r *configReloader) reloadOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		r.reloadLogged()
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/lad/gerrit-code-review-mcp/handler"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// servedTools lists the names of the tools s advertises
+func servedTools(t *testing.T, s *server.MCPServer) []string {
+	t.Helper()
+	response := s.HandleMessage(context.Background(), []byte(`{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`))
+	result, ok := response.(mcp.JSONRPCResponse).Result.(mcp.ListToolsResult)
+	if !ok {
+		t.Fatalf("unexpected response %#v", response)
+	}
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestReloadDuringProbe(t *testing.T) {
+	// Gerrit 2.15 lacks the fix suggestions that apply-gerrit-fix-suggestion needs
+	gerritServer := fakeGerrit(t, "2.15.0", "password")
+	client, err := gerrit.NewClient(context.Background(), gerritServer.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"disabled_tools": ["admin"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tools := &toolSet{handler: handler.NewHandler(handler.NewGerritClientAdapter(client))}
+	tools.registry = handler.NewRegistry(tools.tools()...)
+	tools.server = server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	tools.server.AddTools(tools.registry.ServerTools()...)
+	reloader := &configReloader{path: configPath, tools: tools}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			tools.probeCapabilities(context.Background(), 0)
+		}()
+		go func() {
+			defer wg.Done()
+			if err := reloader.reload(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// The tools are replaced meanwhile, but never all at once
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+			if len(tools.registry.ServerTools()) == 0 {
+				t.Fatal("expected the registry to serve tools throughout reloads")
+			}
+		}
+	}
+
+	var want []string
+	for _, tool := range tools.registry.ServerTools() {
+		want = append(want, tool.Tool.Name)
+	}
+	slices.Sort(want)
+	got := servedTools(t, tools.server)
+	if !slices.Equal(got, want) {
+		t.Errorf("expected the server to serve the registry's tools\n%v\ngot\n%v", want, got)
+	}
+	if slices.Contains(got, "apply-gerrit-fix-suggestion") {
+		t.Error("expected the probed capabilities to survive the reloads")
+	}
+}
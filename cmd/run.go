@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// runTool invokes a single tool once, outside of an MCP session, and prints
+// its result to stdout. It returns the process exit code.
+//
+//	gerrit-code-review-mcp run get-gerrit-change --change_url=https://...
+func runTool(ctx context.Context, tools []server.ServerTool, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: gerrit-code-review-mcp run <tool> [--param=value ...]")
+		fmt.Fprintln(os.Stderr, "\nAvailable tools:")
+		for _, t := range tools {
+			fmt.Fprintf(os.Stderr, "  %-30s %s\n", t.Tool.Name, t.Tool.Description)
+		}
+		return 2
+	}
+
+	var tool *server.ServerTool
+	for i := range tools {
+		if tools[i].Tool.Name == args[0] {
+			tool = &tools[i]
+		}
+	}
+	if tool == nil {
+		fmt.Fprintf(os.Stderr, "unknown tool %q\n", args[0])
+		return 2
+	}
+
+	arguments, err := parseToolArgs(tool.Tool, args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool.Tool.Name
+	request.Params.Arguments = arguments
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	result, err := tool.Handler(ctx, request)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tool %s failed: %v\n", tool.Tool.Name, err)
+		return 1
+	}
+
+	out := os.Stdout
+	if result.IsError {
+		out = os.Stderr
+	}
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			fmt.Fprintln(out, text.Text)
+			continue
+		}
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not encode result content: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(out, string(data))
+	}
+
+	if result.IsError {
+		return 1
+	}
+	return 0
+}
+
+// parseToolArgs converts --name=value and --name value flags into tool
+// arguments, using the tool's input schema to pick the value types.
+// Boolean parameters may be given without a value.
+func parseToolArgs(tool mcp.Tool, args []string) (map[string]any, error) {
+	arguments := make(map[string]any)
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			return nil, fmt.Errorf("unexpected argument %q, expected --name=value", args[i])
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(args[i], "--"), "=")
+
+		property, ok := tool.InputSchema.Properties[name].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("tool %s has no parameter %q (parameters: %s)", tool.Name, name, strings.Join(parameterNames(tool), ", "))
+		}
+		kind, _ := property["type"].(string)
+
+		if !hasValue {
+			if kind == "boolean" {
+				value = "true"
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			} else {
+				return nil, fmt.Errorf("missing value for --%s", name)
+			}
+		}
+
+		switch kind {
+		case "number", "integer":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("--%s expects a number: %w", name, err)
+			}
+			arguments[name] = n
+		case "boolean":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("--%s expects true or false: %w", name, err)
+			}
+			arguments[name] = b
+		case "array":
+			var items []any
+			for _, item := range strings.Split(value, ",") {
+				items = append(items, strings.TrimSpace(item))
+			}
+			arguments[name] = items
+		default:
+			arguments[name] = value
+		}
+	}
+
+	for _, name := range tool.InputSchema.Required {
+		if _, ok := arguments[name]; !ok {
+			return nil, fmt.Errorf("missing required parameter --%s", name)
+		}
+	}
+	return arguments, nil
+}
+
+func parameterNames(tool mcp.Tool) []string {
+	names := make([]string, 0, len(tool.InputSchema.Properties))
+	for name := range tool.InputSchema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
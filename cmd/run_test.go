@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseToolArgs(t *testing.T) {
+	tool := mcp.NewTool("test-tool",
+		mcp.WithString("change_url", mcp.Required()),
+		mcp.WithNumber("limit"),
+		mcp.WithBoolean("verbose"),
+	)
+
+	args, err := parseToolArgs(tool, []string{"--change_url=https://gerrit.example.com/c/p/+/1", "--limit", "5", "--verbose"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if args["change_url"] != "https://gerrit.example.com/c/p/+/1" {
+		t.Errorf("unexpected change_url: %v", args["change_url"])
+	}
+	if args["limit"] != float64(5) {
+		t.Errorf("expected limit 5, got %v", args["limit"])
+	}
+	if args["verbose"] != true {
+		t.Errorf("expected verbose true, got %v", args["verbose"])
+	}
+
+	errorCases := [][]string{
+		{"--limit=5"},
+		{"--change_url=x", "--limit=many"},
+		{"--change_url=x", "--unknown=1"},
+		{"--change_url=x", "positional"},
+		{"--change_url"},
+	}
+	for _, args := range errorCases {
+		if _, err := parseToolArgs(tool, args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
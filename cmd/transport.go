@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// gerritTransport builds the one HTTP transport shared by every Gerrit
+// client, including the per-session ones of the network transports. Go's
+// default keeps only two idle connections per host, so concurrent per-file
+// fetches against a single Gerrit server keep opening new connections.
+//
+//   - GERRIT_MCP_MAX_IDLE_CONNS: idle connections kept in total (default 100)
+//   - GERRIT_MCP_MAX_IDLE_CONNS_PER_HOST: idle connections kept per host (default 32)
+//   - GERRIT_MCP_MAX_CONNS_PER_HOST: connections per host, 0 for no limit (default 0)
+//   - GERRIT_MCP_IDLE_CONN_TIMEOUT: how long an idle connection is kept (default 90s)
+//   - GERRIT_MCP_HTTP2=false: stick to HTTP/1.1
+func gerritTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	var err error
+	if transport.MaxIdleConns, err = intEnv("GERRIT_MCP_MAX_IDLE_CONNS", 100); err != nil {
+		return nil, err
+	}
+	if transport.MaxIdleConnsPerHost, err = intEnv("GERRIT_MCP_MAX_IDLE_CONNS_PER_HOST", 32); err != nil {
+		return nil, err
+	}
+	if transport.MaxConnsPerHost, err = intEnv("GERRIT_MCP_MAX_CONNS_PER_HOST", 0); err != nil {
+		return nil, err
+	}
+	if timeout := os.Getenv("GERRIT_MCP_IDLE_CONN_TIMEOUT"); timeout != "" {
+		transport.IdleConnTimeout, err = time.ParseDuration(timeout)
+		if err != nil || transport.IdleConnTimeout < 0 {
+			return nil, fmt.Errorf("invalid GERRIT_MCP_IDLE_CONN_TIMEOUT %q: expected a non-negative duration", timeout)
+		}
+	}
+
+	// Multiplexing requests over one HTTP/2 connection removes the
+	// per-host limit altogether on servers that support it
+	if os.Getenv("GERRIT_MCP_HTTP2") == "false" {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	} else {
+		transport.ForceAttemptHTTP2 = true
+	}
+	return transport, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGerritTransport(t *testing.T) {
+	transport, err := gerritTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.MaxIdleConnsPerHost != 32 || !transport.ForceAttemptHTTP2 {
+		t.Errorf("expected 32 idle connections per host over HTTP/2, got %d and %v", transport.MaxIdleConnsPerHost, transport.ForceAttemptHTTP2)
+	}
+
+	t.Setenv("GERRIT_MCP_MAX_IDLE_CONNS_PER_HOST", "8")
+	t.Setenv("GERRIT_MCP_MAX_CONNS_PER_HOST", "16")
+	t.Setenv("GERRIT_MCP_IDLE_CONN_TIMEOUT", "30s")
+	t.Setenv("GERRIT_MCP_HTTP2", "false")
+	transport, err = gerritTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.MaxIdleConnsPerHost != 8 || transport.MaxConnsPerHost != 16 || transport.IdleConnTimeout != 30*time.Second {
+		t.Errorf("expected the configured limits, got %d, %d and %s", transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost, transport.IdleConnTimeout)
+	}
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Error("expected HTTP/2 to be disabled")
+	}
+
+	t.Setenv("GERRIT_MCP_MAX_IDLE_CONNS", "many")
+	if _, err := gerritTransport(); err == nil {
+		t.Error("expected an invalid limit to be rejected")
+	}
+}
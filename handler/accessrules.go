@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// metaConfigBranch holds a project's configuration and access rules
+const metaConfigBranch = "refs/meta/config"
+
+// Lines of project.config, a git config file
+var (
+	configSectionRegexp = regexp.MustCompile(`^\s*\[\s*([A-Za-z][\w-]*)(?:\s+"((?:[^"\\]|\\.)*)")?\s*\]`)
+	configRuleRegexp    = regexp.MustCompile(`^\s*([A-Za-z][\w-]*)\s*=\s*(.*?)\s*$`)
+)
+
+// changeInput is Gerrit's ChangeInput
+type changeInput struct {
+	Project string `json:"project"`
+	Branch  string `json:"branch"`
+	Subject string `json:"subject"`
+}
+
+// accessRule is the value of a permission line of project.config, e.g.
+// "deny group Anonymous Users" or "-2..+2 group Reviewers"
+func accessRule(action, labelRange, group string) string {
+	var parts []string
+	if action == "deny" || action == "block" {
+		parts = append(parts, action)
+	}
+	if labelRange != "" {
+		parts = append(parts, labelRange)
+	}
+	return strings.Join(append(parts, "group "+group), " ")
+}
+
+// ruleGroup returns the group a permission line's value grants to
+func ruleGroup(value string) string {
+	if i := strings.Index(value, "group "); i >= 0 {
+		return strings.TrimSpace(value[i+len("group "):])
+	}
+	return ""
+}
+
+// editAccessRule sets or, if rule is empty, removes the permission of group
+// on ref in a project.config, keeping everything else as it was. It reports
+// whether the file changed.
+func editAccessRule(config, ref, permission, group, rule string) (string, bool) {
+	lines := strings.Split(strings.TrimRight(config, "\n"), "\n")
+	if config == "" {
+		lines = nil
+	}
+	newLine := fmt.Sprintf("\t%s = %s", permission, rule)
+
+	// Find the access section of ref, and the end of its last line
+	start, end := -1, -1
+	for i, line := range lines {
+		if m := configSectionRegexp.FindStringSubmatch(line); m != nil {
+			if start >= 0 && end < 0 {
+				end = i
+			}
+			if strings.EqualFold(m[1], "access") && m[2] == ref && start < 0 {
+				start = i
+			}
+		}
+	}
+	if start >= 0 && end < 0 {
+		end = len(lines)
+	}
+
+	if start < 0 {
+		if rule == "" {
+			return config, false
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, fmt.Sprintf("[access %q]", ref), newLine)
+		return strings.Join(lines, "\n") + "\n", true
+	}
+
+	changed := false
+	replaced := false
+	var section []string
+	for _, line := range lines[start+1 : end] {
+		m := configRuleRegexp.FindStringSubmatch(line)
+		if m == nil || !strings.EqualFold(m[1], permission) || ruleGroup(m[2]) != group {
+			section = append(section, line)
+			continue
+		}
+		// The group's existing rule for the permission
+		switch {
+		case rule == "":
+			changed = true
+		case replaced:
+			changed = true
+		default:
+			replaced = true
+			if strings.TrimSpace(line) != strings.TrimSpace(newLine) {
+				changed = true
+			}
+			section = append(section, newLine)
+		}
+	}
+	if rule != "" && !replaced {
+		// Before the blank lines separating the next section
+		at := len(section)
+		for at > 0 && strings.TrimSpace(section[at-1]) == "" {
+			at--
+		}
+		section = append(section[:at], append([]string{newLine}, section[at:]...)...)
+		changed = true
+	}
+	if !changed {
+		return config, false
+	}
+
+	result := append(append(append([]string{}, lines[:start+1]...), section...), lines[end:]...)
+	return strings.Join(result, "\n") + "\n", true
+}
+
+// addGroupReference adds a group to the groups file of refs/meta/config,
+// which maps the UUIDs of the groups in project.config to their names
+func addGroupReference(groups, uuid, name string) string {
+	for line := range strings.SplitSeq(groups, "\n") {
+		if fields := strings.SplitN(line, "\t", 2); fields[0] == uuid {
+			return groups
+		}
+	}
+	if groups == "" {
+		groups = "# UUID\tGroup Name\n#\n"
+	} else if !strings.HasSuffix(groups, "\n") {
+		groups += "\n"
+	}
+	return groups + uuid + "\t" + name + "\n"
+}
+
+// EditGerritProjectAccess changes one access rule of a project by uploading
+// a change to its refs/meta/config branch, so that the permission change is
+// reviewed like any other rather than applied directly
+func (h *Handler) EditGerritProjectAccess(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	project, err := request.RequireString("project")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	ref, err := request.RequireString("ref")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	permission, err := request.RequireString("permission")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	groupName, err := request.RequireString("group")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	action := request.GetString("action", "allow")
+	labelRange := strings.TrimSpace(request.GetString("range", ""))
+	switch action {
+	case "allow", "deny", "block", "remove":
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("unknown action %q, expected allow, deny, block or remove", action)), nil
+	}
+	if ref != "GLOBAL_CAPABILITIES" && !strings.HasPrefix(ref, "refs/") && !strings.HasPrefix(ref, "^refs/") {
+		return mcp.NewToolResultError(fmt.Sprintf("ref %q must start with refs/ or ^refs/, or be GLOBAL_CAPABILITIES", ref)), nil
+	}
+	if !configRuleRegexp.MatchString(permission + " =") {
+		return mcp.NewToolResultError(fmt.Sprintf("%q is not a permission name such as read, push or label-Code-Review", permission)), nil
+	}
+	if strings.HasPrefix(strings.ToLower(permission), "label") && labelRange == "" && action != "remove" {
+		return mcp.NewToolResultError(fmt.Sprintf("%s needs a range of votes, e.g. -2..+2", permission)), nil
+	}
+
+	// The group's UUID goes into the groups file alongside project.config
+	var group struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if _, err := h.client.Call(ctx, http.MethodGet, "groups/"+url.PathEscape(groupName), nil, &group); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to look up group %s: %v", groupName, err)), nil
+	}
+	uuid, err := url.QueryUnescape(group.ID)
+	if err != nil {
+		uuid = group.ID
+	}
+
+	rule := ""
+	if action != "remove" {
+		rule = accessRule(action, labelRange, group.Name)
+	}
+	summaries := map[string]string{"allow": "Grant %s to %s", "deny": "Deny %s to %s", "block": "Block %s for %s", "remove": "Remove %s of %s"}
+	subject := request.GetString("subject", fmt.Sprintf(summaries[action], permission, group.Name)+" on "+ref)
+
+	var created struct {
+		ID     string `json:"id"`
+		Number int    `json:"_number"`
+	}
+	resp, err := h.client.Call(ctx, http.MethodPost, "changes/", changeInput{Project: project, Branch: metaConfigBranch, Subject: subject}, &created)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create a change on %s of %s: %v", metaConfigBranch, project, err)), nil
+	}
+	changeID := strconv.Itoa(created.Number)
+	abandon := func(message string) error {
+		_, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/abandon", changeID), map[string]string{"message": message}, nil)
+		return err
+	}
+	// Don't leave a half-made change behind on refs/meta/config either
+	fail := func(err error) (*mcp.CallToolResult, error) {
+		if abandonErr := abandon("Editing the access rules failed: " + err.Error()); abandonErr != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("%v; abandon change %s, which could not be abandoned automatically (%v)", err, changeID, abandonErr)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("%v; change %s was abandoned", err, changeID)), nil
+	}
+
+	config, _, err := h.fileContent(ctx, fmt.Sprintf("changes/%s/revisions/current/files/project.config/content", changeID))
+	if err != nil {
+		return fail(fmt.Errorf("failed to get project.config of %s: %v", project, err))
+	}
+	edited, changed := editAccessRule(config, ref, permission, group.Name, rule)
+	if !changed {
+		// Don't leave an empty change behind
+		if err := abandon("No access rules to change"); err != nil {
+			return mcp.NewToolResultText(fmt.Sprintf("The access rules of %s already say that; abandon the empty change %s (%v)", project, changeID, err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("The access rules of %s already say that; no change was needed", project)), nil
+	}
+	if err := h.putEditFile(ctx, changeID, "project.config", edited); err != nil {
+		return fail(err)
+	}
+	if action != "remove" && uuid != "" {
+		groups, _, err := h.fileContent(ctx, fmt.Sprintf("changes/%s/revisions/current/files/groups/content", changeID))
+		if err != nil {
+			// A project without groups file yet
+			groups = ""
+		}
+		if updated := addGroupReference(groups, uuid, group.Name); updated != groups {
+			if err := h.putEditFile(ctx, changeID, "groups", updated); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if _, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/edit:publish", changeID), map[string]any{}, nil); err != nil {
+		return fail(fmt.Errorf("failed to publish the change edit of change %s: %v", changeID, err))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Created change %s on %s of %s for review: %s\n", changeID, metaConfigBranch, project, subject)
+	if link := h.canonicalChangeURL(&gerrit.ChangeInfo{Project: project, Number: created.Number}, resp); link != "" {
+		fmt.Fprintf(&b, "%s\n", link)
+	}
+	b.WriteString("\nThe rules take effect once the change is submitted. New project.config:\n\n")
+	b.WriteString(edited)
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// emailInfo is Gerrit's EmailInfo
+type emailInfo struct {
+	Email               string `json:"email"`
+	Preferred           bool   `json:"preferred"`
+	PendingConfirmation bool   `json:"pending_confirmation"`
+}
+
+// emailInput is Gerrit's EmailInput
+type emailInput struct {
+	Email     string `json:"email"`
+	Preferred bool   `json:"preferred,omitempty"`
+}
+
+// emailPreferences holds the email fields of Gerrit's PreferencesInfo
+type emailPreferences struct {
+	EmailStrategy string `json:"email_strategy,omitempty"`
+	EmailFormat   string `json:"email_format,omitempty"`
+}
+
+// Values of the email preferences, as Gerrit names them
+var (
+	emailStrategies = []string{"ENABLED", "CC_ON_OWN_COMMENTS", "ATTENTION_SET_ONLY", "DISABLED"}
+	emailFormats    = []string{"PLAINTEXT", "HTML_PLAINTEXT"}
+)
+
+// ListGerritAccountEmails lists the calling user's email addresses and how
+// Gerrit notifies them
+func (h *Handler) ListGerritAccountEmails(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var emails []emailInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "accounts/self/emails", nil, &emails); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list your emails, which requires authentication: %v", err)), nil
+	}
+	var prefs emailPreferences
+	if _, err := h.client.Call(ctx, http.MethodGet, "accounts/self/preferences", nil, &prefs); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get your preferences: %v", err)), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Your emails (%d):\n", len(emails))
+	for _, e := range emails {
+		var notes []string
+		if e.Preferred {
+			notes = append(notes, "preferred")
+		}
+		if e.PendingConfirmation {
+			notes = append(notes, "pending confirmation")
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(&b, "  %s (%s)\n", e.Email, strings.Join(notes, ", "))
+		} else {
+			fmt.Fprintf(&b, "  %s\n", e.Email)
+		}
+	}
+	fmt.Fprintf(&b, "\nEmail notifications: %s, format %s", prefs.EmailStrategy, prefs.EmailFormat)
+	return mcp.NewToolResultText(b.String()), nil
+}
+
+// AddGerritAccountEmail adds an email address to the calling user's account.
+// Gerrit sends a confirmation link to it before it can be used.
+func (h *Handler) AddGerritAccountEmail(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	email, err := request.RequireString("email")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	email = strings.TrimSpace(email)
+	if !strings.Contains(email, "@") {
+		return mcp.NewToolResultError(fmt.Sprintf("%q is not an email address", email)), nil
+	}
+	preferred := request.GetBool("preferred", false)
+	if result := ownCredentialsOnly(ctx, "add an email"); result != nil {
+		return result, nil
+	}
+	if preferred {
+		if result := unconfirmed(request, fmt.Sprintf("make %s your preferred email once confirmed, which Gerrit sends notifications to and attributes your commits by", email)); result != nil {
+			return result, nil
+		}
+	}
+
+	var added emailInfo
+	path := "accounts/self/emails/" + url.PathEscape(email)
+	if _, err := h.client.Call(ctx, http.MethodPut, path, emailInput{Email: email, Preferred: preferred}, &added); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to add %s: %v", email, err)), nil
+	}
+
+	if added.PendingConfirmation {
+		return mcp.NewToolResultText(fmt.Sprintf("Added %s; open the confirmation link Gerrit sent to it to start using it", email)), nil
+	}
+	if preferred {
+		return mcp.NewToolResultText(fmt.Sprintf("Added %s as your preferred email", email)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Added %s", email)), nil
+}
+
+// DeleteGerritAccountEmail removes an email address from the calling user's
+// account. The preferred email can't be removed, since Gerrit sends
+// notifications to it and attributes commits by it.
+func (h *Handler) DeleteGerritAccountEmail(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	email, err := request.RequireString("email")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	email = strings.TrimSpace(email)
+
+	var emails []emailInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "accounts/self/emails", nil, &emails); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list your emails: %v", err)), nil
+	}
+	i := slices.IndexFunc(emails, func(e emailInfo) bool { return strings.EqualFold(e.Email, email) })
+	switch {
+	case i < 0:
+		return mcp.NewToolResultError(fmt.Sprintf("%s is not an email of your account", email)), nil
+	case emails[i].Preferred:
+		return mcp.NewToolResultError(fmt.Sprintf("%s is your preferred email and can't be deleted; make another email preferred first with set-gerrit-email-preferences", email)), nil
+	}
+	email = emails[i].Email
+	if result := unconfirmed(request, fmt.Sprintf("delete %s from your account, so that Gerrit no longer sends to it or recognises it", email)); result != nil {
+		return result, nil
+	}
+
+	if _, err := h.client.Call(ctx, http.MethodDelete, "accounts/self/emails/"+url.PathEscape(email), nil, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to delete %s: %v", email, err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Deleted %s from your account", email)), nil
+}
+
+// SetGerritEmailPreferences changes which events the calling user is
+// emailed about and the format of the emails, and optionally the preferred
+// email address
+func (h *Handler) SetGerritEmailPreferences(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	prefs := emailPreferences{
+		EmailStrategy: strings.ToUpper(request.GetString("strategy", "")),
+		EmailFormat:   strings.ToUpper(request.GetString("format", "")),
+	}
+	preferred := strings.TrimSpace(request.GetString("preferred_email", ""))
+	if prefs.EmailStrategy == "" && prefs.EmailFormat == "" && preferred == "" {
+		return mcp.NewToolResultError("set at least one of strategy, format or preferred_email"), nil
+	}
+	if prefs.EmailStrategy != "" && !slices.Contains(emailStrategies, prefs.EmailStrategy) {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown strategy %q, expected one of %s", prefs.EmailStrategy, strings.Join(emailStrategies, ", "))), nil
+	}
+	if prefs.EmailFormat != "" && !slices.Contains(emailFormats, prefs.EmailFormat) {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown format %q, expected one of %s", prefs.EmailFormat, strings.Join(emailFormats, ", "))), nil
+	}
+	if result := ownCredentialsOnly(ctx, "change the email preferences"); result != nil {
+		return result, nil
+	}
+	if preferred != "" {
+		if result := unconfirmed(request, fmt.Sprintf("make %s your preferred email, which Gerrit sends notifications to and attributes your commits by", preferred)); result != nil {
+			return result, nil
+		}
+	}
+
+	var b strings.Builder
+	if preferred != "" {
+		if _, err := h.client.Call(ctx, http.MethodPut, fmt.Sprintf("accounts/self/emails/%s/preferred", url.PathEscape(preferred)), nil, nil); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to make %s your preferred email: %v", preferred, err)), nil
+		}
+		fmt.Fprintf(&b, "%s is now your preferred email\n", preferred)
+	}
+	if prefs.EmailStrategy != "" || prefs.EmailFormat != "" {
+		// Gerrit only changes the preferences that are set
+		var updated emailPreferences
+		if _, err := h.client.Call(ctx, http.MethodPut, "accounts/self/preferences", prefs, &updated); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to update your email preferences: %v", err)), nil
+		}
+		fmt.Fprintf(&b, "Email notifications: %s, format %s\n", updated.EmailStrategy, updated.EmailFormat)
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// sshKeyInfo is Gerrit's SshKeyInfo
+type sshKeyInfo struct {
+	Seq       int    `json:"seq"`
+	Algorithm string `json:"algorithm"`
+	Comment   string `json:"comment"`
+	Valid     bool   `json:"valid"`
+}
+
+// httpPasswordInput is Gerrit's HttpPasswordInput
+type httpPasswordInput struct {
+	Generate bool `json:"generate"`
+}
+
+// formatSSHKey describes an SSH key without its key material
+func formatSSHKey(key sshKeyInfo) string {
+	s := fmt.Sprintf("#%d %s", key.Seq, key.Algorithm)
+	if key.Comment != "" {
+		s += " " + key.Comment
+	}
+	if !key.Valid {
+		s += " (invalid)"
+	}
+	return s
+}
+
+// ListGerritSSHKeys lists the SSH keys of the calling user's account
+func (h *Handler) ListGerritSSHKeys(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var keys []sshKeyInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "accounts/self/sshkeys", nil, &keys); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list your SSH keys, which requires authentication: %v", err)), nil
+	}
+	if len(keys) == 0 {
+		return mcp.NewToolResultText("You have no SSH keys"), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Your SSH keys (%d):\n", len(keys))
+	for _, key := range keys {
+		fmt.Fprintf(&b, "  %s\n", formatSSHKey(key))
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// AddGerritSSHKey adds an SSH public key to the calling user's account
+func (h *Handler) AddGerritSSHKey(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	key, err := request.RequireString("key")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	key = strings.TrimSpace(key)
+	if strings.Contains(key, "PRIVATE KEY") {
+		return mcp.NewToolResultError("that is a private key; pass the public key, e.g. the contents of ~/.ssh/id_ed25519.pub"), nil
+	}
+	if len(strings.Fields(key)) < 2 {
+		return mcp.NewToolResultError("expected an OpenSSH public key such as \"ssh-ed25519 AAAA... user@host\""), nil
+	}
+	if result := ownCredentialsOnly(ctx, "add an SSH key"); result != nil {
+		return result, nil
+	}
+	if result := unconfirmed(request, "add an SSH key to your account, which grants git over SSH to whoever holds its private key"); result != nil {
+		return result, nil
+	}
+
+	// Gerrit takes the key as raw input, which it also accepts as a JSON string
+	var added sshKeyInfo
+	if _, err := h.client.Call(ctx, http.MethodPost, "accounts/self/sshkeys", key, &added); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to add the SSH key: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Added SSH key %s", formatSSHKey(added))), nil
+}
+
+// DeleteGerritSSHKey removes an SSH key from the calling user's account
+func (h *Handler) DeleteGerritSSHKey(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	seq := request.GetInt("seq", 0)
+	if seq < 1 {
+		return mcp.NewToolResultError("seq must be the number of a key, as listed by list-gerrit-ssh-keys"), nil
+	}
+	if result := ownCredentialsOnly(ctx, "delete an SSH key"); result != nil {
+		return result, nil
+	}
+	if result := unconfirmed(request, fmt.Sprintf("delete SSH key #%d, so that git over SSH with it stops working", seq)); result != nil {
+		return result, nil
+	}
+
+	if _, err := h.client.Call(ctx, http.MethodDelete, fmt.Sprintf("accounts/self/sshkeys/%d", seq), nil, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to delete SSH key #%d: %v", seq, err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Deleted SSH key #%d", seq)), nil
+}
+
+// GenerateGerritHTTPPassword replaces the calling user's HTTP password with a
+// newly generated one, which Gerrit only returns this once. The password
+// would end up in the model's context, so unless show_password is set the
+// user is sent to Gerrit's settings to generate it there instead.
+func (h *Handler) GenerateGerritHTTPPassword(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if result := ownCredentialsOnly(ctx, "replace the HTTP password"); result != nil {
+		return result, nil
+	}
+	if !request.GetBool("show_password", false) {
+		return mcp.NewToolResultText(fmt.Sprintf("Nothing was changed. Generate the password at %s, where only you see it. "+
+			"Call again with show_password=true and confirm=true to have it returned here instead, where it becomes part of the conversation.", h.settingsURL("HTTPCredentials"))), nil
+	}
+	if result := unconfirmed(request, "replace your HTTP password, so that everything using the old one stops working"); result != nil {
+		return result, nil
+	}
+
+	var password string
+	if _, err := h.client.Call(ctx, http.MethodPut, "accounts/self/password.http", httpPasswordInput{Generate: true}, &password); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to generate an HTTP password: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Your new HTTP password: %s\n\n"+
+		"Store it now, Gerrit won't show it again. The old password no longer works.", password)), nil
+}
+
+// DeleteGerritHTTPPassword revokes the calling user's HTTP password
+func (h *Handler) DeleteGerritHTTPPassword(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if result := ownCredentialsOnly(ctx, "revoke the HTTP password"); result != nil {
+		return result, nil
+	}
+	if result := unconfirmed(request, "revoke your HTTP password, so that everything using it stops working"); result != nil {
+		return result, nil
+	}
+	if _, err := h.client.Call(ctx, http.MethodDelete, "accounts/self/password.http", nil, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to revoke your HTTP password: %v", err)), nil
+	}
+	return mcp.NewToolResultText("Revoked your HTTP password; generate a new one to use the REST API or git over HTTP again"), nil
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxActivityChanges bounds the changes scanned for activity
+const maxActivityChanges = 200
+
+// gerritQueryTime is the time format accepted by Gerrit's before: and after: operators
+const gerritQueryTime = "2006-01-02 15:04:05"
+
+var (
+	voteRegexp    = regexp.MustCompile(`(?:^|\s)([A-Za-z][\w-]*)([+-]\d+)\b`)
+	commentRegexp = regexp.MustCompile(`\((\d+) (?:inline )?comments?\)`)
+	uploadRegexp  = regexp.MustCompile(`^Uploaded patch set (\d+)`)
+)
+
+// parseTimeBound parses a time window bound given as a date, a date and time,
+// or an age such as 36h or 7d relative to now
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected e.g. 7d, 36h, 2024-05-01 or 2024-05-01 14:00", value)
+}
+
+// activityEvent is something an account did on a change
+type activityEvent struct {
+	when        time.Time
+	description string
+}
+
+// describeMessage turns a change message into activity descriptions,
+// counting uploads, votes and comments
+func describeMessage(message string, uploads, votes, comments *int) []string {
+	firstLine, _, _ := strings.Cut(message, "\n")
+	var descriptions []string
+	if m := uploadRegexp.FindStringSubmatch(firstLine); m != nil {
+		*uploads++
+		descriptions = append(descriptions, "uploaded patch set "+m[1])
+	}
+	if rest, ok := strings.CutPrefix(firstLine, "Patch Set "); ok {
+		if _, labels, ok := strings.Cut(rest, ":"); ok {
+			for _, m := range voteRegexp.FindAllStringSubmatch(labels, -1) {
+				*votes++
+				descriptions = append(descriptions, fmt.Sprintf("voted %s%s", m[1], m[2]))
+			}
+		}
+	}
+	if m := commentRegexp.FindStringSubmatch(message); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		*comments += n
+		descriptions = append(descriptions, "wrote "+plural(n, "comment"))
+	}
+	if len(descriptions) == 0 {
+		descriptions = append(descriptions, "wrote a message")
+	}
+	return descriptions
+}
+
+// GetGerritActivity lists what an account did in Gerrit within a time window:
+// patch sets uploaded, votes cast and comments written, grouped by change
+func (h *Handler) GetGerritActivity(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	accountID := request.GetString("account", "self")
+	now := time.Now()
+
+	since, err := parseTimeBound(request.GetString("since", "7d"), now)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	until := now
+	if value := request.GetString("until", ""); value != "" {
+		if until, err = parseTimeBound(value, now); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	var account gerrit.AccountInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "accounts/"+url.PathEscape(accountID), nil, &account); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to look up account %s: %v", accountID, err)), nil
+	}
+
+	query := fmt.Sprintf(`(owner:%[1]d OR commentby:%[1]d OR reviewedby:%[1]d) after:"%[2]s"`, account.AccountID, since.UTC().Format(gerritQueryTime))
+	changes, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{query}, Limit: maxActivityChanges},
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: []string{"MESSAGES"}},
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to query changes: %v", err)), nil
+	}
+
+	var uploads, votes, comments int
+	type changeActivity struct {
+		change gerrit.ChangeInfo
+		events []activityEvent
+	}
+	var active []changeActivity
+	for _, change := range *changes {
+		var events []activityEvent
+		for _, m := range change.Messages {
+			if m.Author.AccountID != account.AccountID || m.Date.Before(since) || m.Date.After(until) {
+				continue
+			}
+			for _, d := range describeMessage(m.Message, &uploads, &votes, &comments) {
+				events = append(events, activityEvent{when: m.Date.Time, description: d})
+			}
+		}
+		if len(events) > 0 {
+			active = append(active, changeActivity{change: change, events: events})
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].events[0].when.Before(active[j].events[0].when)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Activity of %s from %s to %s: %s, %s and %s on %s\n",
+		formatAccount(account), since.Format("2006-01-02 15:04"), until.Format("2006-01-02 15:04"),
+		plural(uploads, "upload"), plural(votes, "vote"), plural(comments, "comment"), plural(len(active), "change"))
+	for _, a := range active {
+		fmt.Fprintf(&b, "\n%d %s: %s [%s]\n", a.change.Number, a.change.Project, a.change.Subject, a.change.Status)
+		for _, e := range a.events {
+			fmt.Fprintf(&b, "  %s %s\n", e.when.Format("2006-01-02 15:04"), e.description)
+		}
+	}
+	if n := len(*changes); n > 0 && (*changes)[n-1].MoreChanges {
+		fmt.Fprintf(&b, "\nWARNING: only the %d most recently updated changes were scanned; narrow the time window to see everything\n", maxActivityChanges)
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
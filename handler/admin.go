@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Admin serves a small REST API for operating a running server: flushing
+// the patch cache, reloading the configuration file and changing the log
+// level. It has no authentication of its own, so it must only listen on an
+// address operators alone can reach.
+type Admin struct {
+	// Cache is the patch cache to flush, if caching is enabled
+	Cache *CachingClient
+	// Notifier is the notifier whose log level can be changed
+	Notifier *Notifier
+	// Reload re-reads the configuration file, if there is one
+	Reload func() error
+}
+
+// Handler returns the HTTP handler of the admin API
+func (a *Admin) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /cache/flush", a.flushCache)
+	mux.HandleFunc("POST /config/reload", a.reloadConfig)
+	mux.HandleFunc("GET /log-level", a.getLogLevel)
+	mux.HandleFunc("PUT /log-level", a.setLogLevel)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (a *Admin) flushCache(w http.ResponseWriter, r *http.Request) {
+	if a.Cache == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("the patch cache is disabled"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"flushed": a.Cache.Flush()})
+}
+
+func (a *Admin) reloadConfig(w http.ResponseWriter, r *http.Request) {
+	if a.Reload == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no configuration file is set"))
+		return
+	}
+	if err := a.Reload(); err != nil {
+		// The previous configuration stays in effect
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+}
+
+func (a *Admin) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]mcp.LoggingLevel{"level": a.Notifier.Level()})
+}
+
+func (a *Admin) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("expected {\"level\": \"...\"}: %w", err))
+		return
+	}
+	level, err := ParseLogLevel(body.Level)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.Notifier.SetLevel(level)
+	writeJSON(w, http.StatusOK, map[string]mcp.LoggingLevel{"level": level})
+}
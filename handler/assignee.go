@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// assigneeRemovedIn is the first Gerrit release without the assignee, which
+// the attention set replaced
+const assigneeRemovedIn = "3.9"
+
+// assigneeInfo holds the assignee field of Gerrit's ChangeInfo, which
+// go-gerrit only has in newer versions
+type assigneeInfo struct {
+	Assignee *gerrit.AccountInfo `json:"assignee"`
+}
+
+// changeAssignee returns the account a change is assigned to, or nil
+func (h *Handler) changeAssignee(ctx context.Context, changeID string) (*gerrit.AccountInfo, error) {
+	var info assigneeInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "changes/"+changeID, nil, &info); err != nil {
+		return nil, fmt.Errorf("failed to get the assignee of change %s: %v", changeID, err)
+	}
+	return info.Assignee, nil
+}
+
+// formatAssignee describes the assignee of a change
+func formatAssignee(assignee *gerrit.AccountInfo) string {
+	if assignee == nil {
+		return "none"
+	}
+	return formatAccount(*assignee)
+}
+
+// GetGerritChangeAssignee reports who a change is assigned to, on Gerrit
+// releases that still have assignees
+func (h *Handler) GetGerritChangeAssignee(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, _, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	assignee, err := h.changeAssignee(ctx, changeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "assignee: %s", formatAssignee(assignee))
+	return mcp.NewToolResultText(b.String()), nil
+}
+
+// SetGerritChangeAssignee assigns a change to an account, or removes the
+// assignee when none is given
+func (h *Handler) SetGerritChangeAssignee(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	account := strings.TrimSpace(request.GetString("assignee", ""))
+
+	changeID, _, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+
+	path := fmt.Sprintf("changes/%s/assignee", changeID)
+	if account == "" {
+		// Gerrit answers 204 No Content if there was no assignee, so don't decode the response
+		if _, err := h.client.Call(ctx, http.MethodDelete, path, nil, nil); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to remove the assignee of change %s: %v", changeID, err)), nil
+		}
+		fmt.Fprintf(&b, "Removed the assignee of change %s", changeID)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	var assignee gerrit.AccountInfo
+	if _, err := h.client.Call(ctx, http.MethodPut, path, map[string]string{"assignee": account}, &assignee); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to assign change %s to %s: %v", changeID, account, err)), nil
+	}
+	fmt.Fprintf(&b, "Assigned change %s to %s", changeID, formatAccount(assignee))
+	return mcp.NewToolResultText(b.String()), nil
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// BackportSettings label the cherry-picks made by backport-gerrit-change.
+// {number} and {branch} in the topic and hashtags are replaced by the
+// original change's number and the target branch.
+type BackportSettings struct {
+	Topic    string   `json:"topic"`
+	Hashtags []string `json:"hashtags"`
+}
+
+// defaultBackportTopic groups the cherry-picks of a change unless configured otherwise
+const defaultBackportTopic = "backport-{number}"
+
+// cherryPickInput is Gerrit's CherryPickInput
+type cherryPickInput struct {
+	Destination    string `json:"destination"`
+	AllowConflicts bool   `json:"allow_conflicts,omitempty"`
+}
+
+// cherryPickResult holds the fields of the created change that the backport reports
+type cherryPickResult struct {
+	Number               int  `json:"_number"`
+	ContainsGitConflicts bool `json:"contains_git_conflicts"`
+}
+
+// hashtagsInput is Gerrit's HashtagsInput
+type hashtagsInput struct {
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+// topicInput is Gerrit's TopicInput
+type topicInput struct {
+	Topic string `json:"topic"`
+}
+
+// BackportGerritChange cherry-picks a change to several branches, labels the
+// cherry-picks with a common topic and hashtags, and adds the original
+// reviewers, reporting the outcome per branch
+func (h *Handler) BackportGerritChange(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	branchList, err := request.RequireString("branches")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var branches []string
+	for _, branch := range strings.Split(branchList, ",") {
+		if branch = strings.TrimSpace(branch); branch != "" {
+			branches = append(branches, branch)
+		}
+	}
+	if len(branches) == 0 {
+		return mcp.NewToolResultError("branches must name at least one branch"), nil
+	}
+
+	settings := h.cfg().Backport
+	topic := request.GetString("topic", settings.Topic)
+	if topic == "" {
+		topic = defaultBackportTopic
+	}
+	hashtags := settings.Hashtags
+	if value := request.GetString("hashtags", ""); value != "" {
+		hashtags = nil
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				hashtags = append(hashtags, tag)
+			}
+		}
+	}
+	allowConflicts := request.GetBool("allow_conflicts", false)
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "DETAILED_LABELS", "DETAILED_ACCOUNTS")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if change.Status != "MERGED" {
+		header = append(header, fmt.Sprintf("WARNING: change %s is %s, not merged; its current patchset was cherry-picked", changeID, change.Status))
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Backport of change %s (%s) to %s:\n", changeID, change.Subject, strings.Join(branches, ", "))
+	for _, branch := range branches {
+		expand := strings.NewReplacer("{number}", strconv.Itoa(change.Number), "{branch}", branch).Replace
+		if err := h.throttle(ctx); err != nil {
+			fmt.Fprintf(&b, "\n%s: SKIPPED: %v\n", branch, err)
+			continue
+		}
+
+		var picked cherryPickResult
+		path := fmt.Sprintf("changes/%s/revisions/%s/cherrypick", changeID, change.CurrentRevision)
+		if _, err := h.client.Call(ctx, http.MethodPost, path, cherryPickInput{Destination: branch, AllowConflicts: allowConflicts}, &picked); err != nil {
+			fmt.Fprintf(&b, "\n%s: FAILED: %v\n", branch, err)
+			if !allowConflicts && strings.Contains(strings.ToLower(err.Error()), "conflict") {
+				b.WriteString("  the change doesn't apply cleanly; retry with allow_conflicts to get a change with conflict markers to resolve\n")
+			}
+			continue
+		}
+
+		pickedID := strconv.Itoa(picked.Number)
+		fmt.Fprintf(&b, "\n%s: created change %s", branch, pickedID)
+		if picked.ContainsGitConflicts {
+			b.WriteString(", CONTAINS CONFLICTS to resolve before it can be submitted")
+		}
+		b.WriteString("\n")
+
+		// Labelling and reviewers are best effort; the cherry-pick exists either way
+		if _, err := h.client.Call(ctx, http.MethodPut, fmt.Sprintf("changes/%s/topic", pickedID), topicInput{Topic: expand(topic)}, nil); err != nil {
+			fmt.Fprintf(&b, "  topic: FAILED: %v\n", err)
+		} else {
+			fmt.Fprintf(&b, "  topic %s\n", expand(topic))
+		}
+		if len(hashtags) > 0 {
+			tags := make([]string, len(hashtags))
+			for i, tag := range hashtags {
+				tags[i] = expand(tag)
+			}
+			if _, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/hashtags", pickedID), hashtagsInput{Add: tags}, nil); err != nil {
+				fmt.Fprintf(&b, "  hashtags: FAILED: %v\n", err)
+			} else {
+				fmt.Fprintf(&b, "  hashtags %s\n", strings.Join(tags, ", "))
+			}
+		}
+		for _, reviewer := range change.Reviewers["REVIEWER"] {
+			if reviewer.AccountID == change.Owner.AccountID {
+				continue
+			}
+			var result reviewerResult
+			input := reviewerInput{Reviewer: strconv.Itoa(reviewer.AccountID)}
+			_, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/reviewers", pickedID), input, &result)
+			switch {
+			case err != nil:
+				fmt.Fprintf(&b, "  reviewer %s: FAILED: %v\n", formatAccount(reviewer), err)
+			case result.Error != "":
+				fmt.Fprintf(&b, "  reviewer %s: FAILED: %s\n", formatAccount(reviewer), result.Error)
+			default:
+				fmt.Fprintf(&b, "  reviewer %s added\n", formatAccount(reviewer))
+			}
+		}
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// blameInfo is Gerrit's BlameInfo
+type blameInfo struct {
+	Author string `json:"author"`
+	ID     string `json:"id"`
+	Time   int64  `json:"time"`
+	Ranges []struct {
+		Start int `json:"start"`
+		End   int `json:"end"`
+	} `json:"ranges"`
+}
+
+// maxBlameFiles bounds the number of files blamed for one change
+const maxBlameFiles = 50
+
+// blameHalfLife is the age at which a blamed line counts half as much as a
+// fresh one, since recent authors know the code best
+const blameHalfLife = 365 * 24 * time.Hour
+
+// touchedOldLines returns the lines of the old file that a file diff changes:
+// the removed lines, and for pure additions the line they follow
+func touchedOldLines(file FileDiff) []int {
+	var lines []int
+	for _, hunk := range file.Hunks {
+		old := hunk.OldStart
+		removed := false
+		for _, line := range hunk.Lines {
+			switch {
+			case strings.HasPrefix(line, "-"):
+				lines = append(lines, old)
+				old++
+				removed = true
+			case strings.HasPrefix(line, "+"):
+				if !removed && old > 1 && (len(lines) == 0 || lines[len(lines)-1] != old-1) {
+					lines = append(lines, old-1)
+				}
+			case strings.HasPrefix(line, `\`):
+			default:
+				old++
+				removed = false
+			}
+		}
+	}
+	return lines
+}
+
+// blameCandidate is an author of lines touched by a change
+type blameCandidate struct {
+	author string
+	email  string
+	lines  int
+	score  float64
+	latest time.Time
+	// commit is the author's commit with most touched lines, whose
+	// author email identifies them in Gerrit
+	commit      string
+	commitLines map[string]int
+}
+
+// SuggestGerritReviewersByBlame ranks the authors of the lines a change
+// modifies or deletes by the number of lines and how recently they wrote
+// them, and optionally adds the best as reviewers, like Gerrit's
+// reviewers-by-blame plugin
+func (h *Handler) SuggestGerritReviewersByBlame(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	limit := request.GetInt("limit", 5)
+	addTop := request.GetInt("add_top", 0)
+	if limit < 1 || addTop < 0 {
+		return mcp.NewToolResultError("limit must be at least 1 and add_top must not be negative"), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "DETAILED_ACCOUNTS", "DETAILED_LABELS")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	patch, err := h.currentPatch(ctx, changeID, change)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	candidates := make(map[string]*blameCandidate)
+	blamed := 0
+	for _, file := range ParsePatch(patch) {
+		if file.OldPath == "" {
+			continue
+		}
+		touched := touchedOldLines(file)
+		if len(touched) == 0 {
+			continue
+		}
+		if blamed == maxBlameFiles {
+			header = append(header, fmt.Sprintf("WARNING: only the first %d modified files were blamed", maxBlameFiles))
+			break
+		}
+		blamed++
+
+		var blames []blameInfo
+		path := fmt.Sprintf("changes/%s/revisions/%s/files/%s/blame?base=t", changeID, change.CurrentRevision, url.PathEscape(file.OldPath))
+		if _, err := h.client.Call(ctx, http.MethodGet, path, nil, &blames); err != nil {
+			header = append(header, fmt.Sprintf("WARNING: could not blame %s: %v", file.OldPath, err))
+			continue
+		}
+		for _, blame := range blames {
+			n := 0
+			for _, r := range blame.Ranges {
+				for _, line := range touched {
+					if line >= r.Start && line <= r.End {
+						n++
+					}
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			c, ok := candidates[blame.Author]
+			if !ok {
+				c = &blameCandidate{author: blame.Author, commitLines: make(map[string]int)}
+				candidates[blame.Author] = c
+			}
+			written := time.Unix(blame.Time, 0)
+			c.lines += n
+			c.score += float64(n) * math.Pow(0.5, float64(time.Since(written))/float64(blameHalfLife))
+			if written.After(c.latest) {
+				c.latest = written
+			}
+			c.commitLines[blame.ID] += n
+			if c.commitLines[blame.ID] > c.commitLines[c.commit] {
+				c.commit = blame.ID
+			}
+		}
+	}
+
+	ranked := make([]*blameCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		ranked = append(ranked, c)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].author < ranked[j].author
+	})
+
+	// Blame names authors only by name, so look up their email, and skip
+	// the change's owner and existing reviewers
+	existing := map[string]bool{change.Owner.Email: true}
+	for _, accounts := range change.Reviewers {
+		for _, a := range accounts {
+			existing[a.Email] = true
+		}
+	}
+	var suggested []*blameCandidate
+	for _, c := range ranked {
+		if len(suggested) == limit {
+			break
+		}
+		var commit gerrit.CommitInfo
+		if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("projects/%s/commits/%s", url.PathEscape(change.Project), c.commit), nil, &commit); err == nil {
+			c.email = commit.Author.Email
+		}
+		if c.email != "" && existing[c.email] {
+			continue
+		}
+		suggested = append(suggested, c)
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	if len(suggested) == 0 {
+		fmt.Fprintf(&b, "No reviewers to suggest for change %s: it modifies no lines written by anyone but its owner and current reviewers", changeID)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	fmt.Fprintf(&b, "Suggested reviewers for change %s by blame of the modified lines, best first:\n", changeID)
+	for i, c := range suggested {
+		name := c.author
+		if c.email != "" {
+			name = fmt.Sprintf("%s <%s>", c.author, c.email)
+		}
+		fmt.Fprintf(&b, "  %d. %s: %s, last touched %s\n", i+1, name, plural(c.lines, "line"), c.latest.UTC().Format("2006-01-02"))
+	}
+
+	if addTop > 0 {
+		b.WriteString("\n")
+		for _, c := range suggested[:min(addTop, len(suggested))] {
+			if c.email == "" {
+				fmt.Fprintf(&b, "%s: not added, no Gerrit account email found\n", c.author)
+				continue
+			}
+			var result reviewerResult
+			_, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/reviewers", changeID), reviewerInput{Reviewer: c.email}, &result)
+			switch {
+			case err != nil:
+				fmt.Fprintf(&b, "%s: FAILED: %v\n", c.email, err)
+			case result.Error != "":
+				fmt.Fprintf(&b, "%s: FAILED: %s\n", c.email, result.Error)
+			default:
+				fmt.Fprintf(&b, "%s: added as reviewer\n", c.email)
+			}
+		}
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
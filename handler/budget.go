@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultResponseBudget is the number of characters a tool may return unless
+// the configuration file says otherwise
+const DefaultResponseBudget = 32000
+
+// continuationHints tell the reader of a truncated response how to get the rest
+var continuationHints = map[string]string{
+	"get-gerrit-change":          "use list-gerrit-change-files and get-gerrit-change-hunks to fetch the rest file by file",
+	"get-gerrit-change-hunks":    "use the hunk parameter to fetch the remaining hunks one at a time",
+	"get-gerrit-change-comments": "use unresolved_only=true to see only the open threads, or page through them with limit and cursor",
+	"list-gerrit-comments":       "use unresolved_only=true to see only the open threads, or page through them with limit and cursor",
+	"query-gerrit-changes":       "use a lower limit and page with cursor",
+	"list-gerrit-projects":       "use a lower limit and page with cursor",
+	"list-gerrit-groups":         "use a lower limit and page with cursor",
+	"get-gerrit-activity":        "use a shorter time window with since and until",
+	"get-gerrit-stale-changes":   "use a lower limit or narrower criteria",
+}
+
+// wholeResponses are the tools whose responses are useless cut short, such
+// as JSON documents. Over budget they fail with the hint instead.
+var wholeResponses = map[string]string{
+	"export-gerrit-change": "raise the response budget of export-gerrit-change in the configuration file, or export without include_patch",
+}
+
+// responseBudget returns the maximum response size of a tool in characters,
+// or 0 if it is unlimited. Settings of the project, if known, take precedence.
+func (c *Config) responseBudget(project, tool string) int {
+	overrides := c.Projects[project].ResponseBudgets
+	if budget, ok := overrides[tool]; ok {
+		return budget
+	}
+	if budget, ok := overrides["default"]; ok {
+		return budget
+	}
+	if budget, ok := c.ResponseBudgets[tool]; ok {
+		return budget
+	}
+	if budget, ok := c.ResponseBudgets["default"]; ok {
+		return budget
+	}
+	return DefaultResponseBudget
+}
+
+// truncate cuts text to at most budget characters, preferably at the end of
+// a line, and reports whether it had to. The result shares text's memory,
+// so that multi-megabyte patches are not copied.
+func truncate(text string, budget int) (string, bool) {
+	if len(text) <= budget {
+		return text, false
+	}
+	end, n := 0, 0
+	for end < len(text) && n < budget {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+		n++
+	}
+	if end == len(text) {
+		return text, false
+	}
+	cut := text[:end]
+	if i := strings.LastIndexByte(cut, '\n'); i > len(cut)/2 {
+		cut = cut[:i+1]
+	}
+	return cut, true
+}
+
+// EnforceResponseBudgets truncates the text a tool returns to its configured
+// budget, telling the reader which tool to use to continue
+func (h *Handler) EnforceResponseBudgets(tool Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		budget := h.cfg().responseBudget(projectFromContext(ctx), tool.Tool.Name)
+		if err != nil || result == nil || result.IsError || budget == 0 {
+			return result, err
+		}
+
+		if hint, ok := wholeResponses[tool.Tool.Name]; ok {
+			size := 0
+			for _, content := range result.Content {
+				if text, ok := content.(mcp.TextContent); ok {
+					size += utf8.RuneCountInString(text.Text)
+				}
+			}
+			if size > budget {
+				return mcp.NewToolResultError(fmt.Sprintf("the response of %d characters exceeds the budget of %d and can't be cut short; %s", size, budget, hint)), nil
+			}
+			return result, nil
+		}
+
+		remaining := budget
+		for i, content := range result.Content {
+			text, ok := content.(mcp.TextContent)
+			if !ok {
+				continue
+			}
+			cut, truncated := truncate(text.Text, remaining)
+			remaining -= utf8.RuneCountInString(cut)
+			if !truncated {
+				continue
+			}
+
+			hint := continuationHints[tool.Tool.Name]
+			if hint == "" {
+				hint = "narrow the request to see the rest"
+			}
+			text.Text = strings.TrimRight(cut, "\n") + fmt.Sprintf("\n\nWARNING: response truncated at %d characters; %s", budget, hint)
+			result.Content = append(result.Content[:i], text)
+			break
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+)
+
+// prefetchTimeout bounds a background download nobody waits for yet
+const prefetchTimeout = 2 * time.Minute
+
+// Prefetcher loads what is usually asked for after a change's details into
+// a cache in the background
+type Prefetcher interface {
+	Prefetch(ctx context.Context, changeID string, change *gerrit.ChangeInfo)
+}
+
+// WithPrefetcher prefetches the files and patch of changes whose details
+// were served, unless they exceed the patch limits
+func WithPrefetcher(p Prefetcher) Option {
+	return func(h *Handler) {
+		h.prefetcher = p
+	}
+}
+
+// CachingClient is a GerritClient that caches patches and the file lists of
+// revisions. Both are addressed by the revision's commit SHA and therefore
+// never change, so entries need no expiry. Concurrent requests for the same
+// patch share one fetch. Patches are bounded by count and by bytes.
+type CachingClient struct {
+	next     GerritClient
+	size     int
+	maxBytes int64
+
+	mu      sync.Mutex
+	patches map[string]*patchEntry
+	order   []string
+	bytes   int64
+	// files are the file lists of revisions, and revisions the current
+	// revision each change was last seen at, so that a change whose files
+	// are cached can be fetched without them
+	files       map[string]map[string]gerrit.FileInfo
+	filesOrder  []string
+	revisions   map[string]string
+	prefetching map[string]bool
+}
+
+// patchEntry is a cached or in-flight patch; done is closed once it is fetched
+type patchEntry struct {
+	done  chan struct{}
+	patch *string
+	resp  *gerrit.Response
+	err   error
+	size  int64
+}
+
+// NewCachingClient wraps next with a cache holding up to size patches, and
+// up to maxBytes of them if maxBytes is positive
+func NewCachingClient(next GerritClient, size int, maxBytes int64) *CachingClient {
+	return &CachingClient{
+		next:        next,
+		size:        size,
+		maxBytes:    maxBytes,
+		patches:     make(map[string]*patchEntry),
+		files:       make(map[string]map[string]gerrit.FileInfo),
+		revisions:   make(map[string]string),
+		prefetching: make(map[string]bool),
+	}
+}
+
+// GetChange implements GerritClient interface. A change asked for with the
+// files of its current revision is fetched without them if they are cached
+// for the revision it was last seen at.
+func (c *CachingClient) GetChange(ctx context.Context, changeID string, opt *gerrit.ChangeOptions) (*gerrit.ChangeInfo, *gerrit.Response, error) {
+	if opt == nil || !slices.Contains(opt.AdditionalFields, "CURRENT_FILES") || !slices.Contains(opt.AdditionalFields, "CURRENT_REVISION") {
+		return c.next.GetChange(ctx, changeID, opt)
+	}
+	changeKey := connectionKey(ctx) + "/" + changeID
+
+	c.mu.Lock()
+	_, cached := c.files[changeKey+"/"+c.revisions[changeKey]]
+	c.mu.Unlock()
+	if !cached {
+		change, resp, err := c.next.GetChange(ctx, changeID, opt)
+		if err == nil && change != nil && change.CurrentRevision != "" {
+			if files := change.Revisions[change.CurrentRevision].Files; files != nil {
+				c.storeFiles(ctx, changeID, change.CurrentRevision, files)
+			}
+		}
+		return change, resp, err
+	}
+
+	without := *opt
+	without.AdditionalFields = slices.DeleteFunc(slices.Clone(opt.AdditionalFields), func(f string) bool { return f == "CURRENT_FILES" })
+	change, resp, err := c.next.GetChange(ctx, changeID, &without)
+	if err != nil || change == nil || change.CurrentRevision == "" {
+		return change, resp, err
+	}
+	files, err := c.revisionFiles(ctx, changeID, change.CurrentRevision)
+	if err != nil {
+		return nil, resp, err
+	}
+	revision := change.Revisions[change.CurrentRevision]
+	revision.Files = files
+	if change.Revisions == nil {
+		change.Revisions = make(map[string]gerrit.RevisionInfo)
+	}
+	change.Revisions[change.CurrentRevision] = revision
+	return change, resp, nil
+}
+
+// revisionFiles returns the file list of a revision, from the cache if it
+// is there and from Gerrit otherwise
+func (c *CachingClient) revisionFiles(ctx context.Context, changeID, revisionID string) (map[string]gerrit.FileInfo, error) {
+	c.mu.Lock()
+	files, ok := c.files[connectionKey(ctx)+"/"+changeID+"/"+revisionID]
+	c.mu.Unlock()
+	if ok {
+		return maps.Clone(files), nil
+	}
+	path := fmt.Sprintf("changes/%s/revisions/%s/files", url.PathEscape(changeID), revisionID)
+	if _, err := c.next.Call(ctx, http.MethodGet, path, nil, &files); err != nil {
+		return nil, fmt.Errorf("failed to list the files of change %s: %w", changeID, err)
+	}
+	c.storeFiles(ctx, changeID, revisionID, files)
+	return maps.Clone(files), nil
+}
+
+// storeFiles caches the file list of a revision, which is now the change's
+// current one
+func (c *CachingClient) storeFiles(ctx context.Context, changeID, revisionID string, files map[string]gerrit.FileInfo) {
+	changeKey := connectionKey(ctx) + "/" + changeID
+	key := changeKey + "/" + revisionID
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.revisions[changeKey] = revisionID
+	if _, ok := c.files[key]; ok {
+		return
+	}
+	c.files[key] = maps.Clone(files)
+	c.filesOrder = append(c.filesOrder, key)
+	for len(c.filesOrder) > c.size {
+		delete(c.files, c.filesOrder[0])
+		c.filesOrder = c.filesOrder[1:]
+	}
+}
+
+// Prefetch downloads the file list and patch of the change's current
+// revision into the cache in the background, since they are usually asked
+// for right after the change's details. Only one prefetch runs per revision,
+// and patches that wouldn't fit the cache are not prefetched. The download
+// runs on its own deadline, outliving the call that started it, and keeps
+// only the call's credentials so that it uses the same connection.
+func (c *CachingClient) Prefetch(ctx context.Context, changeID string, change *gerrit.ChangeInfo) {
+	if change.CurrentRevision == "" || c.maxBytes > 0 && estimatePatchSize(change) > c.maxBytes {
+		return
+	}
+	if files := change.Revisions[change.CurrentRevision].Files; files != nil {
+		c.storeFiles(ctx, changeID, change.CurrentRevision, files)
+	}
+
+	key := connectionKey(ctx) + "/" + changeID + "/" + change.CurrentRevision
+	c.mu.Lock()
+	running := c.prefetching[key]
+	c.prefetching[key] = true
+	c.mu.Unlock()
+	if running {
+		return
+	}
+
+	prefetchCtx := context.Background()
+	if creds, ok := CredentialsFromContext(ctx); ok {
+		prefetchCtx = ContextWithCredentials(prefetchCtx, creds)
+	}
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.prefetching, key)
+			c.mu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(prefetchCtx, prefetchTimeout)
+		defer cancel()
+		if _, err := c.revisionFiles(ctx, changeID, change.CurrentRevision); err != nil {
+			return
+		}
+		c.GetPatch(ctx, changeID, change.CurrentRevision, &gerrit.PatchOptions{})
+	}()
+}
+
+// isContextError tells whether err is the cancellation or deadline of a
+// context, which belongs to the call that ran into it and not to the request
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// GetPatch implements GerritClient interface
+func (c *CachingClient) GetPatch(ctx context.Context, changeID, revisionID string, opt *gerrit.PatchOptions) (*string, *gerrit.Response, error) {
+	// Sessions with different credentials may see different changes
+	key := fmt.Sprintf("%s/%s/%s/%+v", connectionKey(ctx), changeID, revisionID, opt)
+
+	for {
+		c.mu.Lock()
+		entry, ok := c.patches[key]
+		if !ok {
+			entry = &patchEntry{done: make(chan struct{})}
+			c.patches[key] = entry
+			c.order = append(c.order, key)
+			c.evict()
+		}
+		c.mu.Unlock()
+
+		if !ok {
+			return c.fetchPatch(ctx, key, entry, changeID, revisionID, opt)
+		}
+		select {
+		case <-entry.done:
+			// The fetch was cancelled for the caller that started it, not
+			// for this one, which retries
+			if isContextError(entry.err) && ctx.Err() == nil {
+				continue
+			}
+			return entry.patch, entry.resp, entry.err
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// fetchPatch fetches the patch of a new entry and drops the entry on failure
+func (c *CachingClient) fetchPatch(ctx context.Context, key string, entry *patchEntry, changeID, revisionID string, opt *gerrit.PatchOptions) (*string, *gerrit.Response, error) {
+	entry.patch, entry.resp, entry.err = c.next.GetPatch(ctx, changeID, revisionID, opt)
+	c.mu.Lock()
+	// The cache may have been flushed and the key reused meanwhile
+	if c.patches[key] == entry {
+		if entry.err != nil {
+			c.remove(key)
+		} else if entry.patch != nil {
+			entry.size = int64(len(*entry.patch))
+			c.bytes += entry.size
+			c.evict()
+		}
+	}
+	c.mu.Unlock()
+	close(entry.done)
+	return entry.patch, entry.resp, entry.err
+}
+
+// QueryChanges implements GerritClient interface; query results are not cached
+func (c *CachingClient) QueryChanges(ctx context.Context, opt *gerrit.QueryChangeOptions) (*[]gerrit.ChangeInfo, *gerrit.Response, error) {
+	return c.next.QueryChanges(ctx, opt)
+}
+
+// SetReview implements GerritClient interface
+func (c *CachingClient) SetReview(ctx context.Context, changeID, revisionID string, input *gerrit.ReviewInput) (*gerrit.ReviewResult, *gerrit.Response, error) {
+	return c.next.SetReview(ctx, changeID, revisionID, input)
+}
+
+// Call implements GerritClient interface; its responses are not cached
+func (c *CachingClient) Call(ctx context.Context, method, path string, body, v any) (*gerrit.Response, error) {
+	return c.next.Call(ctx, method, path, body, v)
+}
+
+// Flush empties the cache and returns the number of patches dropped. Fetches
+// in flight complete for their callers but are not kept.
+func (c *CachingClient) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.patches)
+	c.patches = make(map[string]*patchEntry)
+	c.order = nil
+	c.bytes = 0
+	c.files = make(map[string]map[string]gerrit.FileInfo)
+	c.filesOrder = nil
+	c.revisions = make(map[string]string)
+	return n
+}
+
+// evict drops the oldest entries beyond the cache's size or bytes; callers
+// hold c.mu
+func (c *CachingClient) evict() {
+	for len(c.order) > c.size || c.maxBytes > 0 && c.bytes > c.maxBytes && len(c.order) > 0 {
+		c.bytes -= c.patches[c.order[0]].size
+		delete(c.patches, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// remove drops the entry for key; callers hold c.mu
+func (c *CachingClient) remove(key string) {
+	c.bytes -= c.patches[key].size
+	delete(c.patches, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// requestIDMetaKey is the _meta field used to carry the JSON-RPC request ID
+// from the BeforeCallTool hook to the tool middleware, which only sees the request
+const requestIDMetaKey = "gerrit-code-review-mcp/request-id"
+
+// CallTracker gives every tool call its own cancellable context, bounded by a
+// per-call timeout, and cancels it when the client sends notifications/cancelled
+// for the request. Since go-gerrit builds its HTTP requests from that context,
+// cancelling it aborts in-flight Gerrit requests such as large patch downloads.
+type CallTracker struct {
+	timeout time.Duration
+
+	mu    sync.Mutex
+	calls map[string]context.CancelFunc
+}
+
+// NewCallTracker creates a CallTracker. A zero timeout disables the deadline.
+func NewCallTracker(timeout time.Duration) *CallTracker {
+	return &CallTracker{
+		timeout: timeout,
+		calls:   make(map[string]context.CancelFunc),
+	}
+}
+
+// callKey identifies a request within its session; request IDs are only unique per session
+func callKey(ctx context.Context, id any) string {
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return fmt.Sprintf("%s/%v", sessionID, id)
+}
+
+// BeforeCallTool is a server hook recording the request ID on the tool call
+func (t *CallTracker) BeforeCallTool(ctx context.Context, id any, message *mcp.CallToolRequest) {
+	if message.Params.Meta == nil {
+		message.Params.Meta = &mcp.Meta{}
+	}
+	if message.Params.Meta.AdditionalFields == nil {
+		message.Params.Meta.AdditionalFields = make(map[string]any)
+	}
+	message.Params.Meta.AdditionalFields[requestIDMetaKey] = id
+}
+
+// HandleCancelled is the notifications/cancelled handler
+func (t *CallTracker) HandleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	id, ok := notification.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	cancel, ok := t.calls[callKey(ctx, id)]
+	t.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// Middleware runs each tool call under its own deadline and registers it for cancellation
+func (t *CallTracker) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var cancel context.CancelFunc
+		if t.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
+		defer cancel()
+
+		if request.Params.Meta != nil {
+			if id, ok := request.Params.Meta.AdditionalFields[requestIDMetaKey]; ok {
+				key := callKey(ctx, id)
+				t.mu.Lock()
+				t.calls[key] = cancel
+				t.mu.Unlock()
+				defer func() {
+					t.mu.Lock()
+					delete(t.calls, key)
+					t.mu.Unlock()
+				}()
+			}
+		}
+
+		result, err := next(ctx, request)
+
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return mcp.NewToolResultError(fmt.Sprintf("tool call %s timed out after %s", request.Params.Name, t.timeout)), nil
+		case errors.Is(ctx.Err(), context.Canceled):
+			return mcp.NewToolResultError(fmt.Sprintf("tool call %s was cancelled", request.Params.Name)), nil
+		}
+		return result, err
+	}
+}
+
+// StdioCancellation lets notifications/cancelled reach calls in flight on the
+// stdio transport, whose server reads the next message only once the current
+// one was handled. It reads stdin ahead, handles cancellations as soon as they
+// arrive and passes every other message on to the stdio server in order.
+type StdioCancellation struct {
+	server *server.MCPServer
+
+	once  sync.Once
+	ready chan struct{}
+	ctx   context.Context
+}
+
+// NewStdioCancellation creates a StdioCancellation for s
+func NewStdioCancellation(s *server.MCPServer) *StdioCancellation {
+	return &StdioCancellation{server: s, ready: make(chan struct{})}
+}
+
+// ContextFunc is the stdio server's context function. It records the
+// session's context, which cancellations are handled with.
+func (c *StdioCancellation) ContextFunc(ctx context.Context) context.Context {
+	c.once.Do(func() {
+		c.ctx = ctx
+		close(c.ready)
+	})
+	return ctx
+}
+
+// Reader returns the messages read from in, less the cancellations
+func (c *StdioCancellation) Reader(in io.Reader) io.Reader {
+	r, w := io.Pipe()
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				var message struct {
+					Method string `json:"method"`
+				}
+				if json.Unmarshal(line, &message) == nil && message.Method == "notifications/cancelled" {
+					<-c.ready
+					c.server.HandleMessage(c.ctx, line)
+				} else if _, err := w.Write(line); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return r
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Requirement is what a tool needs from the Gerrit server. Zero fields
+// mean no requirement.
+type Requirement struct {
+	// MinVersion is the oldest Gerrit release providing the REST endpoints the tool uses, e.g. "3.3"
+	MinVersion string
+	// Before is the first Gerrit release without the REST endpoints the tool uses
+	Before string
+	// Plugin is the name of a Gerrit plugin the tool needs
+	Plugin string
+}
+
+// Capabilities is what the Gerrit server was found to support
+type Capabilities struct {
+	Version string
+	// Plugins is nil if the account may not list plugins, in which case
+	// plugin requirements are assumed to be met
+	Plugins map[string]bool
+}
+
+// ProbeCapabilities asks the Gerrit server for its version and installed
+// plugins and records them, so that tools the server cannot support can be
+// hidden and reported by get-server-status
+func (h *Handler) ProbeCapabilities(ctx context.Context) (*Capabilities, error) {
+	caps := &Capabilities{}
+	if _, err := h.client.Call(ctx, http.MethodGet, "config/server/version", nil, &caps.Version); err != nil {
+		return nil, fmt.Errorf("get the Gerrit version: %w", err)
+	}
+
+	// Listing plugins needs the View Plugins capability
+	var plugins map[string]struct{}
+	if _, err := h.client.Call(ctx, http.MethodGet, "plugins/", nil, &plugins); err == nil {
+		caps.Plugins = make(map[string]bool, len(plugins))
+		for name := range plugins {
+			caps.Plugins[name] = true
+		}
+	}
+
+	h.capabilities.Store(caps)
+	return caps, nil
+}
+
+// Supports reports whether the server meets req, and if not, why
+func (c *Capabilities) Supports(req Requirement) (bool, string) {
+	if c == nil {
+		return true, ""
+	}
+	if req.MinVersion != "" && !versionAtLeast(c.Version, req.MinVersion) {
+		return false, fmt.Sprintf("needs Gerrit %s or later, the server runs %s", req.MinVersion, c.Version)
+	}
+	if req.Before != "" && versionAtLeast(c.Version, req.Before) {
+		return false, fmt.Sprintf("was removed in Gerrit %s, the server runs %s", req.Before, c.Version)
+	}
+	if req.Plugin != "" && c.Plugins != nil && !c.Plugins[req.Plugin] {
+		return false, fmt.Sprintf("needs the %s plugin, which is not installed", req.Plugin)
+	}
+	return true, ""
+}
+
+// versionAtLeast compares Gerrit versions such as "3.9.1" or
+// "3.10.0-rc1-12-gabcdef" by their numeric components. A version that
+// can't be parsed is assumed to be recent enough.
+func versionAtLeast(version, min string) bool {
+	have, ok := parseVersion(version)
+	if !ok {
+		return true
+	}
+	want, _ := parseVersion(min)
+	for i := range max(len(have), len(want)) {
+		var h, w int
+		if i < len(have) {
+			h = have[i]
+		}
+		if i < len(want) {
+			w = want[i]
+		}
+		if h != w {
+			return h > w
+		}
+	}
+	return true
+}
+
+func parseVersion(version string) ([]int, bool) {
+	version, _, _ = strings.Cut(version, "-")
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts, len(parts) > 0
+}
+
+// writeCapabilities writes the probed capabilities and the tools hidden
+// because the server lacks what they need
+func (h *Handler) writeCapabilities(b *strings.Builder) {
+	caps := h.capabilities.Load()
+	if caps == nil {
+		b.WriteString("Capabilities: not probed yet, all tools are served\n")
+		return
+	}
+
+	fmt.Fprintf(b, "Gerrit version: %s\n", caps.Version)
+	if caps.Plugins == nil {
+		b.WriteString("Plugins: unknown, the account may not list them\n")
+	} else {
+		plugins := make([]string, 0, len(caps.Plugins))
+		for name := range caps.Plugins {
+			plugins = append(plugins, name)
+		}
+		sort.Strings(plugins)
+		fmt.Fprintf(b, "Plugins: %s\n", strings.Join(plugins, ", "))
+	}
+	for _, t := range h.Tools() {
+		if ok, reason := caps.Supports(t.Requires); !ok {
+			fmt.Fprintf(b, "Disabled: %s (%s)\n", t.Tool.Name, reason)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ChecklistRule adds review checklist items for changes touching certain paths
+type ChecklistRule struct {
+	// Paths are globs in the same syntax as component paths
+	Paths []string `json:"paths"`
+	Items []string `json:"items"`
+
+	patterns []*regexp.Regexp
+}
+
+// compile prepares the rule's path patterns
+func (r *ChecklistRule) compile() error {
+	if len(r.Items) == 0 {
+		return fmt.Errorf("checklist rule for %s has no items", strings.Join(r.Paths, ", "))
+	}
+	r.patterns = nil
+	for _, p := range r.Paths {
+		re, err := globRegexp(p)
+		if err != nil {
+			return fmt.Errorf("checklist rule: %w", err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return nil
+}
+
+// matches returns the files matched by the rule
+func (r ChecklistRule) matches(files []string) []string {
+	var matched []string
+	for _, f := range files {
+		for _, re := range r.patterns {
+			if re.MatchString(f) {
+				matched = append(matched, f)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// GetGerritChangeChecklist builds a review checklist for a change from the
+// configured rules matching its files, together with its diffstat
+func (h *Handler) GetGerritChangeChecklist(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	_, change, header, err := h.lookupChange(ctx, changeURL, "CURRENT_FILES")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	files := changedFiles(change)
+	paths := sortedKeys(files)
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+
+	b.WriteString("Review checklist:\n")
+	seen := make(map[string]bool)
+	n := 0
+	for _, rule := range h.cfg().Checklists {
+		matched := rule.matches(paths)
+		if len(matched) == 0 {
+			continue
+		}
+		because := matched[0]
+		if len(matched) > 1 {
+			because += fmt.Sprintf(" and %s", plural(len(matched)-1, "other file"))
+		}
+		for _, item := range rule.Items {
+			if seen[item] {
+				continue
+			}
+			seen[item] = true
+			n++
+			fmt.Fprintf(&b, "[ ] %s (%s)\n", item, because)
+		}
+	}
+	if n == 0 {
+		b.WriteString("No checklist rules match the files of this change\n")
+	}
+
+	fmt.Fprintf(&b, "\n%s, +%d -%d lines:\n", plural(len(files), "file"), change.Insertions, change.Deletions)
+	for _, path := range paths {
+		info := files[path]
+		status := info.Status
+		if status == "" {
+			status = "M"
+		}
+		fmt.Fprintf(&b, "%s %s +%d -%d\n", status, path, info.LinesInserted, info.LinesDeleted)
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
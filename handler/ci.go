@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Bounds of get-gerrit-ci-failure-log
+const (
+	defaultCILogBytes   = 1 << 20
+	maxCILogBytes       = 10 << 20
+	maxCILogDownload    = 200 << 20
+	defaultCILogContext = 5
+	defaultCILogChars   = 8000
+	// ciLogTailLines are shown when a log has no recognisable failure
+	ciLogTailLines = 30
+)
+
+var (
+	ciLinkRegexp    = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+	ciFailureRegexp = regexp.MustCompile(`(?i)\b(?:fail(?:ed|ure|ures|s)?|errors?|timed out|timeout|crash(?:ed)?|panic)\b`)
+)
+
+// ciLink is a URL in a CI message, usually of a build or its logs
+type ciLink struct {
+	url string
+	// context is the rest of the line the link was on, e.g. the job name
+	// and its result
+	context string
+}
+
+// ciReport is what a CI system reported on a patchset in a change message
+type ciReport struct {
+	message gerrit.ChangeMessageInfo
+	// vote is the report's vote on the CI label, 0 if it didn't vote
+	vote     int
+	failed   bool
+	links    []ciLink
+	failures []string
+}
+
+// ciVote returns the CI label vote a change message announces
+func ciVote(message string) (int, bool) {
+	firstLine, _, _ := strings.Cut(message, "\n")
+	rest, ok := strings.CutPrefix(firstLine, "Patch Set ")
+	if !ok {
+		return 0, false
+	}
+	_, labels, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0, false
+	}
+	for _, m := range voteRegexp.FindAllStringSubmatch(labels, -1) {
+		if m[1] == ciLabel {
+			n, _ := strconv.Atoi(m[2])
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// isCIMessage reports whether a change message comes from a CI system: one
+// tagged as autogenerated by something other than Gerrit, or voting on the
+// CI label
+func isCIMessage(m gerrit.ChangeMessageInfo) bool {
+	if strings.HasPrefix(m.Tag, "autogenerated:") && !strings.HasPrefix(m.Tag, "autogenerated:gerrit:") {
+		return true
+	}
+	_, voted := ciVote(m.Message)
+	return voted
+}
+
+// parseCIReport extracts the vote, links and failure lines of a CI message
+func parseCIReport(m gerrit.ChangeMessageInfo) ciReport {
+	r := ciReport{message: m}
+	r.vote, _ = ciVote(m.Message)
+	for i, line := range strings.Split(m.Message, "\n") {
+		if i == 0 && strings.HasPrefix(line, "Patch Set ") {
+			// The vote
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rest := line
+		var links []string
+		for _, link := range ciLinkRegexp.FindAllString(line, -1) {
+			link = strings.TrimRight(link, ".,;:!?")
+			rest = strings.Replace(rest, link, "", 1)
+			links = append(links, link)
+		}
+		// The links of a line share the rest of it as their context
+		rest = strings.Trim(strings.Join(strings.Fields(rest), " "), "-*: ")
+		for _, link := range links {
+			r.links = append(r.links, ciLink{url: link, context: rest})
+		}
+		if ciFailureRegexp.MatchString(rest) {
+			r.failures = append(r.failures, line)
+		}
+	}
+	r.failed = r.vote < 0 || r.vote == 0 && len(r.failures) > 0
+	return r
+}
+
+// ciReports returns the reports of CI systems on a change, oldest first. The
+// change must have been fetched with MESSAGES.
+func ciReports(change *gerrit.ChangeInfo) []ciReport {
+	var reports []ciReport
+	for _, m := range change.Messages {
+		if isCIMessage(m) {
+			reports = append(reports, parseCIReport(m))
+		}
+	}
+	return reports
+}
+
+// failureLink picks the link of a report most likely to lead to the log of
+// a failure
+func (r ciReport) failureLink() string {
+	for _, link := range r.links {
+		if ciFailureRegexp.MatchString(link.context) {
+			return link.url
+		}
+	}
+	if len(r.links) > 0 {
+		return r.links[0].url
+	}
+	return ""
+}
+
+// fetchLogTail downloads a log and returns its last maxBytes, since failures
+// are usually reported at the end, along with the size of the whole log.
+// Logs compressed with gzip are decompressed.
+func fetchLogTail(ctx context.Context, link string, maxBytes int) (string, int64, error) {
+	if err := reserveMemory(ctx, 2*int64(maxBytes)); err != nil {
+		return "", 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", 0, fmt.Errorf("%s", resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	if u, err := url.Parse(link); err == nil && strings.HasSuffix(u.Path, ".gz") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return "", 0, fmt.Errorf("decompress: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	var buf []byte
+	chunk := make([]byte, 32<<10)
+	var size int64
+	for size < maxCILogDownload {
+		n, err := body.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		size += int64(n)
+		if len(buf) > 2*maxBytes {
+			buf = append(buf[:0], buf[len(buf)-maxBytes:]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", size, err
+		}
+	}
+	if len(buf) > maxBytes {
+		buf = buf[len(buf)-maxBytes:]
+	}
+	return strings.ToValidUTF8(string(buf), "\uFFFD"), size, nil
+}
+
+// logExcerpt returns the lines of a log around its failures, contextLines
+// before and after each, the last failures first to go when the excerpt
+// would exceed maxChars. Without failures it returns the end of the log.
+func logExcerpt(log string, contextLines, maxChars int) (string, int) {
+	lines := strings.Split(strings.TrimRight(log, "\n"), "\n")
+	type window struct{ from, to int }
+	var windows []window
+	failures := 0
+	for i, line := range lines {
+		if !ciFailureRegexp.MatchString(line) {
+			continue
+		}
+		failures++
+		from, to := max(i-contextLines, 0), min(i+contextLines+1, len(lines))
+		if n := len(windows); n > 0 && from <= windows[n-1].to {
+			windows[n-1].to = to
+			continue
+		}
+		windows = append(windows, window{from, to})
+	}
+	if len(windows) == 0 {
+		windows = []window{{max(len(lines)-ciLogTailLines, 0), len(lines)}}
+	}
+
+	// The failures at the end of a log are the likeliest causes, so the
+	// excerpt is filled from the end
+	var parts []string
+	size := 0
+	for i := len(windows) - 1; i >= 0; i-- {
+		part := strings.Join(lines[windows[i].from:windows[i].to], "\n")
+		if size+len(part) > maxChars && len(parts) > 0 {
+			break
+		}
+		if len(part) > maxChars {
+			part = strings.ToValidUTF8(part[len(part)-maxChars:], "")
+		}
+		parts = append([]string{part}, parts...)
+		size += len(part)
+	}
+	return strings.Join(parts, "\n...\n"), failures
+}
+
+// GetGerritCIFailureLog explains a failing CI vote: it finds the CI report on
+// a patchset, lists its failures and links, and downloads an excerpt of the
+// failure's log around the lines reporting errors
+func (h *Handler) GetGerritCIFailureLog(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	maxBytes := min(request.GetInt("max_bytes", defaultCILogBytes), maxCILogBytes)
+	contextLines := request.GetInt("context", defaultCILogContext)
+	maxChars := request.GetInt("max_chars", defaultCILogChars)
+	if maxBytes <= 0 || maxChars <= 0 || contextLines < 0 {
+		return mcp.NewToolResultError("max_bytes and max_chars must be positive and context must not be negative"), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "MESSAGES")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	patchSet := request.GetInt("patchset", change.Revisions[change.CurrentRevision].Number)
+
+	reports := ciReports(change)
+	var onPatchSet []ciReport
+	for _, r := range reports {
+		if r.message.RevisionNumber == patchSet {
+			onPatchSet = append(onPatchSet, r)
+		}
+	}
+	var report *ciReport
+	for i := range onPatchSet {
+		if onPatchSet[i].failed {
+			report = &onPatchSet[i]
+		}
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	if report == nil {
+		if len(onPatchSet) == 0 {
+			fmt.Fprintf(&b, "No CI results on patch set %d of change %s", patchSet, changeID)
+		} else {
+			fmt.Fprintf(&b, "CI reported no failure on patch set %d of change %s (%s)", patchSet, changeID, plural(len(onPatchSet), "CI message"))
+		}
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	fmt.Fprintf(&b, "CI failure on patch set %d: %s", patchSet, formatAccount(report.message.Author))
+	if report.vote != 0 {
+		fmt.Fprintf(&b, " voted %s%+d", ciLabel, report.vote)
+	}
+	fmt.Fprintf(&b, " at %s\n", report.message.Date.UTC().Format("2006-01-02 15:04"))
+	if len(report.failures) > 0 {
+		b.WriteString("Failures reported:\n")
+		for _, failure := range report.failures {
+			fmt.Fprintf(&b, "  %s\n", failure)
+		}
+	}
+	if len(report.links) > 0 {
+		b.WriteString("Links:\n")
+		for _, link := range report.links {
+			if link.context != "" {
+				fmt.Fprintf(&b, "  %s: %s\n", link.context, link.url)
+			} else {
+				fmt.Fprintf(&b, "  %s\n", link.url)
+			}
+		}
+	}
+
+	// Only links CI posted on the change are fetched, not arbitrary URLs
+	link := request.GetString("link", "")
+	if link != "" {
+		posted := false
+		for _, r := range reports {
+			for _, l := range r.links {
+				posted = posted || l.url == link
+			}
+		}
+		if !posted {
+			return mcp.NewToolResultError(fmt.Sprintf("%s was not posted by CI on change %s; only the links of CI messages can be fetched", link, changeID)), nil
+		}
+	} else if link = report.failureLink(); link == "" {
+		b.WriteString("\nThe CI message links to no log to fetch")
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	log, size, err := fetchLogTail(ctx, link, maxBytes)
+	if err != nil {
+		fmt.Fprintf(&b, "\nFAILED to fetch %s: %v", link, err)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+	if strings.ContainsRune(log, 0) {
+		fmt.Fprintf(&b, "\n%s is not a text log; pass the link of a log file", link)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+	excerpt, failures := logExcerpt(log, contextLines, maxChars)
+	fmt.Fprintf(&b, "\nLog excerpt of %s", link)
+	if int64(len(log)) < size {
+		fmt.Fprintf(&b, " (last %d of %d bytes read)", len(log), size)
+	}
+	if failures > 0 {
+		fmt.Fprintf(&b, ", around %s:\n", plural(failures, "failure line"))
+	} else {
+		b.WriteString(", no failure lines recognised, showing its end:\n")
+	}
+	b.WriteString(excerpt)
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Bounds of get-gerrit-topic-ci-failures
+const (
+	defaultClusterChanges = 50
+	// clusterLogBytes is how much of the end of each failing job's log is
+	// read when clustering by log
+	clusterLogBytes = 256 << 10
+)
+
+// ciVolatileRegexp matches the parts of a failure line that differ between
+// runs of the same failure: timestamps, durations, numbers and hashes
+var ciVolatileRegexp = regexp.MustCompile(`\d{4}-\d\d-\d\d[T ]\d\d:\d\d:\d\d(?:[.,]\d+)?Z?|\b\d+(?:\.\d+)?(?:ms|s|m|h)\b|\b[0-9a-f]*\d[0-9a-f]*\b`)
+
+// failureSignature reduces a failure line to what identical failures have in
+// common, so that the same failure in different runs compares equal
+func failureSignature(line string) string {
+	line = ciLinkRegexp.ReplaceAllString(line, "")
+	line = ciVolatileRegexp.ReplaceAllString(line, "N")
+	return strings.Trim(strings.Join(strings.Fields(line), " "), "-*: ")
+}
+
+// failingJobs returns the failure lines of a report that name a job, those
+// with a link, or all its failure lines if none does
+func (r ciReport) failingJobs() []string {
+	var jobs []string
+	for _, line := range r.failures {
+		if ciLinkRegexp.MatchString(line) {
+			jobs = append(jobs, line)
+		}
+	}
+	if len(jobs) == 0 {
+		return r.failures
+	}
+	return jobs
+}
+
+// ciFailureMember is a change failing with the failure of a cluster
+type ciFailureMember struct {
+	change gerrit.ChangeInfo
+	// line is the failure as reported on the change
+	line string
+}
+
+// ciCluster is a failure shared by one or more changes
+type ciCluster struct {
+	signature string
+	members   []ciFailureMember
+}
+
+// changeCount returns the number of changes failing with the cluster's failure
+func (c *ciCluster) changeCount() int {
+	changes := make(map[int]bool)
+	for _, m := range c.members {
+		changes[m.change.Number] = true
+	}
+	return len(changes)
+}
+
+// ciClusterFields are the fields the changes of a topic or chain are fetched
+// with, to find their CI reports on the current patchset
+var ciClusterFields = []string{"CURRENT_REVISION", "MESSAGES"}
+
+// topicChanges returns the changes of a topic
+func (h *Handler) topicChanges(ctx context.Context, topic string, limit int) ([]gerrit.ChangeInfo, error) {
+	changes, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{"topic:" + strconv.Quote(topic)}, Limit: limit},
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: ciClusterFields},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the changes of topic %s: %v", topic, err)
+	}
+	return *changes, nil
+}
+
+// chainChanges returns the changes of the relation chain of a change
+func (h *Handler) chainChanges(ctx context.Context, change *gerrit.ChangeInfo, limit int) ([]gerrit.ChangeInfo, error) {
+	var related relatedChangesInfo
+	path := fmt.Sprintf("changes/%d/revisions/current/related", change.Number)
+	if _, err := h.client.Call(ctx, http.MethodGet, path, nil, &related); err != nil {
+		return nil, fmt.Errorf("failed to get the relation chain of change %d: %v", change.Number, err)
+	}
+	terms := []string{"change:" + strconv.Itoa(change.Number)}
+	for _, r := range related.Changes {
+		if r.ChangeNumber != 0 && r.ChangeNumber != change.Number {
+			terms = append(terms, "change:"+strconv.Itoa(r.ChangeNumber))
+		}
+	}
+	changes, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{strings.Join(terms, " OR ")}, Limit: limit},
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: ciClusterFields},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the relation chain of change %d: %v", change.Number, err)
+	}
+	return *changes, nil
+}
+
+// ClusterGerritCIFailures collects the CI failures of the current patchsets
+// of a topic's or relation chain's changes and groups identical ones, to
+// tell a failure shared by every change, e.g. a flaky test, from changes
+// that are broken on their own
+func (h *Handler) ClusterGerritCIFailures(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	topic := strings.TrimSpace(request.GetString("topic", ""))
+	changeURL := request.GetString("change_url", "")
+	withLogs := request.GetBool("logs", false)
+	limit := request.GetInt("limit", defaultClusterChanges)
+	if limit <= 0 {
+		return mcp.NewToolResultError("limit must be positive"), nil
+	}
+
+	var header []string
+	var changes []gerrit.ChangeInfo
+	var scope string
+	var err error
+	if topic == "" && changeURL != "" {
+		var change *gerrit.ChangeInfo
+		_, change, header, err = h.lookupChange(ctx, changeURL)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		topic = change.Topic
+		if topic == "" {
+			// Without a topic, the changes stacked with it
+			if changes, err = h.chainChanges(ctx, change, limit); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			scope = fmt.Sprintf("Relation chain of change %d", change.Number)
+		}
+	}
+	switch {
+	case topic != "":
+		if changes, err = h.topicChanges(ctx, topic, limit); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		scope = "Topic " + topic
+	case changeURL == "":
+		return mcp.NewToolResultError("either topic or change_url is required"), nil
+	}
+	return h.renderCIClusters(ctx, header, scope, changes, withLogs)
+}
+
+// renderCIClusters clusters the CI failures of changes and describes them
+func (h *Handler) renderCIClusters(ctx context.Context, header []string, scope string, changes []gerrit.ChangeInfo, withLogs bool) (*mcp.CallToolResult, error) {
+	if len(changes) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("%s has no changes", scope)), nil
+	}
+
+	clusters := make(map[string]*ciCluster)
+	var passing, unreported []gerrit.ChangeInfo
+	failing := 0
+	var logErrors []string
+	for _, change := range changes {
+		current := change.Revisions[change.CurrentRevision].Number
+		// The latest report of each CI system on the current patchset
+		latest := make(map[int]ciReport)
+		for _, r := range ciReports(&change) {
+			if r.message.RevisionNumber == current {
+				latest[r.message.Author.AccountID] = r
+			}
+		}
+		if len(latest) == 0 {
+			unreported = append(unreported, change)
+			continue
+		}
+
+		failed := false
+		for _, author := range slices.Sorted(maps.Keys(latest)) {
+			r := latest[author]
+			if !r.failed {
+				continue
+			}
+			failed = true
+			lines := r.failingJobs()
+			if withLogs {
+				if link := r.failureLink(); link != "" {
+					if line, err := h.firstLogFailure(ctx, link); err != nil {
+						logErrors = append(logErrors, fmt.Sprintf("%d: %s: %v", change.Number, link, err))
+					} else if line != "" {
+						lines = []string{line}
+					}
+				}
+			}
+			if len(lines) == 0 {
+				lines = []string{fmt.Sprintf("%s voted %s%+d without saying why", formatAccount(r.message.Author), ciLabel, r.vote)}
+			}
+			for _, line := range lines {
+				signature := failureSignature(line)
+				c, ok := clusters[signature]
+				if !ok {
+					c = &ciCluster{signature: signature}
+					clusters[signature] = c
+				}
+				c.members = append(c.members, ciFailureMember{change: change, line: line})
+			}
+		}
+		if failed {
+			failing++
+		} else {
+			passing = append(passing, change)
+		}
+	}
+
+	sorted := make([]*ciCluster, 0, len(clusters))
+	for _, c := range clusters {
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if ci, cj := sorted[i].changeCount(), sorted[j].changeCount(); ci != cj {
+			return ci > cj
+		}
+		return sorted[i].signature < sorted[j].signature
+	})
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "%s: %s, %d failing CI on the current patchset, %s\n", scope, plural(len(changes), "change"), failing, plural(len(sorted), "distinct failure"))
+	switch {
+	case failing == 0:
+		b.WriteString("No CI failures\n")
+	case failing > 1 && sorted[0].changeCount() == failing:
+		b.WriteString("All failing changes share a failure: likely a flaky test or a breakage outside these changes\n")
+	case failing > 1 && sorted[0].changeCount() == 1:
+		b.WriteString("No failure is shared: each failing change is likely broken on its own\n")
+	}
+
+	line := func(change gerrit.ChangeInfo) string {
+		return fmt.Sprintf("%d %s: %s (patchset %d)", change.Number, change.Project, change.Subject, change.Revisions[change.CurrentRevision].Number)
+	}
+	for _, c := range sorted {
+		n := c.changeCount()
+		if n > 1 {
+			fmt.Fprintf(&b, "\nShared by %s:\n  %s\n", plural(n, "change"), c.members[0].line)
+		} else {
+			fmt.Fprintf(&b, "\nOnly on one change:\n  %s\n", c.members[0].line)
+		}
+		seen := make(map[int]bool)
+		for _, m := range c.members {
+			if !seen[m.change.Number] {
+				seen[m.change.Number] = true
+				fmt.Fprintf(&b, "    %s\n", line(m.change))
+			}
+		}
+	}
+	for _, section := range []struct {
+		title   string
+		changes []gerrit.ChangeInfo
+	}{
+		{"CI passed", passing},
+		{"No CI results on the current patchset", unreported},
+	} {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		for _, change := range section.changes {
+			fmt.Fprintf(&b, "  %s\n", line(change))
+		}
+	}
+	if len(logErrors) > 0 {
+		b.WriteString("\nLogs that could not be read, clustered by the CI message instead:\n")
+		for _, e := range logErrors {
+			fmt.Fprintf(&b, "  %s\n", e)
+		}
+	}
+	if changes[len(changes)-1].MoreChanges {
+		b.WriteString("\nWARNING: there are more changes; raise limit to see them\n")
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// firstLogFailure returns the first failure line near the end of a log
+func (h *Handler) firstLogFailure(ctx context.Context, link string) (string, error) {
+	log, _, err := fetchLogTail(ctx, link, clusterLogBytes)
+	if err != nil {
+		return "", err
+	}
+	if strings.ContainsRune(log, 0) {
+		return "", nil
+	}
+	for line := range strings.SplitSeq(log, "\n") {
+		if ciFailureRegexp.MatchString(line) {
+			return strings.TrimSpace(line), nil
+		}
+	}
+	return "", nil
+}
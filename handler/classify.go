@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// CommentCategory names the review comment sentences containing any of
+// Keywords. Keywords are matched case-insensitively, as whole words where
+// they start or end with a letter or digit.
+type CommentCategory struct {
+	Name     string   `json:"name"`
+	Keywords []string `json:"keywords"`
+
+	pattern *regexp.Regexp
+}
+
+// fyiCategory is the category of threads that ask nothing of the owner
+const fyiCategory = "fyi"
+
+// defaultCommentCategories are used when the configuration file defines none.
+// The first category a sentence matches wins, so nits are checked before the
+// requests they are phrased as.
+var defaultCommentCategories = []CommentCategory{
+	{Name: "nit", Keywords: []string{"nit", "nits", "nitpick", "typo", "optional", "minor", "style", "whitespace"}},
+	{Name: "requested-change", Keywords: []string{"please", "should", "must", "need to", "needs to", "can you", "could you", "instead", "remove", "rename", "don't", "do not", "avoid", "missing", "fix"}},
+	{Name: "question", Keywords: []string{"?", "why", "wondering", "not sure", "unclear"}},
+}
+
+func init() {
+	for i := range defaultCommentCategories {
+		if err := defaultCommentCategories[i].compile(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// compile prepares the keyword pattern of a category
+func (c *CommentCategory) compile() error {
+	if c.Name == "" {
+		return fmt.Errorf("comment category has no name")
+	}
+	if len(c.Keywords) == 0 {
+		return fmt.Errorf("comment category %s has no keywords", c.Name)
+	}
+	alternatives := make([]string, len(c.Keywords))
+	for i, keyword := range c.Keywords {
+		if keyword == "" {
+			return fmt.Errorf("comment category %s has an empty keyword", c.Name)
+		}
+		alt := regexp.QuoteMeta(keyword)
+		if isWordRune(rune(keyword[0])) {
+			alt = `\b` + alt
+		}
+		if isWordRune(rune(keyword[len(keyword)-1])) {
+			alt += `\b`
+		}
+		alternatives[i] = alt
+	}
+	c.pattern = regexp.MustCompile(`(?i)(?:` + strings.Join(alternatives, "|") + `)`)
+	return nil
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// commentCategories returns the configured comment categories or the defaults
+func (c *Config) commentCategories() []CommentCategory {
+	if len(c.CommentCategories) > 0 {
+		return c.CommentCategories
+	}
+	return defaultCommentCategories
+}
+
+// actionItem is a sentence of a review comment that asks something of the owner
+type actionItem struct {
+	category string
+	text     string
+}
+
+// maxActionItemLength shortens long sentences in action item lists
+const maxActionItemLength = 160
+
+// threadActionItems classifies the sentences that reviewers other than the
+// change's owner wrote in a thread. The thread falls into the category of its
+// first action item, or fyiCategory if it has none.
+func threadActionItems(t *commentThread, ownerID int, categories []CommentCategory) (category string, items []actionItem) {
+	seen := make(map[string]bool)
+	for _, c := range t.comments {
+		if c.draft || c.Author.AccountID == ownerID && ownerID != 0 {
+			continue
+		}
+		for _, sentence := range commentSentences(c.Message) {
+			for _, cat := range categories {
+				if !cat.pattern.MatchString(sentence) {
+					continue
+				}
+				if len(sentence) > maxActionItemLength {
+					sentence = sentence[:maxActionItemLength-3] + "..."
+				}
+				if !seen[sentence] {
+					seen[sentence] = true
+					items = append(items, actionItem{category: cat.Name, text: sentence})
+				}
+				break
+			}
+		}
+	}
+	if len(items) == 0 {
+		return fyiCategory, nil
+	}
+	return items[0].category, items
+}
+
+// commentSentences splits a comment into sentences, leaving out the lines
+// quoted from earlier comments
+func commentSentences(message string) []string {
+	var sentences []string
+	for line := range strings.SplitSeq(message, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ">") {
+			continue
+		}
+		start := 0
+		for i := 0; i < len(line); i++ {
+			if (line[i] == '.' || line[i] == '!' || line[i] == '?') && (i+1 == len(line) || line[i+1] == ' ') {
+				if s := strings.TrimSpace(line[start : i+1]); s != "" {
+					sentences = append(sentences, s)
+				}
+				start = i + 1
+			}
+		}
+		if s := strings.TrimSpace(line[start:]); s != "" {
+			sentences = append(sentences, s)
+		}
+	}
+	return sentences
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+)
+
+// CoalescingClient is a GerritClient that serves identical change queries
+// made within a short window from one upstream query. Several agents
+// refreshing the same dashboard at once then cost Gerrit a single search.
+// Only callers with the same credentials share results, since they may see
+// different changes.
+type CoalescingClient struct {
+	next   GerritClient
+	window time.Duration
+
+	mu      sync.Mutex
+	queries map[string]*queryEntry
+}
+
+// queryEntry is a recent or in-flight query; done is closed once it returns
+type queryEntry struct {
+	done    chan struct{}
+	expires time.Time
+	changes *[]gerrit.ChangeInfo
+	resp    *gerrit.Response
+	err     error
+}
+
+// NewCoalescingClient wraps next so that identical queries made within
+// window of each other share one result
+func NewCoalescingClient(next GerritClient, window time.Duration) *CoalescingClient {
+	return &CoalescingClient{
+		next:    next,
+		window:  window,
+		queries: make(map[string]*queryEntry),
+	}
+}
+
+// GetChange implements GerritClient interface
+func (c *CoalescingClient) GetChange(ctx context.Context, changeID string, opt *gerrit.ChangeOptions) (*gerrit.ChangeInfo, *gerrit.Response, error) {
+	return c.next.GetChange(ctx, changeID, opt)
+}
+
+// GetPatch implements GerritClient interface
+func (c *CoalescingClient) GetPatch(ctx context.Context, changeID, revisionID string, opt *gerrit.PatchOptions) (*string, *gerrit.Response, error) {
+	return c.next.GetPatch(ctx, changeID, revisionID, opt)
+}
+
+// SetReview implements GerritClient interface
+func (c *CoalescingClient) SetReview(ctx context.Context, changeID, revisionID string, input *gerrit.ReviewInput) (*gerrit.ReviewResult, *gerrit.Response, error) {
+	return c.next.SetReview(ctx, changeID, revisionID, input)
+}
+
+// Call implements GerritClient interface
+func (c *CoalescingClient) Call(ctx context.Context, method, path string, body, v any) (*gerrit.Response, error) {
+	return c.next.Call(ctx, method, path, body, v)
+}
+
+// QueryChanges implements GerritClient interface
+func (c *CoalescingClient) QueryChanges(ctx context.Context, opt *gerrit.QueryChangeOptions) (*[]gerrit.ChangeInfo, *gerrit.Response, error) {
+	key := fmt.Sprintf("%s/%+v", connectionKey(ctx), opt)
+
+	for {
+		c.mu.Lock()
+		now := time.Now()
+		for k, e := range c.queries {
+			if !e.expires.IsZero() && now.After(e.expires) {
+				delete(c.queries, k)
+			}
+		}
+		entry, ok := c.queries[key]
+		if !ok {
+			entry = &queryEntry{done: make(chan struct{})}
+			c.queries[key] = entry
+		}
+		c.mu.Unlock()
+
+		if !ok {
+			return c.query(ctx, key, entry, opt)
+		}
+		select {
+		case <-entry.done:
+			// The query was cancelled for the caller that ran it, not for
+			// this one, which retries
+			if isContextError(entry.err) && ctx.Err() == nil {
+				continue
+			}
+			return copyChanges(entry.changes), entry.resp, entry.err
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// query runs the query of a new entry and shares its result with the callers
+// waiting for it
+func (c *CoalescingClient) query(ctx context.Context, key string, entry *queryEntry, opt *gerrit.QueryChangeOptions) (*[]gerrit.ChangeInfo, *gerrit.Response, error) {
+	entry.changes, entry.resp, entry.err = c.next.QueryChanges(ctx, opt)
+	c.mu.Lock()
+	if entry.err != nil {
+		// Let the next caller retry, but only if the entry is still ours
+		if c.queries[key] == entry {
+			delete(c.queries, key)
+		}
+	} else {
+		entry.expires = time.Now().Add(c.window)
+	}
+	c.mu.Unlock()
+	close(entry.done)
+	return copyChanges(entry.changes), entry.resp, entry.err
+}
+
+// copyChanges gives each caller its own slice, so that sorting or trimming
+// the results of one call doesn't affect the others
+func copyChanges(changes *[]gerrit.ChangeInfo) *[]gerrit.ChangeInfo {
+	if changes == nil {
+		return nil
+	}
+	c := append([]gerrit.ChangeInfo(nil), *changes...)
+	return &c
+}
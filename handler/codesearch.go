@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Bounds of search-gerrit-project-code, which reads every file it searches
+const (
+	defaultSearchFiles   = 200
+	maxSearchFiles       = 1000
+	defaultSearchMatches = 50
+	maxSearchLineLength  = 200
+)
+
+// codeSearchLink fills in the configured code search URL template
+func (c *Config) codeSearchLink(pattern, project, branch string) string {
+	if c.CodeSearchURL == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{pattern}", url.QueryEscape(pattern),
+		"{project}", url.QueryEscape(project),
+		"{branch}", url.QueryEscape(branch),
+	).Replace(c.CodeSearchURL)
+}
+
+// searchMatch is a line matching the pattern of a code search
+type searchMatch struct {
+	path string
+	line int
+	text string
+}
+
+// SearchGerritProjectCode searches the files of a change's project at the tip
+// of a branch for a regular expression, e.g. to find out whether a helper the
+// change adds already exists elsewhere. The file names come from the change's
+// current patchset and their content from the branch; at most max_files are
+// read, one request each.
+func (h *Handler) SearchGerritProjectCode(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	pattern, err := request.RequireString("pattern")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid pattern: %v", err)), nil
+	}
+	paths := request.GetString("paths", "")
+	maxFiles := min(request.GetInt("max_files", defaultSearchFiles), maxSearchFiles)
+	maxMatches := request.GetInt("max_matches", defaultSearchMatches)
+	if maxFiles <= 0 || maxMatches <= 0 {
+		return mcp.NewToolResultError("max_files and max_matches must be positive"), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	branch := request.GetString("branch", change.Branch)
+	cfg := h.cfg()
+	link := cfg.codeSearchLink(pattern, change.Project, branch)
+
+	// With q, Gerrit lists every file of the revision whose path contains it
+	var files []string
+	listPath := fmt.Sprintf("changes/%s/revisions/current/files/?q=%s", url.PathEscape(changeID), url.QueryEscape(paths))
+	if _, err := h.client.Call(ctx, http.MethodGet, listPath, nil, &files); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list the files of %s: %v", change.Project, err)), nil
+	}
+	var candidates []string
+	redacted := 0
+	for _, file := range files {
+		switch {
+		case strings.HasPrefix(file, "/"):
+			// /COMMIT_MSG and /MERGE_LIST
+		case cfg.redactedPath(file):
+			redacted++
+		default:
+			candidates = append(candidates, file)
+		}
+	}
+	skipped := 0
+	if len(candidates) > maxFiles {
+		skipped = len(candidates) - maxFiles
+		candidates = candidates[:maxFiles]
+	}
+
+	var matches []searchMatch
+	searched, added, binary := 0, 0, 0
+	var stopped error
+search:
+	for _, file := range candidates {
+		if err := h.throttle(ctx); err != nil {
+			stopped = err
+			break
+		}
+		contentPath := fmt.Sprintf("projects/%s/branches/%s/files/%s/content", url.PathEscape(change.Project), url.PathEscape(branch),
+			strings.ReplaceAll(url.PathEscape(file), "/", "%2F"))
+		content, resp, err := h.fileContent(ctx, contentPath)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			// Added by the change or missing on the branch
+			added++
+			continue
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read %s from branch %s: %v", file, branch, err)), nil
+		}
+		searched++
+		if strings.ContainsRune(content, 0) {
+			binary++
+			continue
+		}
+		for i, line := range strings.Split(content, "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			line = strings.TrimSpace(line)
+			if len(line) > maxSearchLineLength {
+				line = strings.ToValidUTF8(line[:maxSearchLineLength], "") + "..."
+			}
+			matches = append(matches, searchMatch{path: file, line: i + 1, text: line})
+			if len(matches) == maxMatches {
+				break search
+			}
+		}
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Search of %s at the tip of %s for %s: %s in %s\n", change.Project, branch, pattern, plural(len(matches), "matching line"), plural(searched, "file"))
+	for _, m := range matches {
+		fmt.Fprintf(&b, "%s:%d: %s\n", m.path, m.line, m.text)
+	}
+
+	var notes []string
+	if len(matches) == maxMatches {
+		notes = append(notes, fmt.Sprintf("stopped at %d matches; raise max_matches to see more", maxMatches))
+	}
+	if stopped != nil {
+		notes = append(notes, fmt.Sprintf("stopped after %s: %v", plural(searched+added, "file"), stopped))
+	}
+	if skipped > 0 {
+		notes = append(notes, fmt.Sprintf("%s not searched; narrow paths or raise max_files (up to %d)", plural(skipped, "file"), maxSearchFiles))
+	}
+	if added > 0 {
+		notes = append(notes, fmt.Sprintf("%s not on branch %s, e.g. added by the change", plural(added, "file"), branch))
+	}
+	if binary > 0 {
+		notes = append(notes, fmt.Sprintf("%s skipped as binary", plural(binary, "file")))
+	}
+	if redacted > 0 {
+		notes = append(notes, fmt.Sprintf("%s skipped as redacted by policy", plural(redacted, "file")))
+	}
+	if len(notes) > 0 {
+		b.WriteString("\n")
+		for _, note := range notes {
+			fmt.Fprintf(&b, "NOTE: %s\n", note)
+		}
+	}
+	if link != "" {
+		fmt.Fprintf(&b, "\nSearch the whole code base: %s\n", link)
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,332 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// commentInfo is Gerrit's CommentInfo
+type commentInfo struct {
+	ID         string             `json:"id"`
+	Path       string             `json:"path"`
+	PatchSet   int                `json:"patch_set"`
+	Line       int                `json:"line"`
+	InReplyTo  string             `json:"in_reply_to"`
+	Message    string             `json:"message"`
+	Updated    string             `json:"updated"`
+	Author     gerrit.AccountInfo `json:"author"`
+	Unresolved bool               `json:"unresolved"`
+
+	// draft is set for unpublished comments of the calling user
+	draft bool
+}
+
+// commentThread is a root comment with its replies in order
+type commentThread struct {
+	path     string
+	comments []commentInfo
+	// ported is the root comment as ported to the current patchset, if Gerrit could port it
+	ported *commentInfo
+}
+
+func (t *commentThread) root() commentInfo { return t.comments[0] }
+
+// unresolved reports whether the thread is still open, which is decided by its last comment
+func (t *commentThread) unresolved() bool { return t.comments[len(t.comments)-1].Unresolved }
+
+// buildThreads groups comments, keyed by file as returned by Gerrit, into
+// threads ordered by file, patchset and line
+func buildThreads(comments map[string][]commentInfo) []*commentThread {
+	byID := make(map[string]commentInfo)
+	for path, list := range comments {
+		for _, c := range list {
+			c.Path = path
+			byID[c.ID] = c
+		}
+	}
+
+	rootOf := func(c commentInfo) string {
+		// Guard against cycles and replies to unknown comments
+		for seen := 0; c.InReplyTo != "" && seen < len(byID); seen++ {
+			parent, ok := byID[c.InReplyTo]
+			if !ok {
+				break
+			}
+			c = parent
+		}
+		return c.ID
+	}
+
+	threads := make(map[string]*commentThread)
+	for _, c := range byID {
+		id := rootOf(c)
+		t, ok := threads[id]
+		if !ok {
+			t = &commentThread{path: byID[id].Path}
+			threads[id] = t
+		}
+		t.comments = append(t.comments, c)
+	}
+
+	result := make([]*commentThread, 0, len(threads))
+	for id, t := range threads {
+		sort.SliceStable(t.comments, func(i, j int) bool {
+			// The root first, then replies by time, then the unpublished drafts
+			if t.comments[i].ID == id || t.comments[j].ID == id {
+				return t.comments[i].ID == id
+			}
+			if t.comments[i].draft != t.comments[j].draft {
+				return t.comments[j].draft
+			}
+			return t.comments[i].Updated < t.comments[j].Updated
+		})
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].root(), result[j].root()
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.PatchSet != b.PatchSet {
+			return a.PatchSet < b.PatchSet
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Updated < b.Updated
+	})
+	return result
+}
+
+// anchor describes where a thread's root comment sits relative to the current patchset
+func (t *commentThread) anchor(currentPatchSet int) string {
+	root := t.root()
+	where := "file comment"
+	if root.Line > 0 {
+		where = fmt.Sprintf("line %d", root.Line)
+	}
+	if t.path == "/PATCHSET_LEVEL" {
+		where = "change comment"
+	}
+
+	switch {
+	case root.PatchSet == currentPatchSet:
+		return fmt.Sprintf("patchset %d %s", root.PatchSet, where)
+	case t.ported == nil && t.unresolved():
+		return fmt.Sprintf("patchset %d %s, could not be ported to patchset %d", root.PatchSet, where, currentPatchSet)
+	case t.ported == nil:
+		return fmt.Sprintf("patchset %d %s, outdated", root.PatchSet, where)
+	case root.Line > 0 && t.ported.Line == 0:
+		return fmt.Sprintf("patchset %d %s, the line is gone in patchset %d", root.PatchSet, where, currentPatchSet)
+	case t.ported.Line > 0:
+		return fmt.Sprintf("patchset %d %s, now line %d of patchset %d", root.PatchSet, where, t.ported.Line, currentPatchSet)
+	default:
+		return fmt.Sprintf("patchset %d %s, still applies to patchset %d", root.PatchSet, where, currentPatchSet)
+	}
+}
+
+// commentThreads fetches the comment threads of a change, optionally with the
+// calling user's drafts, and maps them onto the current patchset. Problems
+// with the mapping are returned as warnings rather than failing the call.
+func (h *Handler) commentThreads(ctx context.Context, changeID string, change *gerrit.ChangeInfo, drafts bool) ([]*commentThread, []string, error) {
+	var comments map[string][]commentInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("changes/%s/comments", changeID), nil, &comments); err != nil {
+		return nil, nil, fmt.Errorf("failed to get comments of change %s: %v", changeID, err)
+	}
+	if comments == nil {
+		comments = make(map[string][]commentInfo)
+	}
+
+	// Only unresolved threads and drafts are ported
+	compat := h.compat()
+	var endpoints []string
+	if compat.portedComments {
+		endpoints = append(endpoints, "ported_comments")
+	}
+	if drafts {
+		var draftComments map[string][]commentInfo
+		if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("changes/%s/drafts", changeID), nil, &draftComments); err != nil {
+			return nil, nil, fmt.Errorf("failed to get draft comments of change %s: %v", changeID, err)
+		}
+		for path, list := range draftComments {
+			for _, c := range list {
+				c.draft = true
+				comments[path] = append(comments[path], c)
+			}
+		}
+		if compat.portedComments {
+			endpoints = append(endpoints, "ported_drafts")
+		}
+	}
+	threads := buildThreads(comments)
+
+	var warnings []string
+	if !compat.portedComments {
+		warnings = append(warnings, fmt.Sprintf("NOTE: Gerrit %s can't port comments, so threads on older patchsets are not mapped onto the current one", compat.version))
+	}
+	portedByID := make(map[string]commentInfo)
+	for _, endpoint := range endpoints {
+		var ported map[string][]commentInfo
+		path := fmt.Sprintf("changes/%s/revisions/%s/%s", changeID, change.CurrentRevision, endpoint)
+		if _, err := h.client.Call(ctx, http.MethodGet, path, nil, &ported); err != nil {
+			warnings = append(warnings, fmt.Sprintf("WARNING: could not map comments onto the current patchset: %v", err))
+			continue
+		}
+		for _, list := range ported {
+			for _, c := range list {
+				portedByID[c.ID] = c
+			}
+		}
+	}
+	for _, t := range threads {
+		if c, ok := portedByID[t.root().ID]; ok {
+			t.ported = &c
+		}
+	}
+	return threads, warnings, nil
+}
+
+// GetGerritChangeComments lists the comment threads of a change. Threads
+// started on older patchsets are mapped onto the current patchset using
+// Gerrit's ported comments, so that replies can refer to the current code.
+func (h *Handler) GetGerritChangeComments(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	unresolvedOnly := request.GetBool("unresolved_only", false)
+
+	includeDrafts := request.GetBool("include_drafts", false)
+	classify := request.GetBool("classify", false)
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	currentPatchSet := change.Revisions[change.CurrentRevision].Number
+
+	p, err := newPage(request, 0, "get-gerrit-change-comments", changeID, strconv.FormatBool(unresolvedOnly), strconv.FormatBool(includeDrafts))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	threads, warnings, err := h.commentThreads(ctx, changeID, change, includeDrafts)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	header = append(header, warnings...)
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	if len(threads) == 0 {
+		fmt.Fprintf(&b, "Change %s has no comments", changeID)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	unresolved := 0
+	for _, t := range threads {
+		if t.unresolved() {
+			unresolved++
+		}
+	}
+	fmt.Fprintf(&b, "%s on change %s (%d unresolved), current patchset %d\n", plural(len(threads), "comment thread"), changeID, unresolved, currentPatchSet)
+	categories := h.cfg().commentCategories()
+	threadCategory := make(map[*commentThread]string)
+	threadItems := make(map[*commentThread][]actionItem)
+	if classify {
+		counts := make(map[string]int)
+		for _, t := range threads {
+			threadCategory[t], threadItems[t] = threadActionItems(t, change.Owner.AccountID, categories)
+			if t.unresolved() {
+				counts[threadCategory[t]]++
+			}
+		}
+		names := make([]string, 0, len(categories)+1)
+		for _, cat := range categories {
+			names = append(names, cat.Name)
+		}
+		var parts []string
+		for _, name := range append(names, fyiCategory) {
+			if counts[name] > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", counts[name], name))
+				// Categories may share a name
+				delete(counts, name)
+			}
+		}
+		if len(parts) > 0 {
+			fmt.Fprintf(&b, "Unresolved by category: %s\n", strings.Join(parts, ", "))
+		}
+	}
+	if unresolvedOnly {
+		b.WriteString("Showing unresolved threads only\n")
+	}
+
+	shown := threads
+	if unresolvedOnly {
+		shown = nil
+		for _, t := range threads {
+			if t.unresolved() {
+				shown = append(shown, t)
+			}
+		}
+	}
+	from, to := p.window(len(shown))
+	if p.limit > 0 {
+		fmt.Fprintf(&b, "Showing threads %d-%d of %d\n", from+1, to, len(shown))
+	}
+
+	path := ""
+	for _, t := range shown[from:to] {
+		if t.path != path {
+			path = t.path
+			fmt.Fprintf(&b, "\n%s\n", path)
+		}
+		state := "resolved"
+		if t.unresolved() {
+			state = "unresolved"
+		}
+		if classify {
+			state += ", " + threadCategory[t]
+		}
+		fmt.Fprintf(&b, "  [%s] %s (thread %s)\n", state, t.anchor(currentPatchSet), t.root().ID)
+		for _, c := range t.comments {
+			if c.draft {
+				b.WriteString("    DRAFT by you, not yet published:\n")
+			} else {
+				fmt.Fprintf(&b, "    %s, %s:\n", formatAccount(c.Author), formatTimestamp(c.Updated))
+			}
+			for _, line := range strings.Split(strings.TrimRight(c.Message, "\n"), "\n") {
+				fmt.Fprintf(&b, "      %s\n", line)
+			}
+		}
+		if items := threadItems[t]; len(items) > 0 && t.unresolved() {
+			b.WriteString("    Action items:\n")
+			for _, item := range items {
+				fmt.Fprintf(&b, "      - [%s] %s\n", item.category, item.text)
+			}
+		}
+	}
+	if p.limit > 0 {
+		b.WriteString("\n" + p.footer(to-from, to < len(shown)))
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// formatTimestamp shortens a Gerrit timestamp such as
+// "2024-05-01 10:11:12.000000000" to minute precision
+func formatTimestamp(ts string) string {
+	if len(ts) >= 16 {
+		return ts[:16]
+	}
+	return ts
+}
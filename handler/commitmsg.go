@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// CommitMessageConventions are the rules commit messages of a project should
+// follow. Zero lengths mean the defaults.
+type CommitMessageConventions struct {
+	MaxSubjectLength int `json:"max_subject_length"`
+	MaxLineLength    int `json:"max_line_length"`
+	// RequiredFooters are footer keys every message needs, e.g. "Bug"
+	RequiredFooters []string `json:"required_footers"`
+	// Template is an example message shown to whoever fixes the message
+	Template string `json:"template"`
+	// SubjectPattern is a regular expression the subject must match
+	SubjectPattern string `json:"subject_pattern"`
+	// FooterPatterns are footers every message needs by key, with a
+	// regular expression their value must match, e.g. "Bug": "^\\d+$"
+	FooterPatterns map[string]string `json:"footer_patterns"`
+
+	subjectPattern *regexp.Regexp
+	footerPatterns map[string]*regexp.Regexp
+}
+
+// Defaults of the commit message conventions, as git and Gerrit's UI expect
+const (
+	defaultMaxSubjectLength = 72
+	defaultMaxLineLength    = 72
+)
+
+// footerRegexp matches a "Key: value" footer line
+var footerRegexp = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*):\s*(\S.*)$`)
+
+// commitMessageConventions returns the conventions for the changes of
+// project: the project's settings over the server's, over the defaults
+func (c *Config) commitMessageConventions(project string) CommitMessageConventions {
+	conv := c.CommitMessage
+	if override := c.Projects[project].CommitMessage; override != nil {
+		if override.MaxSubjectLength > 0 {
+			conv.MaxSubjectLength = override.MaxSubjectLength
+		}
+		if override.MaxLineLength > 0 {
+			conv.MaxLineLength = override.MaxLineLength
+		}
+		if override.RequiredFooters != nil {
+			conv.RequiredFooters = override.RequiredFooters
+		}
+		if override.Template != "" {
+			conv.Template = override.Template
+		}
+		if override.SubjectPattern != "" {
+			conv.SubjectPattern, conv.subjectPattern = override.SubjectPattern, override.subjectPattern
+		}
+		if override.FooterPatterns != nil {
+			conv.FooterPatterns, conv.footerPatterns = override.FooterPatterns, override.footerPatterns
+		}
+	}
+	if conv.MaxSubjectLength == 0 {
+		conv.MaxSubjectLength = defaultMaxSubjectLength
+	}
+	if conv.MaxLineLength == 0 {
+		conv.MaxLineLength = defaultMaxLineLength
+	}
+	return conv
+}
+
+// compile checks the lengths and prepares the patterns of the conventions
+func (conv *CommitMessageConventions) compile() error {
+	if conv.MaxSubjectLength < 0 || conv.MaxLineLength < 0 {
+		return fmt.Errorf("commit message lengths must not be negative")
+	}
+	conv.subjectPattern = nil
+	if conv.SubjectPattern != "" {
+		re, err := regexp.Compile(conv.SubjectPattern)
+		if err != nil {
+			return fmt.Errorf("commit message subject pattern: %w", err)
+		}
+		conv.subjectPattern = re
+	}
+	conv.footerPatterns = make(map[string]*regexp.Regexp, len(conv.FooterPatterns))
+	for key, pattern := range conv.FooterPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("commit message footer %s pattern: %w", key, err)
+		}
+		conv.footerPatterns[key] = re
+	}
+	return nil
+}
+
+// commitMessageViolation is a rule a commit message breaks
+type commitMessageViolation struct {
+	rule string
+	// line is the 1-based line of the message, 0 for the message as a whole
+	line   int
+	detail string
+}
+
+// enforced reports whether set-gerrit-commit-message refuses messages
+// breaking the rule. The other rules are style advice.
+func (v commitMessageViolation) enforced() bool {
+	switch v.rule {
+	case "subject-missing", "subject-pattern", "missing-footer", "footer-pattern":
+		return true
+	}
+	return false
+}
+
+func (v commitMessageViolation) String() string {
+	s := fmt.Sprintf("[%s] %s", v.rule, v.detail)
+	if v.line > 0 {
+		s = fmt.Sprintf("[%s] line %d: %s", v.rule, v.line, v.detail)
+	}
+	if v.enforced() {
+		s += " (required)"
+	}
+	return s
+}
+
+// messageFooters returns the footers of a commit message, which are the
+// "Key: value" lines of its last paragraph, by lowercase key, and the index
+// of the paragraph's first line
+func messageFooters(lines []string) (footers map[string]string, start int) {
+	footers = make(map[string]string)
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	start = end
+	for start > 1 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	for _, line := range lines[start:end] {
+		m := footerRegexp.FindStringSubmatch(line)
+		if m == nil {
+			// A paragraph that isn't all footers is part of the body
+			return map[string]string{}, end
+		}
+		footers[strings.ToLower(m[1])] = m[2]
+	}
+	return footers, start
+}
+
+// nonImperativeWord reports whether the first word of a subject looks like
+// past tense, a gerund or the third person, e.g. "Added", "Adding", "Adds"
+func nonImperativeWord(word string) bool {
+	w := strings.ToLower(word)
+	switch w {
+	case "need", "embed", "feed", "seed", "speed", "proceed", "exceed", "succeed", "bring", "string", "process", "access", "pass", "address", "focus", "bias", "alias", "canvas":
+		return false
+	}
+	if len(w) < 4 {
+		return false
+	}
+	return strings.HasSuffix(w, "ed") || strings.HasSuffix(w, "ing") ||
+		strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is")
+}
+
+// checkCommitMessage returns the conventions a commit message breaks
+func checkCommitMessage(message string, conv CommitMessageConventions) []commitMessageViolation {
+	var violations []commitMessageViolation
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+	subject := lines[0]
+
+	if strings.TrimSpace(subject) == "" {
+		return append(violations, commitMessageViolation{"subject-missing", 1, "the first line must summarize the change"})
+	}
+	if n := utf8.RuneCountInString(subject); n > conv.MaxSubjectLength {
+		violations = append(violations, commitMessageViolation{"subject-length", 1, fmt.Sprintf("the subject is %d characters, more than %d", n, conv.MaxSubjectLength)})
+	}
+	if conv.subjectPattern != nil && !conv.subjectPattern.MatchString(subject) {
+		violations = append(violations, commitMessageViolation{"subject-pattern", 1, fmt.Sprintf("the subject does not match %s", conv.SubjectPattern)})
+	}
+	if strings.HasSuffix(subject, ".") {
+		violations = append(violations, commitMessageViolation{"subject-period", 1, "the subject should not end with a period"})
+	}
+	// The summary follows an optional "area: " prefix
+	summary := subject
+	if m := subjectPrefixRegexp.FindStringSubmatch(subject); m != nil {
+		summary = m[2]
+	}
+	if fields := strings.Fields(summary); len(fields) > 0 && nonImperativeWord(fields[0]) {
+		violations = append(violations, commitMessageViolation{"imperative-mood", 1, fmt.Sprintf("%q does not look imperative; write the subject as a command, e.g. \"Fix crash\" rather than \"Fixed crash\"", fields[0])})
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		violations = append(violations, commitMessageViolation{"blank-line", 2, "a blank line must separate the subject from the body"})
+	}
+
+	footers, footerStart := messageFooters(lines)
+	if footerStart <= 2 {
+		violations = append(violations, commitMessageViolation{"body-missing", 0, "there is no body explaining what the change does and why"})
+	}
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		// URLs, footers and indented code can't be wrapped
+		if i >= footerStart || strings.Contains(line, "://") || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		if n := utf8.RuneCountInString(line); n > conv.MaxLineLength {
+			violations = append(violations, commitMessageViolation{"line-length", i + 1, fmt.Sprintf("the line is %d characters, more than %d", n, conv.MaxLineLength)})
+		}
+	}
+
+	if _, ok := footers["change-id"]; !ok {
+		violations = append(violations, commitMessageViolation{"missing-footer", 0, "the Change-Id footer is missing from the last paragraph"})
+	}
+	required := slices.Clone(conv.RequiredFooters)
+	for _, key := range sortedKeys(conv.FooterPatterns) {
+		if !slices.ContainsFunc(required, func(k string) bool { return strings.EqualFold(k, key) }) {
+			required = append(required, key)
+		}
+	}
+	for _, key := range required {
+		value, ok := footers[strings.ToLower(key)]
+		if !ok {
+			violations = append(violations, commitMessageViolation{"missing-footer", 0, fmt.Sprintf("the %s footer is missing from the last paragraph", key)})
+			continue
+		}
+		for k, re := range conv.footerPatterns {
+			if strings.EqualFold(k, key) && !re.MatchString(value) {
+				violations = append(violations, commitMessageViolation{"footer-pattern", 0, fmt.Sprintf("the %s footer %q does not match %s", key, value, conv.FooterPatterns[k])})
+			}
+		}
+	}
+	return violations
+}
+
+// CheckGerritCommitMessage reports how the current commit message of a
+// change breaks the conventions of its project, for the caller to fix the
+// message with set-gerrit-commit-message
+func (h *Handler) CheckGerritCommitMessage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "CURRENT_COMMIT")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	revision := change.Revisions[change.CurrentRevision]
+	message := revision.Commit.Message
+	conv := h.cfg().commitMessageConventions(change.Project)
+	violations := checkCommitMessage(message, conv)
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Commit message of change %s, patchset %d:\n\n", changeID, revision.Number)
+	for line := range strings.SplitSeq(strings.TrimRight(message, "\n"), "\n") {
+		fmt.Fprintf(&b, "%s\n", strings.TrimRight("  "+line, " "))
+	}
+
+	rules := []string{fmt.Sprintf("subject at most %d characters", conv.MaxSubjectLength)}
+	if conv.SubjectPattern != "" {
+		rules = append(rules, "subject matching "+conv.SubjectPattern)
+	}
+	rules = append(rules, fmt.Sprintf("body lines at most %d", conv.MaxLineLength))
+	footers := append([]string{"Change-Id"}, conv.RequiredFooters...)
+	for _, key := range sortedKeys(conv.FooterPatterns) {
+		footers = append(footers, fmt.Sprintf("%s matching %s", key, conv.FooterPatterns[key]))
+	}
+	rules = append(rules, "footers "+strings.Join(footers, ", "))
+	fmt.Fprintf(&b, "\nConventions of %s: %s\n", change.Project, strings.Join(rules, "; "))
+	if conv.Template != "" {
+		b.WriteString("Template:\n")
+		for line := range strings.SplitSeq(strings.TrimRight(conv.Template, "\n"), "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+
+	if len(violations) == 0 {
+		b.WriteString("\nThe commit message follows the conventions")
+		return mcp.NewToolResultText(b.String()), nil
+	}
+	fmt.Fprintf(&b, "\n%s:\n", plural(len(violations), "violation"))
+	for _, v := range violations {
+		fmt.Fprintf(&b, "  - %s\n", v)
+	}
+	b.WriteString("\nFix them with set-gerrit-commit-message.")
+	return mcp.NewToolResultText(b.String()), nil
+}
+
+// commitMessageInput is Gerrit's CommitMessageInput
+type commitMessageInput struct {
+	Message string `json:"message"`
+}
+
+// SetGerritCommitMessage replaces the commit message of a change, which
+// creates a new patchset. The change's Change-Id footer is kept if the new
+// message leaves it out, and messages breaking the project's required
+// subject and footer conventions are refused.
+func (h *Handler) SetGerritCommitMessage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	message, err := request.RequireString("message")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	message = strings.TrimRight(message, "\n") + "\n"
+	if strings.TrimSpace(message) == "" {
+		return mcp.NewToolResultError("message must not be empty"), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+	if footers, start := messageFooters(lines); footers["change-id"] == "" && change.ChangeID != "" {
+		separator := "\n"
+		if start == len(lines) || start <= 1 {
+			separator = "\n\n"
+		}
+		message = strings.TrimRight(message, "\n") + separator + "Change-Id: " + change.ChangeID + "\n"
+	}
+
+	// Refuse messages breaking the project's policy before Gerrit or a
+	// reviewer does, and pass on the style advice
+	var refused, advice []string
+	for _, v := range checkCommitMessage(message, h.cfg().commitMessageConventions(change.Project)) {
+		if v.enforced() {
+			refused = append(refused, "  - "+v.String())
+		} else {
+			advice = append(advice, "  - "+v.String())
+		}
+	}
+	if len(refused) > 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("the commit message breaks the conventions of %s, so it was not set:\n%s", change.Project, strings.Join(refused, "\n"))), nil
+	}
+
+	if _, err := h.client.Call(ctx, http.MethodPut, fmt.Sprintf("changes/%s/message", changeID), commitMessageInput{Message: message}, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to set the commit message of change %s: %v", changeID, err)), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Updated the commit message of change %s in a new patchset", changeID)
+	if len(advice) > 0 {
+		fmt.Fprintf(&b, "\n\nThe message could still be improved:\n%s", strings.Join(advice, "\n"))
+	}
+	return mcp.NewToolResultText(b.String()), nil
+}
@@ -0,0 +1,43 @@
+package handler
+
+// compatibility records which side of the REST API differences between
+// Gerrit releases the server is on, so that one binary can serve a mixed
+// fleet. Until the server's version is probed, the newer API is assumed.
+type compatibility struct {
+	version string
+	// attentionSet is set from Gerrit 3.3, which replaced the assignee with the attention set
+	attentionSet bool
+	// assignee is set before Gerrit 3.9, which removed the assignee
+	assignee bool
+	// portedComments is set from Gerrit 3.3, which can port comments to a later patchset
+	portedComments bool
+	// submitRequirements is set from Gerrit 3.5, which replaced submit rules
+	// with submit requirements
+	submitRequirements bool
+	// starLabels is set before starLabelsRemovedIn, which kept only the default star
+	starLabels bool
+}
+
+// compat returns the API differences that apply to the probed server
+func (h *Handler) compat() compatibility {
+	caps := h.capabilities.Load()
+	if caps == nil {
+		return compatibility{attentionSet: true, portedComments: true, submitRequirements: true}
+	}
+	return compatibility{
+		version:            caps.Version,
+		attentionSet:       versionAtLeast(caps.Version, "3.3"),
+		assignee:           !versionAtLeast(caps.Version, assigneeRemovedIn),
+		portedComments:     versionAtLeast(caps.Version, "3.3"),
+		submitRequirements: versionAtLeast(caps.Version, "3.5"),
+		starLabels:         !versionAtLeast(caps.Version, starLabelsRemovedIn),
+	}
+}
+
+// waitingOnSelf is the search term for changes waiting on the calling user
+func (c compatibility) waitingOnSelf() string {
+	if c.attentionSet {
+		return "attention:self"
+	}
+	return "assignee:self"
+}
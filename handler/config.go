@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Config holds the settings read from the server's JSON configuration file
+type Config struct {
+	// Components maps paths to the components they belong to, first match wins
+	Components []ComponentRule `json:"components"`
+	// IssueTrackers recognise issue references in commit and review messages
+	IssueTrackers []IssueTracker `json:"issue_trackers"`
+	// ReviewTemplates are canned reviews by name, e.g. "lgtm-nits"
+	ReviewTemplates map[string]ReviewTemplate `json:"review_templates"`
+	// Checklists add review checklist items for changes touching certain paths
+	Checklists []ChecklistRule `json:"checklists"`
+	// SizeClasses classify changes by size, smallest first
+	SizeClasses []SizeClass `json:"size_classes"`
+	// DefaultReviewers add reviewers and CCs to changes by project and path
+	DefaultReviewers []DefaultReviewerRule `json:"default_reviewers"`
+	// ResponseBudgets cap response sizes in characters by tool name, with
+	// "default" applying to the other tools; 0 means unlimited
+	ResponseBudgets map[string]int `json:"response_budgets"`
+	// Redactions mask content before it is returned
+	Redactions []RedactionRule `json:"redactions"`
+	// Projects override settings for the changes of individual projects
+	Projects map[string]ProjectSettings `json:"projects"`
+	// EnabledTools and DisabledTools add tool names or categories to the
+	// GERRIT_MCP_ENABLED_TOOLS and GERRIT_MCP_DISABLED_TOOLS lists
+	EnabledTools  []string `json:"enabled_tools"`
+	DisabledTools []string `json:"disabled_tools"`
+	// MaxPatchFiles and MaxPatchLines override GERRIT_MCP_MAX_PATCH_FILES and
+	// GERRIT_MCP_MAX_PATCH_LINES when set
+	MaxPatchFiles *int `json:"max_patch_files"`
+	MaxPatchLines *int `json:"max_patch_lines"`
+	// Queries are Gerrit change queries by name, e.g.
+	// "team-open": "status:open project:^team/.* -is:wip"
+	Queries map[string]string `json:"queries"`
+	// CacheWarming runs some of the Queries on a schedule to fetch the
+	// patches of their results ahead of time
+	CacheWarming *CacheWarming `json:"cache_warming"`
+	// ReviewSLAHours is how long reviewers may leave a patchset without a
+	// response, DefaultReviewSLAHours if unset
+	ReviewSLAHours int `json:"review_sla_hours"`
+	// Backport labels the cherry-picks made by backport-gerrit-change
+	Backport BackportSettings `json:"backport"`
+	// CommentCategories classify review comments by keyword, first match
+	// wins; they replace the default nit, requested-change and question
+	CommentCategories []CommentCategory `json:"comment_categories"`
+	// CommitMessage are the commit message conventions of all projects
+	CommitMessage CommitMessageConventions `json:"commit_message"`
+	// ProjectGuidelines are the files get-gerrit-project-guidelines reads
+	// from a change's target branch, DefaultProjectGuidelines if unset
+	ProjectGuidelines []string `json:"project_guidelines"`
+	// CodeSearchURL links search-gerrit-project-code's results to a code
+	// search service, with {pattern}, {project} and {branch} filled in
+	CodeSearchURL string `json:"code_search_url"`
+	// CodeSearch is the code search service search-external-code queries
+	CodeSearch *CodeSearch `json:"code_search"`
+}
+
+// ComponentRule assigns files matching any of Paths to a component
+type ComponentRule struct {
+	Name string `json:"name"`
+	// Paths are globs where * and ? don't cross directories and ** does,
+	// e.g. "storage/**" or "**/*_test.go"
+	Paths []string `json:"paths"`
+
+	patterns []*regexp.Regexp
+}
+
+// LoadConfig reads and validates the configuration file at path
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := cfg.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// compile prepares the path patterns of the configuration
+func (c *Config) compile() error {
+	for i := range c.Components {
+		rule := &c.Components[i]
+		if rule.Name == "" {
+			return fmt.Errorf("component %d has no name", i)
+		}
+		rule.patterns = nil
+		for _, p := range rule.Paths {
+			re, err := globRegexp(p)
+			if err != nil {
+				return fmt.Errorf("component %s: %w", rule.Name, err)
+			}
+			rule.patterns = append(rule.patterns, re)
+		}
+	}
+	for i := range c.IssueTrackers {
+		if err := c.IssueTrackers[i].compile(); err != nil {
+			return err
+		}
+	}
+	for i := range c.Checklists {
+		if err := c.Checklists[i].compile(); err != nil {
+			return err
+		}
+	}
+	for i := range c.DefaultReviewers {
+		if err := c.DefaultReviewers[i].compile(); err != nil {
+			return err
+		}
+	}
+	for i := range c.Redactions {
+		if err := c.Redactions[i].compile(); err != nil {
+			return err
+		}
+	}
+	for i := range c.CommentCategories {
+		if err := c.CommentCategories[i].compile(); err != nil {
+			return err
+		}
+	}
+	for i, class := range c.SizeClasses {
+		if class.Name == "" {
+			return fmt.Errorf("size class %d has no name", i)
+		}
+	}
+	for name, budget := range c.ResponseBudgets {
+		if budget < 0 {
+			return fmt.Errorf("response budget of %s is negative", name)
+		}
+	}
+	if c.ReviewSLAHours < 0 {
+		return fmt.Errorf("review SLA must not be negative")
+	}
+	if err := c.CommitMessage.compile(); err != nil {
+		return err
+	}
+	for project, settings := range c.Projects {
+		if settings.ReviewSLAHours < 0 {
+			return fmt.Errorf("project %s: review SLA must not be negative", project)
+		}
+		if settings.CommitMessage != nil {
+			if err := settings.CommitMessage.compile(); err != nil {
+				return fmt.Errorf("project %s: %w", project, err)
+			}
+		}
+		for name, budget := range settings.ResponseBudgets {
+			if budget < 0 {
+				return fmt.Errorf("project %s: response budget of %s is negative", project, name)
+			}
+		}
+	}
+	if c.MaxPatchFiles != nil && *c.MaxPatchFiles < 0 || c.MaxPatchLines != nil && *c.MaxPatchLines < 0 {
+		return fmt.Errorf("patch limits must not be negative")
+	}
+	for name, query := range c.Queries {
+		if strings.TrimSpace(query) == "" {
+			return fmt.Errorf("query %s is empty", name)
+		}
+	}
+	if c.CacheWarming != nil {
+		if err := c.CacheWarming.compile(c.Queries); err != nil {
+			return err
+		}
+	}
+	for _, file := range c.ProjectGuidelines {
+		if strings.TrimSpace(file) == "" {
+			return fmt.Errorf("project guidelines list an empty file name")
+		}
+	}
+	if c.CodeSearch != nil {
+		if err := c.CodeSearch.compile(); err != nil {
+			return err
+		}
+	}
+	for name, t := range c.ReviewTemplates {
+		if t.Message == "" && len(t.Labels) == 0 {
+			return fmt.Errorf("review template %s has neither a message nor labels", name)
+		}
+	}
+	return nil
+}
+
+// WithConfig applies the settings of a configuration file
+func WithConfig(cfg *Config) Option {
+	return func(h *Handler) {
+		h.SetConfig(cfg)
+	}
+}
+
+// SetConfig replaces the settings of the configuration file, e.g. after it
+// was reloaded. Calls in progress finish with the settings they started with
+// wherever they read them once.
+func (h *Handler) SetConfig(cfg *Config) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	h.config.Store(cfg)
+}
+
+// cfg returns the current settings of the configuration file
+func (h *Handler) cfg() *Config {
+	return h.config.Load()
+}
+
+// component returns the component path belongs to, or "" if none matches
+func (c *Config) component(path string) string {
+	for _, rule := range c.Components {
+		for _, re := range rule.patterns {
+			if re.MatchString(path) {
+				return rule.Name
+			}
+		}
+	}
+	return ""
+}
+
+// globRegexp translates a path glob into a regular expression. A pattern
+// ending in / matches everything below that directory.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	if glob == "" {
+		return nil, fmt.Errorf("empty path pattern")
+	}
+	if strings.HasSuffix(glob, "/") {
+		glob += "**"
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "**/" also matches no directory at all
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// unconfirmed returns the error result of a destructive tool called without
+// confirm=true, describing what the call would do, or nil if it was confirmed
+func unconfirmed(request mcp.CallToolRequest, action string) *mcp.CallToolResult {
+	if request.GetBool("confirm", false) {
+		return nil
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("this would %s; nothing was changed. Call again with confirm=true once the user agreed", action))
+}
+
+// settingsURL returns the address of a page of Gerrit's user settings
+func (h *Handler) settingsURL(section string) string {
+	if h.baseURL == nil {
+		return "Gerrit's settings"
+	}
+	return strings.TrimSuffix(h.baseURL.String(), "/") + "/settings/#" + section
+}
+
+// ownCredentialsOnly refuses to manage the credentials of the account the
+// server itself is configured with: doing so would lock every session
+// sharing it out. Sessions with their own credentials manage their own.
+func ownCredentialsOnly(ctx context.Context, action string) *mcp.CallToolResult {
+	if _, ok := CredentialsFromContext(ctx); ok {
+		return nil
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("refusing to %s of the account this server is configured with, which every session without credentials of its own shares; connect with your own Gerrit credentials to manage yours", action))
+}
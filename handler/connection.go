@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+)
+
+// reauthInterval is how long a connection running in anonymous fallback mode
+// is kept before authentication is attempted again
+const reauthInterval = time.Minute
+
+// sessionConnectionIdle is how long a connection made with per-session
+// credentials is kept after its last use
+const sessionConnectionIdle = 30 * time.Minute
+
+// ConnectFunc creates a ready to use Gerrit client and reports the resulting
+// connection status. It is called on first use and again whenever the
+// adapter needs to reconnect, so it should re-read credentials each time.
+// When ctx carries per-session credentials (see CredentialsFromContext) it
+// must authenticate with those instead of the configured ones.
+type ConnectFunc func(ctx context.Context) (*gerrit.Client, ConnectionStatus, error)
+
+// errNotConnected is returned by an adapter created without a client or a way to connect
+var errNotConnected = errors.New("gerrit client is not connected")
+
+// connection is one authenticated Gerrit client
+type connection struct {
+	client    *gerrit.Client
+	status    ConnectionStatus
+	connected time.Time
+	lastUsed  time.Time
+	// connecting is closed when the connection attempt in progress, if
+	// any, ends; err is the outcome of the last attempt
+	connecting chan struct{}
+	err        error
+}
+
+// gerritClient returns the client for the credentials in ctx, connecting first if needed
+func (a *GerritClientAdapter) gerritClient(ctx context.Context) (*gerrit.Client, error) {
+	if a.connect == nil {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.client == nil {
+			return nil, errNotConnected
+		}
+		return a.client, nil
+	}
+
+	key := connectionKey(ctx)
+	for {
+		a.mu.Lock()
+		conn := a.connection(key)
+		if conn.client != nil {
+			a.mu.Unlock()
+			return conn.client, nil
+		}
+
+		// Connecting takes several round trips to Gerrit, so it happens
+		// outside the lock, once per set of credentials at a time
+		if wait := conn.connecting; wait != nil {
+			a.mu.Unlock()
+			select {
+			case <-wait:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			a.mu.Lock()
+			err := conn.err
+			a.mu.Unlock()
+			// The other call giving up is no reason for this one to
+			if err != nil && !isContextError(err) {
+				return nil, err
+			}
+			continue
+		}
+		done := make(chan struct{})
+		conn.connecting = done
+		a.mu.Unlock()
+
+		client, status, err := a.connect(ctx)
+
+		a.mu.Lock()
+		conn.connecting, conn.err = nil, err
+		close(done)
+		if err != nil {
+			status.State = "error"
+			status.LastError = err.Error()
+			conn.status = status
+			a.mu.Unlock()
+			return nil, err
+		}
+		status.State = "connected"
+		conn.client, conn.status, conn.connected = client, status, time.Now()
+		a.mu.Unlock()
+		return client, nil
+	}
+}
+
+// connection returns the connection for key, dropping idle per-session
+// connections and the client of one in anonymous fallback mode that is due
+// another authentication attempt; callers hold a.mu
+func (a *GerritClientAdapter) connection(key string) *connection {
+	now := time.Now()
+	for k, conn := range a.conns {
+		if k != "" && now.Sub(conn.lastUsed) > sessionConnectionIdle && conn.connecting == nil {
+			delete(a.conns, k)
+		}
+	}
+
+	conn, ok := a.conns[key]
+	if !ok {
+		conn = &connection{}
+		a.conns[key] = conn
+	}
+	conn.lastUsed = now
+
+	// Periodically retry authentication while running in anonymous fallback
+	// mode, so that fixed credentials take effect without a restart
+	if conn.client != nil && conn.status.ReadOnly && now.Sub(conn.connected) > reauthInterval {
+		conn.client = nil
+	}
+	return conn
+}
+
+// invalidate drops client so that the next call reconnects, unless another
+// call has already replaced it
+func (a *GerritClientAdapter) invalidate(ctx context.Context, client *gerrit.Client) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if conn, ok := a.conns[connectionKey(ctx)]; ok && conn.client == client {
+		conn.client = nil
+		conn.status.State = "reconnecting"
+	}
+}
+
+// Status reports the state of the connection used for ctx
+func (a *GerritClientAdapter) Status(ctx context.Context) ConnectionStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.connect == nil {
+		state := "connected"
+		if a.client == nil {
+			state = "not connected"
+		}
+		return ConnectionStatus{State: state}
+	}
+
+	conn, ok := a.conns[connectionKey(ctx)]
+	if !ok {
+		status := ConnectionStatus{State: "not connected yet"}
+		if creds, ok := CredentialsFromContext(ctx); ok {
+			status.Username = creds.Username
+		}
+		return status
+	}
+	return conn.status
+}
+
+// withClient runs fn with a connected client. When Gerrit answers 401
+// Unauthorized, e.g. because the credentials were rotated, the adapter
+// reconnects once and retries.
+func withClient[T any](ctx context.Context, a *GerritClientAdapter, fn func(*gerrit.Client) (T, *gerrit.Response, error)) (T, *gerrit.Response, error) {
+	for attempt := 0; ; attempt++ {
+		client, err := a.gerritClient(ctx)
+		if err != nil {
+			var zero T
+			return zero, nil, err
+		}
+
+		v, resp, err := fn(client)
+		if attempt == 0 && a.connect != nil && resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			a.invalidate(ctx, client)
+			continue
+		}
+		return v, resp, err
+	}
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// currentLine returns the line of the current patchset a thread is about, 0
+// for a comment on the whole file, or -1 if that can't be told because the
+// thread started on an older patchset and Gerrit didn't port it
+func (t *commentThread) currentLine(currentPatchSet int) int {
+	switch {
+	case t.root().PatchSet == currentPatchSet:
+		return t.root().Line
+	case t.ported != nil:
+		return t.ported.Line
+	default:
+		return -1
+	}
+}
+
+// GetGerritCommentCoverage reports which changed files and hunks of the
+// current patchset no comment thread refers to
+func (h *Handler) GetGerritCommentCoverage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	patch, err := h.currentPatch(ctx, changeID, change)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	threads, warnings, err := h.commentThreads(ctx, changeID, change, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	header = append(header, warnings...)
+	currentPatchSet := change.Revisions[change.CurrentRevision].Number
+
+	commented := make(map[string]bool)
+	lines := make(map[string][]int)
+	unmapped := 0
+	for _, t := range threads {
+		commented[t.path] = true
+		switch line := t.currentLine(currentPatchSet); {
+		case line > 0:
+			lines[t.path] = append(lines[t.path], line)
+		case line < 0:
+			unmapped++
+		}
+	}
+
+	files := ParsePatch(patch)
+	var uncommentedFiles, uncommentedHunks []string
+	coveredFiles, hunks, coveredHunks := 0, 0, 0
+	for _, f := range files {
+		if !commented[f.Path()] {
+			added, removed := 0, 0
+			for _, hunk := range f.Hunks {
+				a, r := hunk.Stats()
+				added, removed = added+a, removed+r
+			}
+			uncommentedFiles = append(uncommentedFiles, fmt.Sprintf("  %s (+%d -%d)", f.Path(), added, removed))
+			hunks += len(f.Hunks)
+			continue
+		}
+		coveredFiles++
+		for _, hunk := range f.Hunks {
+			hunks++
+			_, _, start, count := parseHunkHeader(hunk.Header)
+			end := start + max(count, 1) - 1
+			covered := false
+			for _, line := range lines[f.Path()] {
+				if line >= start && line <= end {
+					covered = true
+					break
+				}
+			}
+			if covered {
+				coveredHunks++
+				continue
+			}
+			added, removed := hunk.Stats()
+			uncommentedHunks = append(uncommentedHunks, fmt.Sprintf("  %s hunk %d: %s (+%d -%d)", f.Path(), hunk.Index, hunk.Header, added, removed))
+		}
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Comment coverage of patchset %d: %d of %d files and %d of %d hunks have comments\n",
+		currentPatchSet, coveredFiles, len(files), coveredHunks, hunks)
+	if unmapped > 0 {
+		fmt.Fprintf(&b, "%s on older patchsets could not be mapped to lines of patchset %d; they only count for their file\n", plural(unmapped, "thread"), currentPatchSet)
+	}
+	if len(uncommentedFiles) > 0 {
+		b.WriteString("\nFiles without comments:\n" + strings.Join(uncommentedFiles, "\n") + "\n")
+	}
+	if len(uncommentedHunks) > 0 {
+		b.WriteString("\nHunks without comments in commented files:\n" + strings.Join(uncommentedHunks, "\n") + "\n")
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// Credentials are Gerrit credentials supplied by an MCP client for its own session
+type Credentials struct {
+	Username string
+	Password string
+}
+
+type credentialsKey struct{}
+
+// ContextWithCredentials returns a context carrying per-session Gerrit credentials
+func ContextWithCredentials(ctx context.Context, creds Credentials) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, creds)
+}
+
+// CredentialsFromContext returns the per-session credentials stored in ctx, if any
+func CredentialsFromContext(ctx context.Context) (Credentials, bool) {
+	creds, ok := ctx.Value(credentialsKey{}).(Credentials)
+	return creds, ok && creds.Username != ""
+}
+
+// CredentialsFromRequest is an HTTP context function for the network
+// transports. It picks up Gerrit credentials sent by the client either as
+// X-Gerrit-Username/X-Gerrit-Password headers or as HTTP basic auth, so that
+// Gerrit attributes the session's actions to the actual user.
+func CredentialsFromRequest(ctx context.Context, r *http.Request) context.Context {
+	if username := r.Header.Get("X-Gerrit-Username"); username != "" {
+		return ContextWithCredentials(ctx, Credentials{Username: username, Password: r.Header.Get("X-Gerrit-Password")})
+	}
+	if username, password, ok := r.BasicAuth(); ok && username != "" {
+		return ContextWithCredentials(ctx, Credentials{Username: username, Password: password})
+	}
+	return ctx
+}
+
+// RequireCredentials refuses HTTP requests that carry no Gerrit credentials,
+// so that clients of the network transports can't act as the server's own
+// account without identifying themselves
+func RequireCredentials(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := CredentialsFromContext(CredentialsFromRequest(r.Context(), r)); !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Gerrit"`)
+			http.Error(w, "Gerrit credentials are required, as X-Gerrit-Username/X-Gerrit-Password headers or HTTP basic auth", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// connectionKey identifies the Gerrit connection to use for ctx. The shared
+// connection has the empty key; per-session credentials get their own, keyed
+// by a hash so that passwords are not kept around as map keys.
+func connectionKey(ctx context.Context) string {
+	creds, ok := CredentialsFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(creds.Username + "\x00" + creds.Password))
+	return hex.EncodeToString(sum[:])
+}
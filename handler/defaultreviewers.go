@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultReviewerRule names the reviewers and CCs of changes to certain
+// projects or paths, like Gerrit's reviewers plugin
+type DefaultReviewerRule struct {
+	// Projects are globs matched against the change's project; empty matches all
+	Projects []string `json:"projects"`
+	// Paths are globs matched against the changed files; empty matches all
+	Paths     []string `json:"paths"`
+	Reviewers []string `json:"reviewers"`
+	CCs       []string `json:"ccs"`
+
+	projectPatterns []*regexp.Regexp
+	pathPatterns    []*regexp.Regexp
+}
+
+// compile prepares the rule's project and path patterns
+func (r *DefaultReviewerRule) compile() error {
+	if len(r.Reviewers) == 0 && len(r.CCs) == 0 {
+		return fmt.Errorf("default reviewer rule for %s has neither reviewers nor ccs", strings.Join(append(r.Projects, r.Paths...), ", "))
+	}
+	var err error
+	if r.projectPatterns, err = globRegexps(r.Projects); err != nil {
+		return fmt.Errorf("default reviewer rule: %w", err)
+	}
+	if r.pathPatterns, err = globRegexps(r.Paths); err != nil {
+		return fmt.Errorf("default reviewer rule: %w", err)
+	}
+	return nil
+}
+
+// globRegexps translates a list of globs
+func globRegexps(globs []string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, g := range globs {
+		re, err := globRegexp(g)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// matchesAny reports whether any of values matches any of patterns, or
+// true if there are no patterns
+func matchesAny(patterns []*regexp.Regexp, values ...string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, v := range values {
+		for _, re := range patterns {
+			if re.MatchString(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ApplyGerritDefaultReviewers adds the reviewers and CCs configured for a
+// change's project and files
+func (h *Handler) ApplyGerritDefaultReviewers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	dryRun := request.GetBool("dry_run", false)
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "CURRENT_FILES")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	paths := sortedKeys(changedFiles(change))
+
+	// A reviewer wins over a CC of the same account
+	var inputs []reviewerInput
+	seen := make(map[string]int)
+	for _, rule := range h.cfg().DefaultReviewers {
+		if !matchesAny(rule.projectPatterns, change.Project) || !matchesAny(rule.pathPatterns, paths...) {
+			continue
+		}
+		for _, list := range []struct {
+			state    string
+			accounts []string
+		}{{"REVIEWER", rule.Reviewers}, {"CC", rule.CCs}} {
+			for _, account := range list.accounts {
+				if i, ok := seen[account]; ok {
+					if list.state == "REVIEWER" {
+						inputs[i].State = "REVIEWER"
+					}
+					continue
+				}
+				seen[account] = len(inputs)
+				inputs = append(inputs, reviewerInput{Reviewer: account, State: list.state})
+			}
+		}
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	if len(inputs) == 0 {
+		fmt.Fprintf(&b, "No default reviewers are configured for project %s and the files of change %s", change.Project, changeID)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	if dryRun {
+		fmt.Fprintf(&b, "Default reviewers for change %s (dry run, nothing was added):\n", changeID)
+	} else {
+		fmt.Fprintf(&b, "Default reviewers for change %s:\n", changeID)
+	}
+	for _, input := range inputs {
+		role := strings.ToLower(input.State)
+		if dryRun {
+			fmt.Fprintf(&b, "  %s as %s\n", input.Reviewer, role)
+			continue
+		}
+		if err := h.throttle(ctx); err != nil {
+			fmt.Fprintf(&b, "  %s: SKIPPED: %v\n", input.Reviewer, err)
+			continue
+		}
+
+		var result reviewerResult
+		_, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/reviewers", changeID), input, &result)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&b, "  %s: FAILED: %v\n", input.Reviewer, err)
+		case result.Confirm:
+			fmt.Fprintf(&b, "  %s: not added, Gerrit asks for confirmation: %s (use add-gerrit-reviewer)\n", input.Reviewer, result.Error)
+		case result.Error != "":
+			fmt.Fprintf(&b, "  %s: FAILED: %s\n", input.Reviewer, result.Error)
+		default:
+			fmt.Fprintf(&b, "  %s: added as %s%s\n", input.Reviewer, role, describeAdded(append(result.Reviewers, result.CCs...)))
+		}
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// describeAdded names the accounts a reviewer input resolved to, if it was a group
+func describeAdded(accounts []gerrit.AccountInfo) string {
+	if len(accounts) <= 1 {
+		return ""
+	}
+	names := make([]string, len(accounts))
+	for i, a := range accounts {
+		names[i] = formatAccount(a)
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(names, ", "))
+}
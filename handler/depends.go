@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxDependencyNodes bounds how many changes a dependency graph may contain
+const maxDependencyNodes = 50
+
+var dependsOnRegexp = regexp.MustCompile(`(?mi)^Depends-On:\s*(\S+)\s*$`)
+
+// relatedChangesInfo is Gerrit's RelatedChangesInfo
+type relatedChangesInfo struct {
+	Changes []struct {
+		Project      string            `json:"project"`
+		Commit       gerrit.CommitInfo `json:"commit"`
+		ChangeNumber int               `json:"_change_number"`
+		Status       string            `json:"status"`
+	} `json:"changes"`
+}
+
+// depNode is a change in a dependency graph, or a dependency outside this Gerrit server
+type depNode struct {
+	key     string
+	project string
+	subject string
+	status  string
+	// external is set for dependencies that can't be looked up here
+	external bool
+	deps     []depEdge
+}
+
+// depEdge points from a change to a change it depends on
+type depEdge struct {
+	to   string
+	kind string
+}
+
+// dependencyGraph collects the changes reachable from a starting change
+type dependencyGraph struct {
+	h     *Handler
+	nodes map[string]*depNode
+	order []string
+	queue []string
+	// dependents is set to also follow edges backwards, to the changes
+	// that depend on those in the graph
+	dependents bool
+}
+
+// node returns the node for key, creating it and queueing it for expansion if new
+func (g *dependencyGraph) node(key string) *depNode {
+	if n, ok := g.nodes[key]; ok {
+		return n
+	}
+	n := &depNode{key: key}
+	g.nodes[key] = n
+	g.order = append(g.order, key)
+	g.queue = append(g.queue, key)
+	return n
+}
+
+// addEdge records that from depends on to, once per kind
+func (n *depNode) addEdge(to, kind string) {
+	for _, e := range n.deps {
+		if e.to == to && e.kind == kind {
+			return
+		}
+	}
+	n.deps = append(n.deps, depEdge{to: to, kind: kind})
+}
+
+// expand fetches the change behind n and adds its Depends-On and git parent dependencies
+func (g *dependencyGraph) expand(ctx context.Context, n *depNode) error {
+	change, _, err := g.h.client.GetChange(ctx, n.key, &gerrit.ChangeOptions{
+		AdditionalFields: []string{"CURRENT_REVISION", "CURRENT_COMMIT"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get change %s: %v", n.key, err)
+	}
+	n.project = change.Project
+	n.subject = change.Subject
+	n.status = change.Status
+
+	for _, m := range dependsOnRegexp.FindAllStringSubmatch(change.Revisions[change.CurrentRevision].Commit.Message, -1) {
+		n.addEdge(g.h.dependencyKey(ctx, m[1]), "Depends-On")
+	}
+
+	var related relatedChangesInfo
+	path := fmt.Sprintf("changes/%d/revisions/current/related", change.Number)
+	if _, err := g.h.client.Call(ctx, http.MethodGet, path, nil, &related); err != nil {
+		return fmt.Errorf("failed to get related changes of %d: %v", change.Number, err)
+	}
+	byCommit := make(map[string]int)
+	for _, r := range related.Changes {
+		byCommit[r.Commit.Commit] = r.ChangeNumber
+	}
+	for _, parent := range change.Revisions[change.CurrentRevision].Commit.Parents {
+		if number, ok := byCommit[parent.Commit]; ok && number != 0 {
+			n.addEdge(strconv.Itoa(number), "parent commit")
+		}
+	}
+	if !g.dependents {
+		return nil
+	}
+
+	// Children in the relation chain
+	for _, r := range related.Changes {
+		for _, parent := range r.Commit.Parents {
+			if parent.Commit == change.CurrentRevision && r.ChangeNumber != 0 {
+				g.node(strconv.Itoa(r.ChangeNumber)).addEdge(n.key, "parent commit")
+			}
+		}
+	}
+
+	// Changes naming this one in a Depends-On trailer, by Change-Id or URL.
+	// The search is full text, so the trailers of the hits are checked.
+	query := fmt.Sprintf("message:%s OR message:%d", change.ChangeID, change.Number)
+	dependents, _, err := g.h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{query}, Limit: maxDependencyNodes},
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: []string{"CURRENT_REVISION", "CURRENT_COMMIT"}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to search changes depending on %d: %v", change.Number, err)
+	}
+	for _, d := range *dependents {
+		if d.Number == change.Number {
+			continue
+		}
+		for _, m := range dependsOnRegexp.FindAllStringSubmatch(d.Revisions[d.CurrentRevision].Commit.Message, -1) {
+			if m[1] == change.ChangeID || g.h.dependencyKey(ctx, m[1]) == n.key {
+				g.node(strconv.Itoa(d.Number)).addEdge(n.key, "Depends-On")
+			}
+		}
+	}
+	return nil
+}
+
+// build expands the graph from root until no changes are left to fetch, or
+// the graph reaches maxDependencyNodes, which is reported as truncated
+func (g *dependencyGraph) build(ctx context.Context, root string) (truncated bool, err error) {
+	g.node(root)
+	for len(g.queue) > 0 {
+		key := g.queue[0]
+		g.queue = g.queue[1:]
+		n := g.nodes[key]
+		if !isChangeNumber(key) {
+			n.external = true
+			continue
+		}
+		if len(g.nodes) > maxDependencyNodes {
+			truncated = true
+			continue
+		}
+		if err := g.expand(ctx, n); err != nil {
+			return truncated, err
+		}
+		for _, e := range n.deps {
+			g.node(e.to)
+		}
+	}
+	return truncated, nil
+}
+
+// dependencyKey resolves a Depends-On value to a change number, or returns
+// it unchanged if it refers to another system or can't be resolved
+func (h *Handler) dependencyKey(ctx context.Context, ref string) string {
+	if strings.Contains(ref, "://") {
+		if !h.isKnownHost(changeHost(ref)) {
+			return ref
+		}
+		id, err := extractChangeID(ref)
+		if err != nil {
+			return ref
+		}
+		return id
+	}
+	// A Change-Id, which may be ambiguous across branches
+	change, _, err := h.client.GetChange(ctx, ref, nil)
+	if err != nil {
+		return ref
+	}
+	return strconv.Itoa(change.Number)
+}
+
+// GetGerritChangeDependencies returns the graph of changes a change depends
+// on, following Depends-On trailers across repositories and git parents
+// within a relation chain, together with an order in which to submit them
+func (h *Handler) GetGerritChangeDependencies(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, _, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	g := &dependencyGraph{h: h, nodes: make(map[string]*depNode)}
+	truncated, err := g.build(ctx, changeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Dependency graph of change %s (%s):\n\n", changeID, plural(len(g.nodes), "change"))
+	for _, key := range g.order {
+		n := g.nodes[key]
+		fmt.Fprintf(&b, "%s\n", g.describe(key))
+		for _, e := range n.deps {
+			fmt.Fprintf(&b, "  depends on %s (%s)\n", g.describe(e.to), e.kind)
+		}
+	}
+	if truncated {
+		fmt.Fprintf(&b, "\nWARNING: the graph was cut off at %d changes\n", maxDependencyNodes)
+	}
+
+	order, cycle := g.submissionOrder()
+	if cycle {
+		b.WriteString("\nWARNING: the dependencies contain a cycle, so there is no valid submission order\n")
+	} else if len(order) > 0 {
+		fmt.Fprintf(&b, "\nSubmission order of unmerged changes: %s\n", strings.Join(order, ", "))
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// graphNode is a change in the JSON change graph
+type graphNode struct {
+	ID       string `json:"id"`
+	Project  string `json:"project,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Status   string `json:"status,omitempty"`
+	External bool   `json:"external,omitempty"`
+}
+
+// graphEdge points from a change to a change it depends on
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// GetGerritChangeGraph returns the whole stack around a change as JSON nodes
+// and edges: its ancestors, its descendants and the changes linked to any of
+// them by Depends-On, together with the order in which they can land
+func (h *Handler) GetGerritChangeGraph(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, _, _, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	g := &dependencyGraph{h: h, nodes: make(map[string]*depNode), dependents: true}
+	truncated, err := g.build(ctx, changeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	nodes := make([]graphNode, 0, len(g.order))
+	edges := []graphEdge{}
+	for _, key := range g.order {
+		n := g.nodes[key]
+		nodes = append(nodes, graphNode{ID: key, Project: n.project, Subject: n.subject, Status: n.status, External: n.external})
+		for _, e := range n.deps {
+			edges = append(edges, graphEdge{From: key, To: e.to, Kind: e.kind})
+		}
+	}
+	order, cycle := g.submissionOrder()
+	if order == nil || cycle {
+		order = []string{}
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Root         string      `json:"root"`
+		Nodes        []graphNode `json:"nodes"`
+		Edges        []graphEdge `json:"edges"`
+		LandingOrder []string    `json:"landing_order"`
+		Cycle        bool        `json:"cycle"`
+		Truncated    bool        `json:"truncated"`
+	}{changeID, nodes, edges, order, cycle, truncated}, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode the change graph: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// describe renders a node in one line
+func (g *dependencyGraph) describe(key string) string {
+	n := g.nodes[key]
+	switch {
+	case n.external && strings.Contains(key, "://"):
+		return fmt.Sprintf("%s [not on this Gerrit server]", key)
+	case n.external:
+		return fmt.Sprintf("%s [could not be resolved]", key)
+	case n.status == "":
+		return fmt.Sprintf("%s [not fetched]", key)
+	default:
+		return fmt.Sprintf("%s [%s] %s: %s", key, n.status, n.project, n.subject)
+	}
+}
+
+// submissionOrder sorts the unmerged changes so that every change comes after
+// its dependencies, reporting whether a cycle made that impossible
+func (g *dependencyGraph) submissionOrder() (order []string, cycle bool) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var visit func(key string)
+	visit = func(key string) {
+		switch state[key] {
+		case visiting:
+			cycle = true
+			return
+		case done:
+			return
+		}
+		state[key] = visiting
+		n := g.nodes[key]
+		deps := append([]depEdge(nil), n.deps...)
+		sort.Slice(deps, func(i, j int) bool { return deps[i].to < deps[j].to })
+		for _, e := range deps {
+			visit(e.to)
+		}
+		state[key] = done
+		if !n.external && n.status != "" && n.status != "MERGED" && n.status != "ABANDONED" {
+			order = append(order, key)
+		}
+	}
+	for _, key := range g.order {
+		visit(key)
+	}
+	return order, cycle
+}
+
+// isChangeNumber reports whether s is a numeric change ID
+func isChangeNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileDiff is the diff of a single file within a patch
+type FileDiff struct {
+	OldPath string
+	NewPath string
+	// Header holds the lines from "diff --git" up to the first hunk
+	Header []string
+	Hunks  []Hunk
+}
+
+// Path returns the path of the file after the change, or before it if the file was deleted
+func (f FileDiff) Path() string {
+	if f.NewPath == "" {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// Hunk is one "@@" section of a file diff, addressable by file and index
+type Hunk struct {
+	File  string
+	Index int
+	// Header is the "@@ -a,b +c,d @@ context" line
+	Header string
+	// OldStart and NewStart are the line numbers of the hunk's first line
+	// in the old and the new file
+	OldStart int
+	NewStart int
+	Lines    []string
+}
+
+// Stats counts the added and removed lines of the hunk
+func (h Hunk) Stats() (added, removed int) {
+	for _, line := range h.Lines {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// String renders the hunk as it appears in the patch
+func (h Hunk) String() string {
+	return strings.Join(append([]string{h.Header}, h.Lines...), "\n")
+}
+
+var hunkHeaderRegexp = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch splits a unified diff, such as the output of git format-patch,
+// into files and hunks. Anything outside the file diffs, such as the commit
+// message or the signature trailer, is ignored.
+func ParsePatch(patch string) []FileDiff {
+	var files []FileDiff
+	p := patchParser{visit: func(f FileDiff) error {
+		files = append(files, f)
+		return nil
+	}}
+	// Lines are substrings of patch, so parsing doesn't copy the content
+	for line := range strings.SplitSeq(patch, "\n") {
+		p.line(line)
+	}
+	p.finish()
+	return files
+}
+
+// errStopScan is returned by a ScanPatch visitor that needs no more files
+var errStopScan = errors.New("stop scanning")
+
+// ScanPatch parses a unified diff like ParsePatch, but reads it
+// incrementally and hands each file to visit as soon as it is complete, so
+// that only the current file is held in memory. It returns the first error
+// of r or visit; a visitor returns errStopScan to stop early without error.
+func ScanPatch(r io.Reader, visit func(FileDiff) error) error {
+	p := patchParser{visit: visit}
+	br := bufio.NewReader(r)
+	for p.err == nil {
+		line, err := br.ReadString('\n')
+		if err == io.EOF {
+			p.line(line)
+			break
+		}
+		if err != nil {
+			return err
+		}
+		p.line(strings.TrimSuffix(line, "\n"))
+	}
+	p.finish()
+	if p.err == errStopScan {
+		return nil
+	}
+	return p.err
+}
+
+// patchParser is the state of parsing a patch line by line
+type patchParser struct {
+	visit func(FileDiff) error
+	err   error
+	file  *FileDiff
+	hunk  *Hunk
+	// Lines still expected in the current hunk, so that a trailer such as
+	// "-- " is not mistaken for a removed line
+	oldLeft, newLeft int
+}
+
+func (p *patchParser) flushHunk() {
+	if p.file != nil && p.hunk != nil {
+		p.file.Hunks = append(p.file.Hunks, *p.hunk)
+	}
+	p.hunk = nil
+}
+
+func (p *patchParser) flushFile() {
+	p.flushHunk()
+	if p.file != nil && p.err == nil {
+		// The paths are only final once the whole header is read
+		for i := range p.file.Hunks {
+			p.file.Hunks[i].File = p.file.Path()
+		}
+		p.err = p.visit(*p.file)
+	}
+	p.file = nil
+}
+
+// finish hands over the last file
+func (p *patchParser) finish() {
+	p.flushFile()
+}
+
+// line parses the next line of the patch, without its line break
+func (p *patchParser) line(line string) {
+	switch {
+	case strings.HasPrefix(line, "diff --git "):
+		p.flushFile()
+		p.file = &FileDiff{Header: []string{line}}
+		if a, b, ok := strings.Cut(strings.TrimPrefix(line, "diff --git "), " b/"); ok {
+			p.file.OldPath = strings.TrimPrefix(a, "a/")
+			p.file.NewPath = b
+		}
+	case p.file == nil:
+		// Commit message and diffstat before the first file
+	case strings.HasPrefix(line, "@@ ") && (p.hunk == nil || p.oldLeft <= 0 && p.newLeft <= 0):
+		p.flushHunk()
+		p.hunk = &Hunk{File: p.file.Path(), Index: len(p.file.Hunks), Header: line}
+		p.hunk.OldStart, p.oldLeft, p.hunk.NewStart, p.newLeft = parseHunkHeader(line)
+	case p.hunk == nil:
+		p.file.Header = append(p.file.Header, line)
+		switch {
+		case line == "--- /dev/null":
+			p.file.OldPath = ""
+		case line == "+++ /dev/null":
+			p.file.NewPath = ""
+		case strings.HasPrefix(line, "--- a/"):
+			p.file.OldPath = strings.TrimPrefix(line, "--- a/")
+		case strings.HasPrefix(line, "+++ b/"):
+			p.file.NewPath = strings.TrimPrefix(line, "+++ b/")
+		}
+	case p.oldLeft > 0 || p.newLeft > 0 || strings.HasPrefix(line, `\`):
+		p.hunk.Lines = append(p.hunk.Lines, line)
+		switch {
+		case strings.HasPrefix(line, "+"):
+			p.newLeft--
+		case strings.HasPrefix(line, "-"):
+			p.oldLeft--
+		case strings.HasPrefix(line, `\`):
+		default:
+			p.oldLeft--
+			p.newLeft--
+		}
+	}
+}
+
+// parseHunkHeader returns the start lines and line counts of the old and
+// the new file from a hunk header
+func parseHunkHeader(header string) (oldStart, oldLines, newStart, newLines int) {
+	m := hunkHeaderRegexp.FindStringSubmatch(header)
+	if m == nil {
+		return 0, 0, 0, 0
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	oldStart, _ = strconv.Atoi(m[1])
+	newStart, _ = strconv.Atoi(m[3])
+	return oldStart, count(m[2]), newStart, count(m[4])
+}
+
+// splitPatch cuts a patch into the part before the first file diff, such as
+// the commit message, and the raw text of each file diff. The parts share the
+// patch's memory.
+func splitPatch(patch string) (preamble string, files []string) {
+	start := -1
+	for offset := 0; offset < len(patch); {
+		if strings.HasPrefix(patch[offset:], "diff --git ") {
+			if start < 0 {
+				preamble = patch[:offset]
+			} else {
+				files = append(files, patch[start:offset])
+			}
+			start = offset
+		}
+		next := strings.IndexByte(patch[offset:], '\n')
+		if next < 0 {
+			break
+		}
+		offset += next + 1
+	}
+	if start < 0 {
+		return patch, nil
+	}
+	return preamble, append(files, patch[start:])
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// previewContext is the number of unchanged lines shown around an edit
+const previewContext = 3
+
+// fileContent fetches a file from an endpoint returning base64 encoded content
+func (h *Handler) fileContent(ctx context.Context, path string) (string, *gerrit.Response, error) {
+	var buf bytes.Buffer
+	resp, err := h.client.Call(ctx, http.MethodGet, path, nil, &buf)
+	if err != nil {
+		return "", resp, err
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.TrimSpace(buf.String()))
+	if err != nil {
+		return "", resp, fmt.Errorf("decode content: %w", err)
+	}
+	return string(content), resp, nil
+}
+
+// putEditFile replaces a file in the change edit of a change
+func (h *Handler) putEditFile(ctx context.Context, changeID, path, content string) error {
+	input := map[string]string{
+		"binary_content": "data:text/plain;base64," + base64.StdEncoding.EncodeToString([]byte(content)),
+	}
+	if _, err := h.client.Call(ctx, http.MethodPut, fmt.Sprintf("changes/%s/edit/%s", changeID, url.PathEscape(path)), input, nil); err != nil {
+		return fmt.Errorf("failed to update %s in the change edit of change %s: %v", path, changeID, err)
+	}
+	return nil
+}
+
+// ApplySuggestedEdit replaces a range of lines of a file in the change edit
+// of a change, creating the edit if needed, and returns a preview diff. The
+// edit only becomes a patchset once published with publish-gerrit-change-edit.
+func (h *Handler) ApplySuggestedEdit(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	filePath, err := request.RequireString("file")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	startLine := request.GetInt("start_line", 0)
+	endLine := request.GetInt("end_line", 0)
+	replacement, err := request.RequireString("replacement")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if startLine < 1 || endLine < startLine-1 {
+		return mcp.NewToolResultError("start_line must be at least 1 and end_line at least start_line - 1 (which inserts before start_line)"), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Build on an existing change edit, so that several suggestions can be
+	// combined into one patchset
+	escaped := url.PathEscape(filePath)
+	content, resp, err := h.fileContent(ctx, fmt.Sprintf("changes/%s/edit/%s", changeID, escaped))
+	if err != nil || resp == nil || resp.StatusCode == http.StatusNoContent {
+		content, _, err = h.fileContent(ctx, fmt.Sprintf("changes/%s/revisions/%s/files/%s/content", changeID, change.CurrentRevision, escaped))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get %s of change %s: %v", filePath, changeID, err)), nil
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	trailingNewline := strings.HasSuffix(content, "\n")
+	if trailingNewline {
+		lines = lines[:len(lines)-1]
+	}
+	if endLine > len(lines) {
+		return mcp.NewToolResultError(fmt.Sprintf("%s has %d lines, cannot replace up to line %d", filePath, len(lines), endLine)), nil
+	}
+
+	var newLines []string
+	if replacement != "" {
+		newLines = strings.Split(strings.TrimSuffix(replacement, "\n"), "\n")
+	}
+	edited := append(append(append([]string{}, lines[:startLine-1]...), newLines...), lines[endLine:]...)
+	newContent := strings.Join(edited, "\n")
+	if trailingNewline && len(edited) > 0 {
+		newContent += "\n"
+	}
+
+	if err := h.putEditFile(ctx, changeID, filePath, newContent); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Updated %s in the change edit of change %s. Preview:\n\n", filePath, changeID)
+	b.WriteString(editPreview(filePath, lines, startLine, endLine, newLines))
+	b.WriteString("\nThe edit is not published yet; use publish-gerrit-change-edit to publish it as a new patchset or to discard it.")
+	return mcp.NewToolResultText(b.String()), nil
+}
+
+// editPreview renders the replacement of lines[start-1:end] by newLines as a unified diff
+func editPreview(path string, lines []string, start, end int, newLines []string) string {
+	from := max(start-1-previewContext, 0)
+	to := min(end+previewContext, len(lines))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+	oldCount := to - from
+	newCount := oldCount - (end - start + 1) + len(newLines)
+	fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", from+1, oldCount, from+1, newCount)
+	for _, l := range lines[from : start-1] {
+		b.WriteString(" " + l + "\n")
+	}
+	for _, l := range lines[start-1 : end] {
+		b.WriteString("-" + l + "\n")
+	}
+	for _, l := range newLines {
+		b.WriteString("+" + l + "\n")
+	}
+	for _, l := range lines[end:to] {
+		b.WriteString(" " + l + "\n")
+	}
+	return b.String()
+}
+
+// PublishGerritChangeEdit publishes the change edit of a change as a new
+// patchset, or discards it
+func (h *Handler) PublishGerritChangeEdit(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, _, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+
+	if request.GetBool("discard", false) {
+		if _, err := h.client.Call(ctx, http.MethodDelete, fmt.Sprintf("changes/%s/edit", changeID), nil, nil); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to discard the change edit of change %s: %v", changeID, err)), nil
+		}
+		fmt.Fprintf(&b, "Discarded the change edit of change %s", changeID)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	if _, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/edit:publish", changeID), map[string]any{}, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to publish the change edit of change %s: %v", changeID, err)), nil
+	}
+	fmt.Fprintf(&b, "Published the change edit of change %s as a new patchset", changeID)
+	return mcp.NewToolResultText(b.String()), nil
+}
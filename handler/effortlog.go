@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxEffortEntries bounds how many entries the review log tool lists
+const maxEffortEntries = 200
+
+// EffortLog is an append-only JSON lines file recording which tools were
+// used on which changes, so that users can reconstruct what was examined
+// before signing off on a change
+type EffortLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// effortEntry is one line of the effort log
+type effortEntry struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"session,omitempty"`
+	Tool    string    `json:"tool"`
+	Change  string    `json:"change,omitempty"`
+	File    string    `json:"file,omitempty"`
+	Failed  bool      `json:"failed,omitempty"`
+}
+
+// NewEffortLog opens the effort log at path, creating it if needed
+func NewEffortLog(path string) (*EffortLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &EffortLog{path: path, file: file}, nil
+}
+
+// Close closes the effort log file
+func (l *EffortLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// WithEffortLog records tool calls in l and enables the tool reading it back
+func WithEffortLog(l *EffortLog) Option {
+	return func(h *Handler) {
+		h.effortLog = l
+	}
+}
+
+func (l *EffortLog) append(entry effortEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+// entries reads back the entries matching keep, oldest first
+func (l *EffortLog) entries(keep func(effortEntry) bool) ([]effortEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []effortEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry effortEntry
+		// Skip lines a crash may have left half-written
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && keep(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// RecordEffort appends every call of a tool about a change to the effort log
+func (h *Handler) RecordEffort(tool Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if h.effortLog == nil || tool.Tool.Name == "get-gerrit-review-log" {
+		return next
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+
+		changeURL := request.GetString("change_url", "")
+		if changeURL == "" {
+			return result, err
+		}
+		entry := effortEntry{
+			Time:    time.Now().UTC(),
+			Session: sessionID(ctx),
+			Tool:    tool.Tool.Name,
+			Change:  changeURL,
+			File:    request.GetString("file", ""),
+			Failed:  err != nil || result == nil || result.IsError,
+		}
+		if changeID, idErr := extractChangeID(changeURL); idErr == nil {
+			entry.Change = changeID
+		}
+		if logErr := h.effortLog.append(entry); logErr != nil {
+			log.Printf("Warning: failed to write the effort log: %v", logErr)
+		}
+		return result, err
+	}
+}
+
+// GetGerritReviewLog lists the tool calls recorded for a change, by default
+// those of the current session
+func (h *Handler) GetGerritReviewLog(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	changeID, err := extractChangeID(changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to parse change URL: %v", err)), nil
+	}
+	allSessions := request.GetBool("all_sessions", false)
+	session := sessionID(ctx)
+
+	entries, err := h.effortLog.entries(func(e effortEntry) bool {
+		return e.Change == changeID && (allSessions || e.Session == session)
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read the effort log: %v", err)), nil
+	}
+
+	scope := "this session"
+	if allSessions {
+		scope = "all sessions"
+	}
+	if len(entries) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No tools were used on change %s in %s", changeID, scope)), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s on change %s in %s:\n\n", plural(len(entries), "tool call"), changeID, scope)
+	if len(entries) > maxEffortEntries {
+		fmt.Fprintf(&b, "(the %d oldest calls are left out)\n", len(entries)-maxEffortEntries)
+		entries = entries[len(entries)-maxEffortEntries:]
+	}
+	for _, e := range entries {
+		line := fmt.Sprintf("%s %s", e.Time.Format("2006-01-02 15:04:05"), e.Tool)
+		if e.File != "" {
+			line += " " + e.File
+		}
+		if allSessions && e.Session != "" {
+			line += " [session " + e.Session + "]"
+		}
+		if e.Failed {
+			line += " (failed)"
+		}
+		b.WriteString(line + "\n")
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// withConditionalResults adds the if_none_match parameter to the read tools,
+// which ConditionalResults acts on
+func withConditionalResults(tools []Tool) []Tool {
+	for i := range tools {
+		if tools[i].Category != CategoryRead {
+			continue
+		}
+		mcp.WithString("if_none_match",
+			mcp.Description("etag of a previous result of the same call; if the result is unchanged only a short note is returned"),
+		)(&tools[i].Tool)
+	}
+	return tools
+}
+
+// resultETag hashes the content of a result
+func resultETag(result *mcp.CallToolResult) string {
+	hash := sha256.New()
+	for _, content := range result.Content {
+		switch c := content.(type) {
+		case mcp.TextContent:
+			fmt.Fprintf(hash, "text\x00%s\x00", c.Text)
+		case mcp.ImageContent:
+			fmt.Fprintf(hash, "image\x00%s\x00%s\x00", c.MIMEType, c.Data)
+		case mcp.EmbeddedResource:
+			if blob, ok := c.Resource.(mcp.BlobResourceContents); ok {
+				fmt.Fprintf(hash, "blob\x00%s\x00%s\x00", blob.URI, blob.Blob)
+			} else if text, ok := c.Resource.(mcp.TextResourceContents); ok {
+				fmt.Fprintf(hash, "resource\x00%s\x00%s\x00", text.URI, text.Text)
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+// ConditionalResults tags the results of tools taking if_none_match with an
+// etag, and replaces a result by a short note when it matches the etag the
+// caller already has, so that polling clients don't re-read unchanged data
+func (h *Handler) ConditionalResults(tool Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if _, ok := tool.Tool.InputSchema.Properties["if_none_match"]; !ok {
+		return next
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError {
+			return result, err
+		}
+
+		etag := resultETag(result)
+		if request.GetString("if_none_match", "") == etag {
+			return mcp.NewToolResultText(fmt.Sprintf("not modified: the result still has etag %s", etag)), nil
+		}
+		result.Content = append(result.Content, mcp.NewTextContent("etag: "+etag))
+		return result, nil
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// exportSchemaVersion is the version of the export format, raised whenever
+// a field is removed or changes meaning
+const exportSchemaVersion = 1
+
+// exportOptions are the Gerrit options fetching everything known about a change
+var exportOptions = []string{
+	"ALL_REVISIONS", "ALL_COMMITS", "ALL_FILES", "DETAILED_ACCOUNTS", "DETAILED_LABELS",
+	"MESSAGES", "REVIEWER_UPDATES", "SUBMITTABLE",
+}
+
+// changeExport is the bundle returned by export-gerrit-change. Change and
+// Comments are Gerrit's ChangeInfo and comments as returned by the REST API.
+type changeExport struct {
+	SchemaVersion int             `json:"schema_version"`
+	ExportedAt    string          `json:"exported_at"`
+	Server        string          `json:"server,omitempty"`
+	Change        json.RawMessage `json:"change"`
+	Comments      json.RawMessage `json:"comments"`
+	// Patches maps revisions to their patches, for the revisions exported with one
+	Patches map[string]string `json:"patches,omitempty"`
+}
+
+// ExportGerritChange returns a change with all revisions, files, messages
+// and comments as one JSON document, for archiving or analytics pipelines
+func (h *Handler) ExportGerritChange(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := h.checkHost(changeURL); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	changeID, err := extractChangeID(changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to parse change URL: %v", err)), nil
+	}
+
+	query := url.Values{"o": exportOptions}
+	export := changeExport{SchemaVersion: exportSchemaVersion, ExportedAt: time.Now().UTC().Format(time.RFC3339)}
+	if h.baseURL != nil {
+		export.Server = h.baseURL.String()
+	}
+	if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("changes/%s?%s", changeID, query.Encode()), nil, &export.Change); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get change %s: %v", changeID, err)), nil
+	}
+	if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("changes/%s/comments", changeID), nil, &export.Comments); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get comments of change %s: %v", changeID, err)), nil
+	}
+
+	if request.GetBool("include_patch", false) {
+		var change gerrit.ChangeInfo
+		if err := json.Unmarshal(export.Change, &change); err != nil || change.CurrentRevision == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("no current revision found for change %s", changeID)), nil
+		}
+		if h.exceedsPatchLimits(&change) {
+			return mcp.NewToolResultError(fmt.Sprintf("change %s is too large to export with its patch: %s, +%d -%d lines; export it without include_patch",
+				changeID, plural(len(changedFiles(&change)), "file"), change.Insertions, change.Deletions)), nil
+		}
+		patch, err := h.currentPatch(ctx, changeID, &change)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		export.Patches = map[string]string{change.CurrentRevision: patch}
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode change %s: %v", changeID, err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
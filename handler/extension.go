@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Extension contributes organisation-specific tools. It is given the handler
+// so that its tools can use the same Gerrit connection as the built-in ones.
+type Extension func(h *Handler) []Tool
+
+var (
+	extensionsMu sync.Mutex
+	extensions   []Extension
+)
+
+// RegisterExtension adds tools to every server built from this binary. It is
+// meant to be called from an init function in a file added to the build, so
+// that deployments can ship their own tools without patching existing code.
+func RegisterExtension(ext Extension) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+
+	extensions = append(extensions, ext)
+}
+
+// ExtensionTools returns the tools contributed by registered extensions
+func (h *Handler) ExtensionTools() []Tool {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+
+	var tools []Tool
+	for _, ext := range extensions {
+		tools = append(tools, ext(h)...)
+	}
+	return tools
+}
+
+// Client returns the Gerrit client used by the handler, for use by extensions
+func (h *Handler) Client() GerritClient {
+	return h.client
+}
+
+// ExternalToolSpec describes a tool implemented by an external program. The
+// program receives {"tool": name, "arguments": {...}} as JSON on stdin and
+// its stdout becomes the tool result; a non-zero exit status makes the
+// result an error carrying its stderr.
+type ExternalToolSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Category defaults to write, since the server can't know what the program does
+	Category   string                  `json:"category"`
+	Command    []string                `json:"command"`
+	Parameters []ExternalToolParameter `json:"parameters"`
+}
+
+// ExternalToolParameter describes a parameter of an external tool
+type ExternalToolParameter struct {
+	Name string `json:"name"`
+	// Type is string (default), number or boolean
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// LoadExternalTools reads a JSON array of ExternalToolSpec from path
+func LoadExternalTools(path string) ([]Tool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []ExternalToolSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	tools := make([]Tool, 0, len(specs))
+	for _, spec := range specs {
+		tool, err := externalTool(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// externalTool builds the MCP tool for spec
+func externalTool(spec ExternalToolSpec) (Tool, error) {
+	if spec.Name == "" {
+		return Tool{}, fmt.Errorf("external tool without a name")
+	}
+	if len(spec.Command) == 0 {
+		return Tool{}, fmt.Errorf("external tool %s has no command", spec.Name)
+	}
+
+	category := spec.Category
+	switch category {
+	case "":
+		category = CategoryWrite
+	case CategoryRead, CategoryWrite, CategoryAdmin:
+	default:
+		return Tool{}, fmt.Errorf("external tool %s has unknown category %q", spec.Name, category)
+	}
+
+	opts := []mcp.ToolOption{mcp.WithDescription(spec.Description)}
+	for _, p := range spec.Parameters {
+		propOpts := []mcp.PropertyOption{mcp.Description(p.Description)}
+		if p.Required {
+			propOpts = append(propOpts, mcp.Required())
+		}
+		switch p.Type {
+		case "", "string":
+			opts = append(opts, mcp.WithString(p.Name, propOpts...))
+		case "number":
+			opts = append(opts, mcp.WithNumber(p.Name, propOpts...))
+		case "boolean":
+			opts = append(opts, mcp.WithBoolean(p.Name, propOpts...))
+		default:
+			return Tool{}, fmt.Errorf("parameter %s of external tool %s has unknown type %q", p.Name, spec.Name, p.Type)
+		}
+	}
+
+	return Tool{
+		ServerTool: server.ServerTool{
+			Tool:    mcp.NewTool(spec.Name, opts...),
+			Handler: runExternalTool(spec),
+		},
+		Category: category,
+	}, nil
+}
+
+// runExternalTool returns the handler executing spec's command
+func runExternalTool(spec ExternalToolSpec) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		input, err := json.Marshal(map[string]any{
+			"tool":      spec.Name,
+			"arguments": request.GetArguments(),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to encode arguments: %v", err)), nil
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, spec.Command[0], spec.Command[1:]...)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				msg = strings.TrimSpace(stdout.String())
+			}
+			return mcp.NewToolResultError(fmt.Sprintf("%s failed: %v: %s", spec.Name, err, msg)), nil
+		}
+
+		return mcp.NewToolResultText(strings.TrimRight(stdout.String(), "\n")), nil
+	}
+}
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Types of code search services search-external-code can query
+const (
+	CodeSearchSourcegraph = "sourcegraph"
+	CodeSearchOpenGrok    = "opengrok"
+)
+
+// Bounds of search-external-code
+const (
+	defaultExternalSearchResults = 20
+	maxExternalSearchResults     = 100
+	maxExternalSearchResponse    = 10 << 20
+)
+
+// CodeSearch is an external code search service covering the code of the
+// Gerrit projects
+type CodeSearch struct {
+	// Type is sourcegraph or opengrok
+	Type string `json:"type"`
+	// URL is the service's base URL, e.g. https://sourcegraph.example.com
+	URL string `json:"url"`
+	// TokenEnv names the environment variable holding an access token
+	TokenEnv string `json:"token_env"`
+	// Repository is the service's name of a Gerrit project, with {project}
+	// standing for the project name; by default the project name itself
+	Repository string `json:"repository"`
+}
+
+// compile validates the code search settings
+func (s *CodeSearch) compile() error {
+	switch s.Type {
+	case CodeSearchSourcegraph, CodeSearchOpenGrok:
+	default:
+		return fmt.Errorf("code search: unknown type %q, expected %s or %s", s.Type, CodeSearchSourcegraph, CodeSearchOpenGrok)
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("code search: %q is not an absolute URL", s.URL)
+	}
+	return nil
+}
+
+// repository returns the service's name of a Gerrit project
+func (s *CodeSearch) repository(project string) string {
+	if s.Repository == "" {
+		return project
+	}
+	return strings.ReplaceAll(s.Repository, "{project}", project)
+}
+
+// codeSearchHit is a line found by an external code search
+type codeSearchHit struct {
+	repository string
+	path       string
+	line       int
+	text       string
+	link       string
+}
+
+// htmlTagRegexp matches the highlighting markup of OpenGrok's results
+var htmlTagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+// do sends a request to the code search service and decodes its JSON answer
+func (s *CodeSearch) do(ctx context.Context, method, path string, body io.Reader, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(s.URL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := os.Getenv(s.TokenEnv); s.TokenEnv != "" && token != "" {
+		if s.Type == CodeSearchSourcegraph {
+			req.Header.Set("Authorization", "token "+token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxExternalSearchResponse))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data[:min(len(data), 200)])))
+	}
+	return json.Unmarshal(data, v)
+}
+
+// sourcegraphQuery asks Sourcegraph's GraphQL API for matching lines
+const sourcegraphQuery = `query ($query: String!) {
+  search(query: $query, version: V3) {
+    results {
+      limitHit
+      results {
+        ... on FileMatch {
+          repository { name }
+          file { path url }
+          lineMatches { preview lineNumber }
+        }
+      }
+    }
+  }
+}`
+
+// searchSourcegraph searches a Sourcegraph instance
+func (s *CodeSearch) searchSourcegraph(ctx context.Context, query, repository string, limit int) ([]codeSearchHit, bool, error) {
+	q := fmt.Sprintf("%s count:%d", query, limit)
+	if repository != "" {
+		q = fmt.Sprintf("repo:^%s$ %s", regexp.QuoteMeta(repository), q)
+	}
+	input, err := json.Marshal(map[string]any{"query": sourcegraphQuery, "variables": map[string]string{"query": q}})
+	if err != nil {
+		return nil, false, err
+	}
+	var answer struct {
+		Data struct {
+			Search struct {
+				Results struct {
+					LimitHit bool `json:"limitHit"`
+					Results  []struct {
+						Repository struct {
+							Name string `json:"name"`
+						} `json:"repository"`
+						File struct {
+							Path string `json:"path"`
+							URL  string `json:"url"`
+						} `json:"file"`
+						LineMatches []struct {
+							Preview    string `json:"preview"`
+							LineNumber int    `json:"lineNumber"`
+						} `json:"lineMatches"`
+					} `json:"results"`
+				} `json:"results"`
+			} `json:"search"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/.api/graphql", bytes.NewReader(input), &answer); err != nil {
+		return nil, false, err
+	}
+	if len(answer.Errors) > 0 {
+		return nil, false, fmt.Errorf("%s", answer.Errors[0].Message)
+	}
+
+	results := answer.Data.Search.Results
+	var hits []codeSearchHit
+	for _, file := range results.Results {
+		for _, match := range file.LineMatches {
+			// Sourcegraph counts lines from 0
+			line := match.LineNumber + 1
+			hits = append(hits, codeSearchHit{
+				repository: file.Repository.Name,
+				path:       file.File.Path,
+				line:       line,
+				text:       match.Preview,
+				link:       fmt.Sprintf("%s%s?L%d", strings.TrimSuffix(s.URL, "/"), file.File.URL, line),
+			})
+		}
+	}
+	return hits, results.LimitHit, nil
+}
+
+// searchOpenGrok searches an OpenGrok instance through its REST API
+func (s *CodeSearch) searchOpenGrok(ctx context.Context, query, repository string, limit int) ([]codeSearchHit, bool, error) {
+	params := url.Values{"full": {query}, "maxresults": {strconv.Itoa(limit)}}
+	if repository != "" {
+		params.Set("projects", repository)
+	}
+	var answer struct {
+		ResultCount int `json:"resultCount"`
+		// Results maps /project/path to the matching lines of the file
+		Results map[string][]struct {
+			Line       string `json:"line"`
+			LineNumber string `json:"lineNumber"`
+		} `json:"results"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/api/v1/search?"+params.Encode(), nil, &answer); err != nil {
+		return nil, false, err
+	}
+
+	var hits []codeSearchHit
+	for _, file := range sortedKeys(answer.Results) {
+		repo, path, _ := strings.Cut(strings.TrimPrefix(file, "/"), "/")
+		for _, match := range answer.Results[file] {
+			line, _ := strconv.Atoi(match.LineNumber)
+			hits = append(hits, codeSearchHit{
+				repository: repo,
+				path:       path,
+				line:       line,
+				text:       html.UnescapeString(htmlTagRegexp.ReplaceAllString(match.Line, "")),
+				link:       fmt.Sprintf("%s/xref%s#%d", strings.TrimSuffix(s.URL, "/"), file, line),
+			})
+		}
+	}
+	return hits, answer.ResultCount > len(answer.Results), nil
+}
+
+// SearchExternalCode searches the configured code search service, which
+// covers more code than a single Gerrit project and links to its results
+func (h *Handler) SearchExternalCode(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	limit := request.GetInt("limit", defaultExternalSearchResults)
+	if limit <= 0 {
+		return mcp.NewToolResultError("limit must be positive"), nil
+	}
+	limit = min(limit, maxExternalSearchResults)
+	service := h.cfg().CodeSearch
+	if service == nil {
+		return mcp.NewToolResultError("no code search service is configured; set code_search in the configuration file"), nil
+	}
+
+	project := request.GetString("project", "")
+	var header []string
+	if changeURL := request.GetString("change_url", ""); changeURL != "" && project == "" {
+		_, change, changeHeader, err := h.lookupChange(ctx, changeURL)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		project, header = change.Project, changeHeader
+	}
+	repository := ""
+	if project != "" {
+		repository = service.repository(project)
+	}
+
+	var hits []codeSearchHit
+	var more bool
+	if service.Type == CodeSearchSourcegraph {
+		hits, more, err = service.searchSourcegraph(ctx, query, repository, limit)
+	} else {
+		hits, more, err = service.searchOpenGrok(ctx, query, repository, limit)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to search %s: %v", service.URL, err)), nil
+	}
+	if len(hits) > limit {
+		hits, more = hits[:limit], true
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	scope := "all repositories"
+	if repository != "" {
+		scope = repository
+	}
+	fmt.Fprintf(&b, "Code search for %s in %s: %s\n", query, scope, plural(len(hits), "matching line"))
+	for _, hit := range hits {
+		text := strings.TrimSpace(hit.text)
+		if len(text) > maxSearchLineLength {
+			text = strings.ToValidUTF8(text[:maxSearchLineLength], "") + "..."
+		}
+		if repository == "" {
+			fmt.Fprintf(&b, "%s: ", hit.repository)
+		}
+		fmt.Fprintf(&b, "%s:%d: %s\n  %s\n", hit.path, hit.line, text, hit.link)
+	}
+	if more {
+		b.WriteString("\nNOTE: there are more results; narrow the query or raise limit\n")
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
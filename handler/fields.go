@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+)
+
+// changeFields maps the blocks callers can select to the Gerrit options
+// (the o parameter) that fetch them
+var changeFields = map[string][]string{
+	"owner":       {"DETAILED_ACCOUNTS"},
+	"labels":      {"DETAILED_LABELS", "DETAILED_ACCOUNTS"},
+	"reviewers":   {"DETAILED_LABELS", "DETAILED_ACCOUNTS"},
+	"messages":    {"MESSAGES"},
+	"files":       {"CURRENT_REVISION", "CURRENT_FILES"},
+	"commit":      {"CURRENT_REVISION", "CURRENT_COMMIT"},
+	"submittable": {"SUBMITTABLE"},
+}
+
+// parseFields parses a comma-separated list of change blocks and returns them
+// with the Gerrit options they need
+func parseFields(value string) (fields, options []string, err error) {
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		opts, ok := changeFields[field]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown field %q, expected some of %s", field, strings.Join(sortedKeys(changeFields), ", "))
+		}
+		fields = append(fields, field)
+		for _, opt := range opts {
+			if !seen[opt] {
+				seen[opt] = true
+				options = append(options, opt)
+			}
+		}
+	}
+	return fields, options, nil
+}
+
+// renderFields writes the selected blocks of a change, each line indented
+func renderFields(b *strings.Builder, change gerrit.ChangeInfo, fields []string, indent string) {
+	for _, field := range fields {
+		switch field {
+		case "owner":
+			fmt.Fprintf(b, "%sowner: %s\n", indent, formatAccount(change.Owner))
+		case "labels":
+			for _, name := range sortedKeys(change.Labels) {
+				fmt.Fprintf(b, "%s%s: %s\n", indent, name, formatLabel(change.Labels[name]))
+			}
+		case "reviewers":
+			for _, state := range sortedKeys(change.Reviewers) {
+				names := make([]string, len(change.Reviewers[state]))
+				for i, a := range change.Reviewers[state] {
+					names[i] = formatAccount(a)
+				}
+				fmt.Fprintf(b, "%s%s: %s\n", indent, strings.ToLower(state), strings.Join(names, ", "))
+			}
+		case "messages":
+			fmt.Fprintf(b, "%s%s:\n", indent, plural(len(change.Messages), "message"))
+			for _, m := range change.Messages {
+				firstLine, _, _ := strings.Cut(m.Message, "\n")
+				fmt.Fprintf(b, "%s  %s %s: %s\n", indent, m.Date.UTC().Format("2006-01-02 15:04"), formatAccount(m.Author), firstLine)
+			}
+		case "files":
+			files := changedFiles(&change)
+			fmt.Fprintf(b, "%s%s:\n", indent, plural(len(files), "file"))
+			for _, path := range sortedKeys(files) {
+				fmt.Fprintf(b, "%s  %s +%d -%d\n", indent, path, files[path].LinesInserted, files[path].LinesDeleted)
+			}
+		case "commit":
+			message := strings.TrimRight(change.Revisions[change.CurrentRevision].Commit.Message, "\n")
+			fmt.Fprintf(b, "%scommit message:\n", indent)
+			for _, line := range strings.Split(message, "\n") {
+				fmt.Fprintf(b, "%s  %s\n", indent, line)
+			}
+		case "submittable":
+			fmt.Fprintf(b, "%ssubmittable: %t\n", indent, change.Submittable)
+		}
+	}
+}
+
+// formatLabel describes the votes on a label
+func formatLabel(label gerrit.LabelInfo) string {
+	var votes []string
+	all := append([]gerrit.ApprovalInfo(nil), label.All...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Value > all[j].Value })
+	for _, a := range all {
+		if a.Value != 0 {
+			votes = append(votes, fmt.Sprintf("%+d %s", a.Value, formatAccount(a.AccountInfo)))
+		}
+	}
+	if len(votes) == 0 {
+		return "no votes"
+	}
+	return strings.Join(votes, ", ")
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ListGerritChangeFiles lists the files changed by the current patchset with
+// their size, language and, if configured, the component they belong to
+func (h *Handler) ListGerritChangeFiles(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	_, change, header, err := h.lookupChange(ctx, changeURL, "CURRENT_FILES")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	files := changedFiles(change)
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "%d files, +%d -%d lines, %s\n\n", len(files), change.Insertions, change.Deletions,
+		h.cfg().sizeSummary(len(files), change.Insertions+change.Deletions))
+
+	components := make(map[string]int)
+	for _, path := range paths {
+		info := files[path]
+		status := info.Status
+		if status == "" {
+			status = "M"
+		}
+		name := path
+		if info.OldPath != "" {
+			name = fmt.Sprintf("%s -> %s", info.OldPath, path)
+		}
+		fmt.Fprintf(&b, "%s %s", status, name)
+		if info.Binary {
+			b.WriteString(" (binary)")
+		} else {
+			fmt.Fprintf(&b, " +%d -%d", info.LinesInserted, info.LinesDeleted)
+		}
+		if lang := DetectLanguage(path); lang != "" {
+			fmt.Fprintf(&b, " [%s]", lang)
+		}
+		if component := h.cfg().component(path); component != "" {
+			fmt.Fprintf(&b, " component=%s", component)
+			components[component]++
+		}
+		b.WriteString("\n")
+	}
+
+	if len(components) > 0 {
+		b.WriteString("\nComponents touched:\n")
+		for _, name := range sortedKeys(components) {
+			fmt.Fprintf(&b, "  %s: %s\n", name, plural(components[name], "file"))
+		}
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// plural formats a count with a singular or plural noun
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// robotCommentInfo is Gerrit's RobotCommentInfo, limited to what the tools use
+type robotCommentInfo struct {
+	commentInfo
+	RobotID        string              `json:"robot_id"`
+	FixSuggestions []fixSuggestionInfo `json:"fix_suggestions"`
+}
+
+// fixSuggestionInfo is Gerrit's FixSuggestionInfo
+type fixSuggestionInfo struct {
+	FixID        string               `json:"fix_id"`
+	Description  string               `json:"description"`
+	Replacements []fixReplacementInfo `json:"replacements"`
+}
+
+type fixReplacementInfo struct {
+	Path        string `json:"path"`
+	Replacement string `json:"replacement"`
+}
+
+// ApplyGerritFixSuggestion applies a robot comment's fix suggestion to a
+// change edit and optionally publishes the edit as a new patchset. Without a
+// fix ID it lists the fix suggestions available on the change.
+func (h *Handler) ApplyGerritFixSuggestion(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fixID := request.GetString("fix_id", "")
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	currentPatchSet := change.Revisions[change.CurrentRevision].Number
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+
+	if fixID == "" {
+		var robotComments map[string][]robotCommentInfo
+		if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("changes/%s/robotcomments", changeID), nil, &robotComments); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get robot comments of change %s: %v", changeID, err)), nil
+		}
+		n := 0
+		for _, path := range sortedKeys(robotComments) {
+			for _, c := range robotComments[path] {
+				for _, fix := range c.FixSuggestions {
+					n++
+					fmt.Fprintf(&b, "fix_id %s by %s on %s line %d (patchset %d): %s\n", fix.FixID, c.RobotID, path, c.Line, c.PatchSet, fix.Description)
+					if c.PatchSet != currentPatchSet {
+						fmt.Fprintf(&b, "  WARNING: made on an older patchset, applying it to patchset %d may fail\n", currentPatchSet)
+					}
+				}
+			}
+		}
+		if n == 0 {
+			fmt.Fprintf(&b, "Change %s has no fix suggestions", changeID)
+		}
+		return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+	}
+
+	path := fmt.Sprintf("changes/%s/revisions/%s/fixes/%s/apply", changeID, change.CurrentRevision, fixID)
+	if _, err := h.client.Call(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to apply fix %s to change %s: %v", fixID, changeID, err)), nil
+	}
+	fmt.Fprintf(&b, "Applied fix %s to a change edit of change %s\n", fixID, changeID)
+
+	if !request.GetBool("publish", false) {
+		b.WriteString("The change edit is not published yet; review it and use publish-gerrit-change-edit to publish it as a new patchset or to discard it")
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	if _, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/edit:publish", changeID), map[string]any{}, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("applied fix %s but failed to publish the change edit of change %s: %v", fixID, changeID, err)), nil
+	}
+	fmt.Fprintf(&b, "Published the change edit as patchset %d", currentPatchSet+1)
+	return mcp.NewToolResultText(b.String()), nil
+}
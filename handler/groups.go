@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// groupInput is Gerrit's GroupInput
+type groupInput struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description,omitempty"`
+	Owner        string   `json:"owner,omitempty"`
+	VisibleToAll bool     `json:"visible_to_all,omitempty"`
+	Members      []string `json:"members,omitempty"`
+}
+
+// membersInput is Gerrit's MembersInput
+type membersInput struct {
+	Members []string `json:"members"`
+}
+
+// createdGroup holds the fields of Gerrit's GroupInfo that creating a group reports
+type createdGroup struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+}
+
+// splitList splits a comma-separated argument, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// CreateGerritGroup creates a group, optionally with an owner group and
+// initial members
+func (h *Handler) CreateGerritGroup(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	name = strings.TrimSpace(name)
+	input := groupInput{
+		Name:         name,
+		Description:  request.GetString("description", ""),
+		Owner:        strings.TrimSpace(request.GetString("owner", "")),
+		VisibleToAll: request.GetBool("visible_to_all", false),
+		Members:      splitList(request.GetString("members", "")),
+	}
+
+	var group createdGroup
+	if _, err := h.client.Call(ctx, http.MethodPut, "groups/"+url.PathEscape(name), input, &group); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create group %s: %v", name, err)), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Created group %s (%s)", group.Name, group.ID)
+	if group.Owner != "" && group.Owner != group.Name {
+		fmt.Fprintf(&b, ", owned by %s", group.Owner)
+	}
+	if len(input.Members) > 0 {
+		fmt.Fprintf(&b, ", with members %s", strings.Join(input.Members, ", "))
+	}
+	return mcp.NewToolResultText(b.String()), nil
+}
+
+// UpdateGerritGroupMembers adds and removes members of a group
+func (h *Handler) UpdateGerritGroupMembers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	group, err := request.RequireString("group")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	group = strings.TrimSpace(group)
+	add := splitList(request.GetString("add", ""))
+	remove := splitList(request.GetString("remove", ""))
+	if len(add) == 0 && len(remove) == 0 {
+		return mcp.NewToolResultError("add or remove must name at least one account"), nil
+	}
+	if len(remove) > 0 {
+		if result := unconfirmed(request, fmt.Sprintf("remove %s from %s, taking away the access the group grants", strings.Join(remove, ", "), group)); result != nil {
+			return result, nil
+		}
+	}
+
+	var b strings.Builder
+	escaped := url.PathEscape(group)
+	if len(add) > 0 {
+		var added []gerrit.AccountInfo
+		if _, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("groups/%s/members.add", escaped), membersInput{Members: add}, &added); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to add members to %s: %v", group, err)), nil
+		}
+		names := make([]string, len(added))
+		for i, a := range added {
+			names[i] = formatAccount(a)
+		}
+		fmt.Fprintf(&b, "Added to %s: %s\n", group, strings.Join(names, ", "))
+	}
+	if len(remove) > 0 {
+		if _, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("groups/%s/members.delete", escaped), membersInput{Members: remove}, nil); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("%sfailed to remove members from %s: %v", b.String(), group, err)), nil
+		}
+		fmt.Fprintf(&b, "Removed from %s: %s\n", group, strings.Join(remove, ", "))
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// SetGerritGroupOwner makes another group the owner of a group, whose
+// members may then administrate it
+func (h *Handler) SetGerritGroupOwner(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	group, err := request.RequireString("group")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	owner, err := request.RequireString("owner")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	group, owner = strings.TrimSpace(group), strings.TrimSpace(owner)
+	if result := unconfirmed(request, fmt.Sprintf("let the members of %s administrate %s, and only them besides Gerrit administrators", owner, group)); result != nil {
+		return result, nil
+	}
+
+	path := fmt.Sprintf("groups/%s/owner", url.PathEscape(group))
+	if _, err := h.client.Call(ctx, http.MethodPut, path, map[string]string{"owner": owner}, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to set the owner of %s: %v", group, err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("%s is now owned by %s", group, owner)), nil
+}
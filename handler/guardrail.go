@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+)
+
+// Default limits above which get-gerrit-change returns a summary instead of the patch
+const (
+	DefaultMaxPatchFiles = 200
+	DefaultMaxPatchLines = 10000
+)
+
+// WithPatchLimits sets how many files and changed lines a change may have
+// before get-gerrit-change returns a diffstat instead of the patch. Zero
+// disables the respective limit.
+func WithPatchLimits(maxFiles, maxLines int) Option {
+	return func(h *Handler) {
+		h.maxPatchFiles = maxFiles
+		h.maxPatchLines = maxLines
+	}
+}
+
+// changedFiles returns the files of the current revision, without Gerrit's
+// magic files such as /COMMIT_MSG
+func changedFiles(change *gerrit.ChangeInfo) map[string]gerrit.FileInfo {
+	files := make(map[string]gerrit.FileInfo)
+	for path, info := range change.Revisions[change.CurrentRevision].Files {
+		if !strings.HasPrefix(path, "/") {
+			files[path] = info
+		}
+	}
+	return files
+}
+
+// patchLimits returns the patch limits, those of the configuration file
+// taking precedence
+func (h *Handler) patchLimits() (maxFiles, maxLines int) {
+	maxFiles, maxLines = h.maxPatchFiles, h.maxPatchLines
+	cfg := h.cfg()
+	if cfg.MaxPatchFiles != nil {
+		maxFiles = *cfg.MaxPatchFiles
+	}
+	if cfg.MaxPatchLines != nil {
+		maxLines = *cfg.MaxPatchLines
+	}
+	return maxFiles, maxLines
+}
+
+// exceedsPatchLimits reports whether change is too big to return as one patch
+func (h *Handler) exceedsPatchLimits(change *gerrit.ChangeInfo) bool {
+	maxFiles, maxLines := h.patchLimits()
+	if maxFiles > 0 && len(changedFiles(change)) > maxFiles {
+		return true
+	}
+	return maxLines > 0 && change.Insertions+change.Deletions > maxLines
+}
+
+// diffstat describes the size of change and lists its files
+func (h *Handler) diffstat(change *gerrit.ChangeInfo) string {
+	files := changedFiles(change)
+
+	maxFiles, maxLines := h.patchLimits()
+	var b strings.Builder
+	fmt.Fprintf(&b, "This change is too large to return as a single patch: %d files, +%d -%d lines (limits: %d files, %d lines).\n",
+		len(files), change.Insertions, change.Deletions, maxFiles, maxLines)
+	b.WriteString("Use list-gerrit-change-files and get-gerrit-change-hunks to review it file by file, or call again with force=true to fetch the patch truncated to the response size budget.\n\n")
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		info := files[path]
+		status := info.Status
+		if status == "" {
+			status = "M"
+		}
+		name := path
+		if info.OldPath != "" {
+			name = fmt.Sprintf("%s -> %s", info.OldPath, path)
+		}
+		if info.Binary {
+			fmt.Fprintf(&b, "%s %s (binary)\n", status, name)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s +%d -%d\n", status, name, info.LinesInserted, info.LinesDeleted)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultProjectGuidelines are the files get-gerrit-project-guidelines reads
+// unless configured otherwise
+var DefaultProjectGuidelines = []string{
+	"README.md",
+	"README",
+	"CONTRIBUTING.md",
+	".github/CONTRIBUTING.md",
+	"CODEOWNERS",
+	".github/CODEOWNERS",
+	"STYLE.md",
+	"docs/STYLE.md",
+}
+
+// maxGuidelineChars is how much of each file get-gerrit-project-guidelines
+// returns by default
+const maxGuidelineChars = 20000
+
+// projectGuidelines returns the files holding the conventions of a project
+func (c *Config) projectGuidelines(project string) []string {
+	if files := c.Projects[project].ProjectGuidelines; len(files) > 0 {
+		return files
+	}
+	if len(c.ProjectGuidelines) > 0 {
+		return c.ProjectGuidelines
+	}
+	return DefaultProjectGuidelines
+}
+
+// GetGerritProjectGuidelines reads the README, contributing guidelines, code
+// owners and style guides of a change's project from its target branch, so
+// that a review can follow the project's conventions
+func (h *Handler) GetGerritProjectGuidelines(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	maxChars := request.GetInt("max_chars", maxGuidelineChars)
+	if maxChars < 0 {
+		return mcp.NewToolResultError("max_chars must not be negative"), nil
+	}
+
+	_, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	cfg := h.cfg()
+	files := splitList(request.GetString("files", ""))
+	if len(files) == 0 {
+		files = cfg.projectGuidelines(change.Project)
+	}
+
+	var b strings.Builder
+	var missing, redacted []string
+	found := 0
+	for _, file := range files {
+		file = strings.TrimPrefix(file, "/")
+		// files may name any file of the branch, not just guidelines
+		if cfg.redactedPath(file) {
+			redacted = append(redacted, file)
+			continue
+		}
+		// The branch file API needs the slashes of the path encoded too
+		contentPath := fmt.Sprintf("projects/%s/branches/%s/files/%s/content", url.PathEscape(change.Project), url.PathEscape(change.Branch),
+			strings.ReplaceAll(url.PathEscape(file), "/", "%2F"))
+		content, resp, err := h.fileContent(ctx, contentPath)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			missing = append(missing, file)
+			continue
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read %s from branch %s of %s: %v", file, change.Branch, change.Project, err)), nil
+		}
+		found++
+
+		fmt.Fprintf(&b, "\n=== %s ===\n", file)
+		switch {
+		case strings.ContainsRune(content, 0):
+			b.WriteString("(binary file, not shown)\n")
+		case maxChars > 0 && len(content) > maxChars:
+			b.WriteString(strings.ToValidUTF8(content[:maxChars], ""))
+			fmt.Fprintf(&b, "\n... truncated after %d of %d characters\n", maxChars, len(content))
+		default:
+			b.WriteString(strings.TrimRight(content, "\n") + "\n")
+		}
+	}
+
+	var out strings.Builder
+	if len(header) > 0 {
+		out.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	if found == 0 && len(redacted) == 0 {
+		fmt.Fprintf(&out, "None of %s exists on branch %s of %s", strings.Join(files, ", "), change.Branch, change.Project)
+		return mcp.NewToolResultText(out.String()), nil
+	}
+	fmt.Fprintf(&out, "Guidelines of %s from branch %s: %d of %s found\n", change.Project, change.Branch, found, plural(len(files), "file"))
+	if len(missing) > 0 {
+		fmt.Fprintf(&out, "Not found: %s\n", strings.Join(missing, ", "))
+	}
+	if len(redacted) > 0 {
+		fmt.Fprintf(&out, "Redacted by policy, not read: %s\n", strings.Join(redacted, ", "))
+	}
+	out.WriteString(b.String())
+	return mcp.NewToolResultText(strings.TrimRight(out.String(), "\n")), nil
+}
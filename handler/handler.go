@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 
 	"github.com/andygrunwald/go-gerrit"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -14,11 +22,21 @@ import (
 type GerritClient interface {
 	GetChange(ctx context.Context, changeID string, opt *gerrit.ChangeOptions) (*gerrit.ChangeInfo, *gerrit.Response, error)
 	GetPatch(ctx context.Context, changeID, revisionID string, opt *gerrit.PatchOptions) (*string, *gerrit.Response, error)
+	QueryChanges(ctx context.Context, opt *gerrit.QueryChangeOptions) (*[]gerrit.ChangeInfo, *gerrit.Response, error)
+	SetReview(ctx context.Context, changeID, revisionID string, input *gerrit.ReviewInput) (*gerrit.ReviewResult, *gerrit.Response, error)
+	// Call sends a request to a REST endpoint that go-gerrit has no typed
+	// method for, such as a plugin's. The response is decoded into v, or
+	// copied to it as is if v is an io.Writer.
+	Call(ctx context.Context, method, path string, body, v any) (*gerrit.Response, error)
 }
 
 // GerritClientAdapter adapts the go-gerrit client to implement GerritClient interface
 type GerritClientAdapter struct {
-	client *gerrit.Client
+	connect ConnectFunc
+	client  *gerrit.Client
+
+	mu    sync.Mutex
+	conns map[string]*connection
 }
 
 // NewGerritClientAdapter creates a new adapter for the go-gerrit client
@@ -26,27 +44,143 @@ func NewGerritClientAdapter(client *gerrit.Client) *GerritClientAdapter {
 	return &GerritClientAdapter{client: client}
 }
 
+// NewLazyGerritClientAdapter creates an adapter that connects to Gerrit on
+// first use, reconnects when the server stops accepting its credentials and
+// keeps a separate connection for each set of per-session credentials
+func NewLazyGerritClientAdapter(connect ConnectFunc) *GerritClientAdapter {
+	return &GerritClientAdapter{connect: connect, conns: make(map[string]*connection)}
+}
+
 // GetChange implements GerritClient interface
 func (a *GerritClientAdapter) GetChange(ctx context.Context, changeID string, opt *gerrit.ChangeOptions) (*gerrit.ChangeInfo, *gerrit.Response, error) {
-	return a.client.Changes.GetChange(ctx, changeID, opt)
+	return withClient(ctx, a, func(c *gerrit.Client) (*gerrit.ChangeInfo, *gerrit.Response, error) {
+		return c.Changes.GetChange(ctx, changeID, opt)
+	})
 }
 
-// GetPatch implements GerritClient interface
+// GetPatch implements GerritClient interface. The patch is read through
+// Call rather than go-gerrit's GetPatch, which decodes the body as JSON,
+// something neither a base64 encoded nor a zipped patch is.
 func (a *GerritClientAdapter) GetPatch(ctx context.Context, changeID, revisionID string, opt *gerrit.PatchOptions) (*string, *gerrit.Response, error) {
-	return a.client.Changes.GetPatch(ctx, changeID, revisionID, opt)
+	path := fmt.Sprintf("changes/%s/revisions/%s/patch", changeID, revisionID)
+	if opt != nil {
+		params := url.Values{}
+		if opt.Zip {
+			params.Set("zip", "true")
+		}
+		if opt.Download {
+			params.Set("download", "true")
+		}
+		if opt.Path != "" {
+			params.Set("path", opt.Path)
+		}
+		if len(params) > 0 {
+			path += "?" + params.Encode()
+		}
+	}
+	var buf bytes.Buffer
+	resp, err := a.Call(ctx, http.MethodGet, path, nil, &buf)
+	if err != nil {
+		return nil, resp, err
+	}
+	patch := buf.String()
+	return &patch, resp, nil
+}
+
+// QueryChanges implements GerritClient interface
+func (a *GerritClientAdapter) QueryChanges(ctx context.Context, opt *gerrit.QueryChangeOptions) (*[]gerrit.ChangeInfo, *gerrit.Response, error) {
+	return withClient(ctx, a, func(c *gerrit.Client) (*[]gerrit.ChangeInfo, *gerrit.Response, error) {
+		return c.Changes.QueryChanges(ctx, opt)
+	})
+}
+
+// SetReview implements GerritClient interface
+func (a *GerritClientAdapter) SetReview(ctx context.Context, changeID, revisionID string, input *gerrit.ReviewInput) (*gerrit.ReviewResult, *gerrit.Response, error) {
+	return withClient(ctx, a, func(c *gerrit.Client) (*gerrit.ReviewResult, *gerrit.Response, error) {
+		return c.Changes.SetReview(ctx, changeID, revisionID, input)
+	})
+}
+
+// Call implements GerritClient interface
+func (a *GerritClientAdapter) Call(ctx context.Context, method, path string, body, v any) (*gerrit.Response, error) {
+	_, resp, err := withClient(ctx, a, func(c *gerrit.Client) (struct{}, *gerrit.Response, error) {
+		req, err := c.NewRequest(ctx, method, path, body)
+		if err != nil {
+			return struct{}{}, nil, err
+		}
+		resp, err := c.Do(req, v)
+		return struct{}{}, resp, err
+	})
+	return resp, err
 }
 
 type Handler struct {
-	client GerritClient
+	client      GerritClient
+	baseURL     *url.URL
+	hostAliases map[string]bool
+	// allowedHosts, if set, are the only other hosts accepted in change URLs
+	allowedHosts []string
+	status       func(ctx context.Context) ConnectionStatus
+	capabilities atomic.Pointer[Capabilities]
+	// config is swapped as a whole when the configuration file is reloaded
+	config     atomic.Pointer[Config]
+	sessions   *SessionStore
+	effortLog  *EffortLog
+	index      *ChangeIndex
+	quota      *QuotaTracker
+	prefetcher Prefetcher
+
+	maxPatchFiles int
+	maxPatchLines int
+	scanSecrets   bool
+	guardContent  bool
+}
+
+// Option configures optional Handler behaviour
+type Option func(*Handler)
+
+// WithBaseURL sets the configured Gerrit base URL, used to recognise change
+// URLs pointing at this server and to build canonical web URLs
+func WithBaseURL(baseURL *url.URL) Option {
+	return func(h *Handler) {
+		h.baseURL = baseURL
+	}
 }
 
-func NewHandler(client GerritClient) *Handler {
+// WithHostAliases registers additional hostnames that refer to the same
+// Gerrit server as the base URL (e.g. review.example.com vs gerrit.example.com)
+func WithHostAliases(hosts ...string) Option {
+	return func(h *Handler) {
+		for _, host := range hosts {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if host != "" {
+				h.hostAliases[host] = true
+			}
+		}
+	}
+}
+
+func NewHandler(client GerritClient, opts ...Option) *Handler {
 	h := Handler{
-		client: client,
+		client:        client,
+		hostAliases:   make(map[string]bool),
+		sessions:      NewSessionStore(),
+		maxPatchFiles: DefaultMaxPatchFiles,
+		maxPatchLines: DefaultMaxPatchLines,
+	}
+	h.config.Store(&Config{})
+	for _, opt := range opts {
+		opt(&h)
 	}
 	return &h
 }
 
+// Sessions returns the store of per-session state, whose OnUnregisterSession
+// hook should be registered with the server
+func (h *Handler) Sessions() *SessionStore {
+	return h.sessions
+}
+
 // extractChangeID extracts the change ID from a Gerrit change URL
 func extractChangeID(url string) (string, error) {
 	// Handle different Gerrit URL formats:
@@ -77,51 +211,280 @@ func extractChangeID(url string) (string, error) {
 	return "", fmt.Errorf("could not extract change ID from URL: %s", url)
 }
 
-// GetGerritChangePatch fetches the patch for the latest patchset for a gerrit change
-func (h *Handler) GetGerritChangePatch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	changeURL, err := request.RequireString("change_url")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+// lookupChange fetches the change a URL points at, including its current
+// revision. The returned header lines identify the change for the reader and
+// warn when the URL points at a different server.
+func (h *Handler) lookupChange(ctx context.Context, changeURL string, fields ...string) (string, *gerrit.ChangeInfo, []string, error) {
+	if err := h.checkHost(changeURL); err != nil {
+		return "", nil, nil, err
 	}
 
 	// Extract change ID from URL
 	changeID, err := extractChangeID(changeURL)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to parse change URL: %v", err)), nil
+		return "", nil, nil, fmt.Errorf("failed to parse change URL: %v", err)
+	}
+
+	var header []string
+	if host := changeHost(changeURL); !h.isKnownHost(host) {
+		header = append(header, fmt.Sprintf("WARNING: host %s is not the configured Gerrit server; change %s was looked up on %s instead", host, changeID, h.baseURL.Host))
 	}
 
 	// Fetch change details with revisions
 	opt := &gerrit.ChangeOptions{
-		AdditionalFields: []string{"CURRENT_REVISION", "CURRENT_COMMIT"},
+		AdditionalFields: append([]string{"CURRENT_REVISION"}, fields...),
 	}
-	change, _, err := h.client.GetChange(ctx, changeID, opt)
+	change, resp, err := h.client.GetChange(ctx, changeID, opt)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to get change %s: %v", changeID, err)), nil
+		return "", nil, nil, fmt.Errorf("failed to get change %s: %v", changeID, err)
+	}
+
+	if webURL := h.canonicalChangeURL(change, resp); webURL != "" {
+		header = append([]string{"Change: " + webURL}, header...)
 	}
 
 	// Get the current revision ID
 	if change.CurrentRevision == "" {
-		return mcp.NewToolResultError("no current revision found for change"), nil
+		return "", nil, nil, fmt.Errorf("no current revision found for change")
 	}
+	return changeID, change, header, nil
+}
 
-	// Get the patch for the current revision
-	patch, _, err := h.client.GetPatch(ctx, changeID, change.CurrentRevision, &gerrit.PatchOptions{})
+// atPatchSet returns a copy of change whose current revision is the given
+// patchset, so that the functions working on the current revision can be
+// applied to an earlier one. The change must have been fetched with
+// ALL_REVISIONS and ALL_FILES.
+func atPatchSet(change *gerrit.ChangeInfo, number int) (*gerrit.ChangeInfo, error) {
+	latest := change.Revisions[change.CurrentRevision].Number
+	for sha, revision := range change.Revisions {
+		if revision.Number != number {
+			continue
+		}
+		view := *change
+		view.CurrentRevision = sha
+		view.Insertions, view.Deletions = 0, 0
+		for path, file := range revision.Files {
+			if !strings.HasPrefix(path, "/") {
+				view.Insertions += file.LinesInserted
+				view.Deletions += file.LinesDeleted
+			}
+		}
+		return &view, nil
+	}
+	return nil, fmt.Errorf("change %d has no patch set %d; its patch sets are 1 to %d", change.Number, number, latest)
+}
+
+// patchBytesPerLine is the size a changed line is assumed to take up in a
+// patch, along with its share of context lines and headers
+const patchBytesPerLine = 100
+
+// estimatePatchSize estimates the size of the patch of the change's current
+// revision from its diffstat, base64 encoded as Gerrit serves it, so that
+// memory can be reserved before the patch is downloaded
+func estimatePatchSize(change *gerrit.ChangeInfo) int64 {
+	return int64(change.Insertions+change.Deletions) * patchBytesPerLine * 4 / 3
+}
+
+// currentPatch fetches the patch of the change's current revision
+func (h *Handler) currentPatch(ctx context.Context, changeID string, change *gerrit.ChangeInfo) (string, error) {
+	// The decoded patch and what is rendered from it take about twice its
+	// decoded size. rawPatch reserves the encoded patch, so only the rest
+	// is reserved here, up front and topped up once the size is known.
+	estimated := estimatePatchSize(change)
+	reserved := 2*estimated*3/4 - estimated
+	if err := reserveMemory(ctx, reserved); err != nil {
+		return "", fmt.Errorf("cannot process the patch of change %s: %v; get-gerrit-change-hunks reviews it file by file with less memory", changeID, err)
+	}
+	patch, err := h.rawPatch(ctx, changeID, change, &gerrit.PatchOptions{})
+	if err != nil {
+		return "", err
+	}
+	reserved += max(estimated, int64(len(patch)))
+	decoded := decodePatch(patch)
+	if extra := 2*int64(len(decoded)) - reserved; extra > 0 {
+		if err := reserveMemory(ctx, extra); err != nil {
+			return "", fmt.Errorf("cannot process the patch of change %s: %v; get-gerrit-change-hunks reviews it file by file with less memory", changeID, err)
+		}
+	}
+	return decoded, nil
+}
+
+// rawPatch fetches the patch of the change's current revision as Gerrit
+// serves it, usually base64 encoded, or zipped with opt.Zip. Memory for it
+// is reserved by its estimated size before it is downloaded, which
+// overestimates a zipped patch.
+func (h *Handler) rawPatch(ctx context.Context, changeID string, change *gerrit.ChangeInfo, opt *gerrit.PatchOptions) (string, error) {
+	reserved := estimatePatchSize(change)
+	if err := reserveMemory(ctx, reserved); err != nil {
+		return "", fmt.Errorf("cannot process the patch of change %s: %v", changeID, err)
+	}
+	patch, _, err := h.client.GetPatch(ctx, changeID, change.CurrentRevision, opt)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to get patch for change %s: %v", changeID, err)), nil
+		return "", fmt.Errorf("failed to get patch for change %s: %v", changeID, err)
 	}
 
 	if patch == nil {
-		return mcp.NewToolResultError("received nil patch content"), nil
+		return "", fmt.Errorf("received nil patch content")
 	}
+	if extra := int64(len(*patch)) - reserved; extra > 0 {
+		if err := reserveMemory(ctx, extra); err != nil {
+			return "", fmt.Errorf("cannot process the patch of change %s: %v", changeID, err)
+		}
+	}
+	return *patch, nil
+}
 
-	p := *patch
+// decodePatch undoes the base64 encoding Gerrit applies to patches. Patches that are already plain text are
+// returned unchanged.
+func decodePatch(patch string) string {
+	trimmed := strings.TrimSpace(patch)
+	if strings.HasPrefix(trimmed, "From ") || strings.HasPrefix(trimmed, "diff ") {
+		return patch
+	}
+	// The decoder skips line breaks by itself, so only other whitespace
+	// needs the copy that removes it
+	decoded, err := base64.StdEncoding.DecodeString(trimmed)
+	if err != nil {
+		decoded, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(trimmed), ""))
+		if err != nil {
+			return patch
+		}
+	}
+	// Patches of Latin-1 and other non-UTF-8 files are still patches
+	if !utf8.Valid(decoded) && !bytes.HasPrefix(decoded, []byte("From ")) && !bytes.HasPrefix(decoded, []byte("diff ")) {
+		return patch
+	}
+	return string(decoded)
+}
 
-	// limit size of patch
-	n := 32000
-	r := []rune(p)
-	if len(r) > n {
-		p = fmt.Sprintf("WARNING: This patch has been truncated as it is very big:\n%s", string(r[:n]))
+// decodePatchReader is decodePatch for ScanPatch: the patch is decoded
+// while it is read instead of as a whole. Only the start of the patch is
+// checked to tell base64 from plain text.
+func decodePatchReader(patch string) io.Reader {
+	trimmed := strings.TrimSpace(patch)
+	if strings.HasPrefix(trimmed, "From ") || strings.HasPrefix(trimmed, "diff ") {
+		return strings.NewReader(patch)
+	}
+	decoder := base64.NewDecoder(base64.StdEncoding, strings.NewReader(trimmed))
+	start := make([]byte, len("From "))
+	if _, err := io.ReadFull(decoder, start); err != nil || !bytes.HasPrefix(start, []byte("From ")) && !bytes.HasPrefix(start, []byte("diff ")) {
+		return strings.NewReader(patch)
+	}
+	return io.MultiReader(bytes.NewReader(start), decoder)
+}
+
+// GetGerritChangePatch fetches the patch for the latest patchset for a gerrit
+// change, or for an earlier patchset if one is given
+func (h *Handler) GetGerritChangePatch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	patchSet := request.GetInt("patchset", 0)
+	if patchSet < 0 {
+		return mcp.NewToolResultError("patchset must be positive"), nil
+	}
+
+	fields := []string{"CURRENT_COMMIT", "CURRENT_FILES"}
+	if patchSet > 0 {
+		fields = []string{"ALL_REVISIONS", "ALL_FILES"}
+	}
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, fields...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if latest := change.Revisions[change.CurrentRevision].Number; patchSet > 0 && patchSet != latest {
+		if change, err = atPatchSet(change, patchSet); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		header = append(header, fmt.Sprintf("Patch set %d of %d, not the current one", patchSet, latest))
+	}
+
+	// A zip archive is a download, not text for the model, so the limits
+	// below don't apply to it
+	format := request.GetString("format", "text")
+	if format == "zip" {
+		return h.zippedPatch(ctx, changeID, change, header)
+	}
+	if format != "text" && format != "base64" {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown format %q, expected text, base64 or zip", format)), nil
+	}
+
+	// Rather than returning a patch truncated beyond use, describe the change
+	// so that it can be reviewed piecemeal
+	if !request.GetBool("force", false) && h.exceedsPatchLimits(change) {
+		return mcp.NewToolResultText(strings.Join(append(header, "", h.diffstat(change)), "\n")), nil
+	}
+
+	norm, err := parseNormalization(request.GetString("normalize", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Get the patch for the current revision
+	p, err := h.currentPatch(ctx, changeID, change)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	p, note := norm.apply(p)
+	if note != "" {
+		header = append(header, note)
+	}
+	header = append(header, h.patchSecretWarnings(p)...)
+
+	if format == "base64" {
+		return patchResource(header, h.patchURI(changeID, change, ""), "text/x-diff", base64.StdEncoding.EncodeToString([]byte(p))), nil
+	}
+
+	// Separate blocks let the client pick the files it passes on to a model
+	if request.GetBool("split_files", false) {
+		preamble, files := splitPatch(p)
+		result := &mcp.CallToolResult{}
+		if first := strings.TrimSpace(strings.Join(append(header, "", preamble), "\n")); first != "" {
+			result.Content = append(result.Content, mcp.NewTextContent(first))
+		}
+		for _, file := range files {
+			result.Content = append(result.Content, mcp.NewTextContent(file))
+		}
+		return result, nil
+	}
+
+	if len(header) > 0 {
+		p = strings.Join(header, "\n") + "\n\n" + p
 	}
 
 	return mcp.NewToolResultText(string(p)), nil
 }
+
+// zippedPatch returns the current revision's patch as the zip archive Gerrit offers for download
+func (h *Handler) zippedPatch(ctx context.Context, changeID string, change *gerrit.ChangeInfo, header []string) (*mcp.CallToolResult, error) {
+	zipped, err := h.rawPatch(ctx, changeID, change, &gerrit.PatchOptions{Zip: true})
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	// The archive is returned base64 encoded
+	if err := reserveMemory(ctx, int64(base64.StdEncoding.EncodedLen(len(zipped)))); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("cannot return the zipped patch of change %s: %v", changeID, err)), nil
+	}
+	return patchResource(header, h.patchURI(changeID, change, "?zip"), "application/zip", base64.StdEncoding.EncodeToString([]byte(zipped))), nil
+}
+
+// patchURI identifies a patch download for embedded resources
+func (h *Handler) patchURI(changeID string, change *gerrit.ChangeInfo, query string) string {
+	base := ""
+	if h.baseURL != nil {
+		base = strings.TrimSuffix(h.baseURL.String(), "/")
+	}
+	return fmt.Sprintf("%s/changes/%s/revisions/%s/patch%s", base, changeID, change.CurrentRevision, query)
+}
+
+// patchResource returns base64 encoded patch data as an embedded resource,
+// preceded by the header lines if there are any
+func patchResource(header []string, uri, mimeType, blob string) *mcp.CallToolResult {
+	result := &mcp.CallToolResult{}
+	if len(header) > 0 {
+		result.Content = append(result.Content, mcp.NewTextContent(strings.Join(header, "\n")))
+	}
+	result.Content = append(result.Content, mcp.NewEmbeddedResource(mcp.BlobResourceContents{URI: uri, MIMEType: mimeType, Blob: blob}))
+	return result
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// GetGerritChangeHunks gives access to a change's patch one piece at a time:
+// without a file it lists the hunks of every file, with a file it returns
+// that file's diff, and with a file and hunk index just that hunk
+func (h *Handler) GetGerritChangeHunks(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	path := request.GetString("file", "")
+	index := request.GetInt("hunk", -1)
+	if index >= 0 && path == "" {
+		return mcp.NewToolResultError("hunk requires file"), nil
+	}
+
+	norm, err := parseNormalization(request.GetString("normalize", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	patch, err := h.rawPatch(ctx, changeID, change, &gerrit.PatchOptions{})
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// The patch is decoded, normalized and parsed one file at a time, and
+	// only the files that are returned are kept
+	var body strings.Builder
+	var file *FileDiff
+	var secrets []SecretFinding
+	normalized := norm.reader(decodePatchReader(patch))
+	err = ScanPatch(normalized, func(f FileDiff) error {
+		if h.scanSecrets {
+			secrets = append(secrets, ScanSecrets([]FileDiff{f})...)
+		}
+		switch {
+		case path == "":
+			fmt.Fprintf(&body, "%s\n", f.Path())
+			if len(f.Hunks) == 0 {
+				body.WriteString("  (no hunks, e.g. a binary file or a mode change)\n")
+			}
+			for _, hunk := range f.Hunks {
+				added, removed := hunk.Stats()
+				fmt.Fprintf(&body, "  hunk %d: %s (+%d -%d)\n", hunk.Index, hunk.Header, added, removed)
+			}
+		case file == nil && (f.NewPath == path || f.OldPath == path):
+			file = &f
+			if !h.scanSecrets {
+				return errStopScan
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read patch for change %s: %v", changeID, err)), nil
+	}
+	if note := normalized.counts.note(); note != "" {
+		header = append(header, note)
+	}
+	header = append(header, secretWarnings(secrets)...)
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+
+	if path == "" {
+		b.WriteString(body.String())
+		return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+	}
+
+	if file == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("file %s is not part of the patch", path)), nil
+	}
+
+	b.WriteString(strings.Join(file.Header, "\n") + "\n")
+	if index < 0 {
+		for _, hunk := range file.Hunks {
+			b.WriteString(hunk.String() + "\n")
+		}
+		return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+	}
+
+	if index >= len(file.Hunks) {
+		return mcp.NewToolResultError(fmt.Sprintf("file %s has %d hunks, hunk %d does not exist", path, len(file.Hunks), index)), nil
+	}
+	b.WriteString(file.Hunks[index].String())
+	return mcp.NewToolResultText(b.String()), nil
+}
@@ -0,0 +1,307 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffInfo is Gerrit's DiffInfo, limited to what is needed to render it
+type diffInfo struct {
+	ChangeType string        `json:"change_type"`
+	Binary     bool          `json:"binary"`
+	Content    []diffContent `json:"content"`
+}
+
+// diffContent is one section of a DiffInfo: lines only on side A, only on
+// side B, on both, or a number of common lines left out. DueToRebase marks
+// edits that came with a rebase between the compared patchsets.
+type diffContent struct {
+	A           []string `json:"a"`
+	B           []string `json:"b"`
+	AB          []string `json:"ab"`
+	Skip        int      `json:"skip"`
+	DueToRebase bool     `json:"due_to_rebase"`
+}
+
+// diffLine is a line of a unified diff: ' ', '-' or '+' followed by text, or
+// a number of unchanged lines left out
+type diffLine struct {
+	op   byte
+	text string
+	skip int
+}
+
+// unifiedDiff renders the content of a DiffInfo as unified diff hunks with
+// context lines of context around each change
+func unifiedDiff(d diffInfo, context int) string {
+	var lines []diffLine
+	for _, c := range d.Content {
+		if c.Skip > 0 {
+			lines = append(lines, diffLine{skip: c.Skip})
+		}
+		for _, text := range c.AB {
+			lines = append(lines, diffLine{op: ' ', text: text})
+		}
+		for _, text := range c.A {
+			lines = append(lines, diffLine{op: '-', text: text})
+		}
+		for _, text := range c.B {
+			lines = append(lines, diffLine{op: '+', text: text})
+		}
+	}
+
+	var b strings.Builder
+	var hunk []string
+	var startA, startB, countA, countB int
+	a, bn := 1, 1
+	open := func() {
+		if hunk == nil {
+			hunk = []string{}
+			startA, startB, countA, countB = a, bn, 0, 0
+		}
+	}
+	add := func(l diffLine) {
+		hunk = append(hunk, string(l.op)+l.text)
+		if l.op != '+' {
+			a++
+			countA++
+		}
+		if l.op != '-' {
+			bn++
+			countB++
+		}
+	}
+	flush := func() {
+		if hunk == nil {
+			return
+		}
+		// An empty side starts at the line before, as in diff -u
+		if countA == 0 {
+			startA--
+		}
+		if countB == 0 {
+			startB--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", startA, countA, startB, countB)
+		b.WriteString(strings.Join(hunk, "\n") + "\n")
+		hunk = nil
+	}
+
+	for i := 0; i < len(lines); {
+		l := lines[i]
+		switch {
+		case l.skip > 0:
+			flush()
+			a += l.skip
+			bn += l.skip
+			i++
+		case l.op != ' ':
+			open()
+			add(l)
+			i++
+		default:
+			j := i
+			for j < len(lines) && lines[j].op == ' ' && lines[j].skip == 0 {
+				j++
+			}
+			run := lines[i:j]
+			nextChange := j < len(lines) && lines[j].skip == 0
+			if hunk != nil && nextChange && len(run) <= 2*context {
+				for _, c := range run {
+					add(c)
+				}
+				i = j
+				continue
+			}
+
+			lead := 0
+			if hunk != nil {
+				lead = min(context, len(run))
+				for _, c := range run[:lead] {
+					add(c)
+				}
+				flush()
+			}
+			trail := 0
+			if nextChange {
+				trail = min(context, len(run)-lead)
+			}
+			a += len(run) - lead - trail
+			bn += len(run) - lead - trail
+			if trail > 0 {
+				open()
+				for _, c := range run[len(run)-trail:] {
+					add(c)
+				}
+			}
+			i = j
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// lastReviewedPatchSet returns the latest patch set the account voted or
+// commented on, or 0 if it never did. Uploading a patch set isn't a review.
+func lastReviewedPatchSet(change *gerrit.ChangeInfo, accountID int) (int, gerrit.ChangeMessageInfo) {
+	var last gerrit.ChangeMessageInfo
+	for _, m := range change.Messages {
+		if m.Author.AccountID != accountID || m.RevisionNumber < last.RevisionNumber {
+			continue
+		}
+		if uploadRegexp.MatchString(m.Message) || strings.HasPrefix(m.Tag, "autogenerated:gerrit:newPatchSet") {
+			continue
+		}
+		last = m
+	}
+	return last.RevisionNumber, last
+}
+
+// patchSetKinds describes the patch sets after base, and reports whether any
+// of them changed the code rather than only rebasing it. Patch sets of
+// unknown kind count as changing the code.
+func patchSetKinds(change *gerrit.ChangeInfo, base int) ([]string, bool) {
+	byNumber := make(map[int]gerrit.RevisionInfo)
+	for _, revision := range change.Revisions {
+		byNumber[revision.Number] = revision
+	}
+	current := change.Revisions[change.CurrentRevision].Number
+
+	var kinds []string
+	codeChanged := false
+	for n := base + 1; n <= current; n++ {
+		kind := byNumber[n].Kind
+		if !sameCode(kind) {
+			codeChanged = true
+		}
+		if description, ok := revisionKinds[kind]; ok {
+			kinds = append(kinds, fmt.Sprintf("patch set %d: %s", n, description))
+		}
+	}
+	return kinds, codeChanged
+}
+
+// GetGerritChangeDiffSinceReview returns the diff between the last patch set
+// the calling user reviewed and the current one
+func (h *Handler) GetGerritChangeDiffSinceReview(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "MESSAGES", "ALL_REVISIONS")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	current := change.Revisions[change.CurrentRevision].Number
+
+	var self gerrit.AccountInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "accounts/self", nil, &self); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to look up the current account: %v", err)), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+
+	base, message := lastReviewedPatchSet(change, self.AccountID)
+	switch {
+	case base == 0:
+		b.WriteString(fmt.Sprintf("%s has not reviewed this change yet; use get-gerrit-change for the full diff of patch set %d", formatAccount(self), current))
+		return mcp.NewToolResultText(b.String()), nil
+	case base >= current:
+		b.WriteString(fmt.Sprintf("Nothing new: %s last reviewed patch set %d on %s, which is the current one", formatAccount(self), base, message.Date.UTC().Format("2006-01-02 15:04")))
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	reviewed := fmt.Sprintf("patch set %d, last reviewed by %s on %s,", base, formatAccount(self), message.Date.UTC().Format("2006-01-02 15:04"))
+
+	// The kind of each newer patch set tells whether its code changed at all
+	kinds, codeChanged := patchSetKinds(change, base)
+	if !codeChanged {
+		fmt.Fprintf(&b, "Nothing to re-read: since %s the code was only rebased\n", reviewed)
+		for _, kind := range kinds {
+			fmt.Fprintf(&b, "  %s\n", kind)
+		}
+		return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+	}
+
+	revisionPath := fmt.Sprintf("changes/%s/revisions/%s/files/", url.PathEscape(changeID), change.CurrentRevision)
+	var files, currentFiles map[string]gerrit.FileInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("%s?base=%d", revisionPath, base), nil, &files); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list files changed since patch set %d: %v", base, err)), nil
+	}
+	if _, err := h.client.Call(ctx, http.MethodGet, revisionPath, nil, &currentFiles); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list the files of patch set %d: %v", current, err)), nil
+	}
+	for _, magic := range []string{"/COMMIT_MSG", "/MERGE_LIST"} {
+		delete(files, magic)
+		delete(currentFiles, magic)
+	}
+
+	// Files of the change the base patch set already had as they are
+	var unchanged []string
+	for _, path := range sortedKeys(currentFiles) {
+		if _, ok := files[path]; !ok {
+			unchanged = append(unchanged, path)
+		}
+	}
+
+	var diffs strings.Builder
+	var rebasedOnly []string
+	for _, path := range sortedKeys(files) {
+		var diff diffInfo
+		// File paths are a single path segment in the REST API
+		diffPath := fmt.Sprintf("%s%s/diff?base=%d&context=%d", revisionPath, strings.ReplaceAll(url.PathEscape(path), "/", "%2F"), base, diffContext)
+		if _, err := h.client.Call(ctx, http.MethodGet, diffPath, nil, &diff); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get the diff of %s since patch set %d: %v", path, base, err)), nil
+		}
+		if added, removed := ownEdits(diff); added == 0 && removed == 0 && !diff.Binary && diff.ChangeType == "MODIFIED" {
+			rebasedOnly = append(rebasedOnly, path)
+			continue
+		}
+
+		oldPath := path
+		if files[path].OldPath != "" {
+			oldPath = files[path].OldPath
+		}
+		fmt.Fprintf(&diffs, "\ndiff --git a/%s b/%s\n", oldPath, path)
+		if diff.Binary || files[path].Binary {
+			diffs.WriteString("Binary files differ\n")
+			continue
+		}
+		from, to := "a/"+oldPath, "b/"+path
+		switch diff.ChangeType {
+		case "ADDED":
+			from = "/dev/null"
+		case "DELETED":
+			to = "/dev/null"
+		}
+		fmt.Fprintf(&diffs, "--- %s\n+++ %s\n", from, to)
+		diffs.WriteString(unifiedDiff(diff, diffContext))
+	}
+
+	fmt.Fprintf(&b, "Changes from %s to patch set %d: %s\n", reviewed, current, plural(len(files)-len(rebasedOnly), "file"))
+	for _, kind := range kinds {
+		fmt.Fprintf(&b, "  %s\n", kind)
+	}
+	if len(unchanged) > 0 {
+		fmt.Fprintf(&b, "Unchanged since patch set %d, no need to re-read: %s\n", base, strings.Join(unchanged, ", "))
+	}
+	if len(rebasedOnly) > 0 {
+		fmt.Fprintf(&b, "Only changed by a rebase: %s\n", strings.Join(rebasedOnly, ", "))
+	}
+	b.WriteString(diffs.String())
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxSnippetLength bounds the matching text shown for a search result
+const maxSnippetLength = 160
+
+var commentsPathRegexp = regexp.MustCompile(`^changes/([^/]+)/comments$`)
+
+// ChangeIndex is a GerritClient that remembers the metadata, messages and
+// comments of every change fetched through it, in a JSON file, so that
+// changes seen before can be searched offline
+type ChangeIndex struct {
+	next GerritClient
+	path string
+
+	mu      sync.Mutex
+	changes map[string]*indexedChange
+}
+
+// indexedChange is what the index knows about a change
+type indexedChange struct {
+	Number        int       `json:"number"`
+	Project       string    `json:"project"`
+	Branch        string    `json:"branch"`
+	Status        string    `json:"status"`
+	Subject       string    `json:"subject"`
+	Owner         string    `json:"owner,omitempty"`
+	Updated       time.Time `json:"updated"`
+	CommitMessage string    `json:"commit_message,omitempty"`
+	Messages      []string  `json:"messages,omitempty"`
+	// Comments maps comment IDs to "path: message"
+	Comments map[string]string `json:"comments,omitempty"`
+}
+
+// NewChangeIndex wraps next with an index stored at path, loading what an
+// earlier run stored there
+func NewChangeIndex(next GerritClient, path string) (*ChangeIndex, error) {
+	idx := &ChangeIndex{next: next, path: path, changes: make(map[string]*indexedChange)}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &idx.changes); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+	return idx, nil
+}
+
+// WithChangeIndex enables the tool searching the changes idx has seen
+func WithChangeIndex(idx *ChangeIndex) Option {
+	return func(h *Handler) {
+		h.index = idx
+	}
+}
+
+// GetChange implements GerritClient interface
+func (idx *ChangeIndex) GetChange(ctx context.Context, changeID string, opt *gerrit.ChangeOptions) (*gerrit.ChangeInfo, *gerrit.Response, error) {
+	change, resp, err := idx.next.GetChange(ctx, changeID, opt)
+	if err == nil && change != nil {
+		idx.update(func() { idx.addChange(*change) })
+	}
+	return change, resp, err
+}
+
+// GetPatch implements GerritClient interface
+func (idx *ChangeIndex) GetPatch(ctx context.Context, changeID, revisionID string, opt *gerrit.PatchOptions) (*string, *gerrit.Response, error) {
+	return idx.next.GetPatch(ctx, changeID, revisionID, opt)
+}
+
+// QueryChanges implements GerritClient interface
+func (idx *ChangeIndex) QueryChanges(ctx context.Context, opt *gerrit.QueryChangeOptions) (*[]gerrit.ChangeInfo, *gerrit.Response, error) {
+	changes, resp, err := idx.next.QueryChanges(ctx, opt)
+	if err == nil && changes != nil && len(*changes) > 0 {
+		idx.update(func() {
+			for _, change := range *changes {
+				idx.addChange(change)
+			}
+		})
+	}
+	return changes, resp, err
+}
+
+// SetReview implements GerritClient interface
+func (idx *ChangeIndex) SetReview(ctx context.Context, changeID, revisionID string, input *gerrit.ReviewInput) (*gerrit.ReviewResult, *gerrit.Response, error) {
+	return idx.next.SetReview(ctx, changeID, revisionID, input)
+}
+
+// Call implements GerritClient interface; the comments of known changes are indexed
+func (idx *ChangeIndex) Call(ctx context.Context, method, path string, body, v any) (*gerrit.Response, error) {
+	resp, err := idx.next.Call(ctx, method, path, body, v)
+	if err != nil || method != http.MethodGet {
+		return resp, err
+	}
+	m := commentsPathRegexp.FindStringSubmatch(path)
+	comments, ok := v.(*map[string][]commentInfo)
+	if m == nil || !ok || *comments == nil {
+		return resp, err
+	}
+	idx.update(func() {
+		entry, known := idx.changes[m[1]]
+		if !known {
+			return
+		}
+		if entry.Comments == nil {
+			entry.Comments = make(map[string]string)
+		}
+		for path, list := range *comments {
+			for _, c := range list {
+				entry.Comments[c.ID] = path + ": " + c.Message
+			}
+		}
+	})
+	return resp, err
+}
+
+// update applies fn to the index under its lock and saves the result
+func (idx *ChangeIndex) update(fn func()) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	fn()
+	if err := idx.save(); err != nil {
+		// The index is a convenience; failing to save it must not fail the call
+		log.Printf("Warning: failed to save the change index: %v", err)
+	}
+}
+
+// save writes the index to its file atomically; callers hold idx.mu
+func (idx *ChangeIndex) save() error {
+	data, err := json.Marshal(idx.changes)
+	if err != nil {
+		return err
+	}
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+// addChange records a change, keeping what was learned from earlier
+// fetches that asked for more fields; callers hold idx.mu
+func (idx *ChangeIndex) addChange(change gerrit.ChangeInfo) {
+	if change.Number == 0 {
+		return
+	}
+	key := fmt.Sprint(change.Number)
+	entry, ok := idx.changes[key]
+	if !ok {
+		entry = &indexedChange{Number: change.Number}
+		idx.changes[key] = entry
+	}
+	entry.Project, entry.Branch, entry.Status, entry.Subject = change.Project, change.Branch, change.Status, change.Subject
+	entry.Updated = change.Updated.Time
+	if change.Owner.AccountID != 0 || change.Owner.Name != "" {
+		entry.Owner = formatAccount(change.Owner)
+	}
+	if message := change.Revisions[change.CurrentRevision].Commit.Message; message != "" {
+		entry.CommitMessage = message
+	}
+	if len(change.Messages) > 0 {
+		entry.Messages = entry.Messages[:0]
+		for _, m := range change.Messages {
+			entry.Messages = append(entry.Messages, m.Message)
+		}
+	}
+}
+
+// indexMatch is a change matching a search, with the text that matched
+type indexMatch struct {
+	change  indexedChange
+	source  string
+	snippet string
+}
+
+// search returns the changes containing every term, most recently updated first
+func (idx *ChangeIndex) search(terms []string, project string) []indexMatch {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var matches []indexMatch
+	for _, entry := range idx.changes {
+		if project != "" && entry.Project != project {
+			continue
+		}
+		texts := [][2]string{{"subject", entry.Subject}, {"commit message", entry.CommitMessage}}
+		for _, m := range entry.Messages {
+			texts = append(texts, [2]string{"message", m})
+		}
+		for _, id := range sortedKeys(entry.Comments) {
+			texts = append(texts, [2]string{"comment", entry.Comments[id]})
+		}
+
+		var all strings.Builder
+		for _, t := range texts {
+			all.WriteString(strings.ToLower(t[1]) + "\n")
+		}
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(all.String(), term) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		// Show where the first term appears
+		match := indexMatch{change: *entry}
+		for _, t := range texts {
+			for _, line := range strings.Split(t[1], "\n") {
+				if match.snippet == "" && strings.Contains(strings.ToLower(line), terms[0]) {
+					match.source, match.snippet = t[0], strings.TrimSpace(line)
+				}
+			}
+		}
+		if r := []rune(match.snippet); len(r) > maxSnippetLength {
+			match.snippet = string(r[:maxSnippetLength]) + "..."
+		}
+		matches = append(matches, match)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].change.Updated.Equal(matches[j].change.Updated) {
+			return matches[i].change.Updated.After(matches[j].change.Updated)
+		}
+		return matches[i].change.Number > matches[j].change.Number
+	})
+	return matches
+}
+
+// SearchGerritIndex searches the subjects, commit messages, messages and
+// comments of the changes fetched before, without contacting Gerrit
+func (h *Handler) SearchGerritIndex(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return mcp.NewToolResultError("query must contain at least one word"), nil
+	}
+	limit := request.GetInt("limit", 20)
+	if limit <= 0 {
+		return mcp.NewToolResultError("limit must be positive"), nil
+	}
+
+	matches := h.index.search(terms, request.GetString("project", ""))
+	if len(matches) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No change seen before matches %q", query)), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s seen before match %q", plural(len(matches), "change"), query)
+	if len(matches) > limit {
+		fmt.Fprintf(&b, ", showing the %d most recently updated", limit)
+		matches = matches[:limit]
+	}
+	b.WriteString(":\n")
+	for _, m := range matches {
+		c := m.change
+		fmt.Fprintf(&b, "\n%d %s [%s] %s: %s (updated %s)\n", c.Number, c.Project, c.Branch, c.Status, c.Subject, c.Updated.UTC().Format("2006-01-02"))
+		fmt.Fprintf(&b, "  %s: %s\n", m.source, m.snippet)
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
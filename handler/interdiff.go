@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// trivialRebaseWithMessageUpdate is the revision kind of a trivial rebase
+// that also changed the commit message, which go-gerrit doesn't name
+const trivialRebaseWithMessageUpdate gerrit.RevisionKind = "TRIVIAL_REBASE_WITH_MESSAGE_UPDATE"
+
+// revisionKinds describes how a patchset relates to the previous one
+var revisionKinds = map[gerrit.RevisionKind]string{
+	gerrit.Rework:                  "rework",
+	gerrit.TrivialRebase:           "trivial rebase",
+	trivialRebaseWithMessageUpdate: "trivial rebase with a new commit message",
+	gerrit.MergeFirstParentUpdate:  "merge with an updated first parent",
+	gerrit.NoCodeChange:            "commit message only",
+	gerrit.NoChange:                "no change",
+}
+
+// sameCode reports whether a patchset of the kind has the code of the
+// previous one, apart from what a rebase brought in
+func sameCode(kind gerrit.RevisionKind) bool {
+	return kind != "" && kind != gerrit.Rework
+}
+
+// ownEdits counts the lines a diff adds and removes, leaving out the edits
+// Gerrit attributes to a rebase between the compared patchsets
+func ownEdits(diff diffInfo) (added, removed int) {
+	for _, c := range diff.Content {
+		if !c.DueToRebase {
+			added += len(c.B)
+			removed += len(c.A)
+		}
+	}
+	return added, removed
+}
+
+// topicInterdiff is the interdiff of one change of a topic between its two
+// latest patchsets
+type topicInterdiff struct {
+	change   gerrit.ChangeInfo
+	previous int
+	current  int
+	kind     gerrit.RevisionKind
+	// files holds the reworked files with their added and removed lines,
+	// leaving out edits Gerrit attributes to the rebase
+	files       []string
+	rebasedOnly int
+	diff        string
+	err         error
+}
+
+// latestPatchSets returns the revisions of the two latest patchsets of a
+// change, the previous one empty if there is only one
+func latestPatchSets(change gerrit.ChangeInfo) (previous, current string) {
+	currentNumber := change.Revisions[change.CurrentRevision].Number
+	for sha, revision := range change.Revisions {
+		if revision.Number == currentNumber-1 {
+			previous = sha
+		}
+	}
+	return previous, change.CurrentRevision
+}
+
+// interdiff lists the files a reworked change touched between two patchsets,
+// and renders their diffs if withDiff is set
+func (h *Handler) interdiff(ctx context.Context, d *topicInterdiff, withDiff bool) error {
+	revisionPath := fmt.Sprintf("changes/%d/revisions/%s/files/", d.change.Number, d.change.CurrentRevision)
+	var files map[string]gerrit.FileInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("%s?base=%d", revisionPath, d.previous), nil, &files); err != nil {
+		return fmt.Errorf("failed to list the files changed since patchset %d: %v", d.previous, err)
+	}
+	delete(files, "/COMMIT_MSG")
+	delete(files, "/MERGE_LIST")
+
+	var b strings.Builder
+	for _, path := range sortedKeys(files) {
+		if err := h.throttle(ctx); err != nil {
+			return err
+		}
+		var diff diffInfo
+		diffPath := fmt.Sprintf("%s%s/diff?base=%d&context=%d", revisionPath, strings.ReplaceAll(url.PathEscape(path), "/", "%2F"), d.previous, diffContext)
+		if _, err := h.client.Call(ctx, http.MethodGet, diffPath, nil, &diff); err != nil {
+			return fmt.Errorf("failed to get the diff of %s since patchset %d: %v", path, d.previous, err)
+		}
+
+		added, removed := ownEdits(diff)
+		if added == 0 && removed == 0 && !diff.Binary {
+			d.rebasedOnly++
+			continue
+		}
+		d.files = append(d.files, fmt.Sprintf("%s +%d -%d", path, added, removed))
+
+		if withDiff {
+			fmt.Fprintf(&b, "diff --git a/%s b/%s\n", path, path)
+			if diff.Binary {
+				b.WriteString("Binary files differ\n")
+				continue
+			}
+			fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+			b.WriteString(unifiedDiff(diff, diffContext))
+		}
+	}
+	d.diff = b.String()
+	return nil
+}
+
+// GetGerritTopicInterdiff compares the two latest patchsets of every change
+// of a topic and reports which changes were reworked rather than only
+// rebased, so that after a topic-wide rebase reviewers know what to look at
+func (h *Handler) GetGerritTopicInterdiff(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	topic := strings.TrimSpace(request.GetString("topic", ""))
+	changeURL := request.GetString("change_url", "")
+	withDiff := request.GetBool("diff", false)
+	limit := request.GetInt("limit", 50)
+
+	var header []string
+	if topic == "" {
+		if changeURL == "" {
+			return mcp.NewToolResultError("either topic or change_url is required"), nil
+		}
+		changeID, change, changeHeader, err := h.lookupChange(ctx, changeURL)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if change.Topic == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("change %s has no topic", changeID)), nil
+		}
+		topic, header = change.Topic, changeHeader
+	}
+
+	changes, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{"topic:" + strconv.Quote(topic)}, Limit: limit},
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: []string{"ALL_REVISIONS"}},
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to query the changes of topic %s: %v", topic, err)), nil
+	}
+	if len(*changes) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("no changes found in topic %s", topic)), nil
+	}
+
+	var reworked, rebased, message, unchanged, single []*topicInterdiff
+	for _, change := range *changes {
+		d := &topicInterdiff{change: change}
+		previous, current := latestPatchSets(change)
+		d.current = change.Revisions[current].Number
+		if previous == "" {
+			single = append(single, d)
+			continue
+		}
+		d.previous = change.Revisions[previous].Number
+		d.kind = change.Revisions[current].Kind
+
+		switch d.kind {
+		case gerrit.TrivialRebase, gerrit.MergeFirstParentUpdate, trivialRebaseWithMessageUpdate:
+			rebased = append(rebased, d)
+		case gerrit.NoCodeChange:
+			message = append(message, d)
+		case gerrit.NoChange:
+			unchanged = append(unchanged, d)
+		default:
+			d.err = h.interdiff(ctx, d, withDiff)
+			reworked = append(reworked, d)
+		}
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Topic %s: %s, %d reworked since the previous patchset\n", topic, plural(len(*changes), "change"), len(reworked))
+
+	line := func(d *topicInterdiff) string {
+		s := fmt.Sprintf("%d %s: %s", d.change.Number, d.change.Project, d.change.Subject)
+		if d.previous > 0 {
+			s += fmt.Sprintf(" (patchset %d -> %d)", d.previous, d.current)
+		}
+		return s
+	}
+	if len(reworked) > 0 {
+		b.WriteString("\nReworked, review the interdiff:\n")
+		for _, d := range reworked {
+			fmt.Fprintf(&b, "  %s\n", line(d))
+			if d.err != nil {
+				fmt.Fprintf(&b, "    interdiff FAILED: %v\n", d.err)
+				continue
+			}
+			for _, file := range d.files {
+				fmt.Fprintf(&b, "    %s\n", file)
+			}
+			if d.rebasedOnly > 0 {
+				fmt.Fprintf(&b, "    %s only changed by the rebase\n", plural(d.rebasedOnly, "file"))
+			}
+			if len(d.files) == 0 && d.rebasedOnly == 0 {
+				b.WriteString("    no file differs\n")
+			}
+			if d.diff != "" {
+				b.WriteString("\n" + d.diff + "\n")
+			}
+		}
+	}
+	for _, section := range []struct {
+		title   string
+		changes []*topicInterdiff
+	}{
+		{"Trivial rebase, nothing to review again", rebased},
+		{"Only the commit message changed", message},
+		{"Same code and parent as the previous patchset", unchanged},
+		{"Single patchset, review it in full", single},
+	} {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		for _, d := range section.changes {
+			fmt.Fprintf(&b, "  %s\n", line(d))
+		}
+	}
+	if (*changes)[len(*changes)-1].MoreChanges {
+		b.WriteString("\nWARNING: the topic has more changes; raise limit to see them\n")
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// IssueTracker recognises references to issues of one tracker
+type IssueTracker struct {
+	Name string `json:"name"`
+	// Pattern is a regular expression matching a reference; its first capture
+	// group, or else the whole match, holds the issue ID. Several IDs in one
+	// match may be separated by commas.
+	Pattern string `json:"pattern"`
+	// URL is the web URL of an issue, with {id} standing for the issue ID
+	URL string `json:"url"`
+
+	re *regexp.Regexp
+}
+
+// defaultIssueTrackers recognise the usual commit message trailers when no trackers are configured
+var defaultIssueTrackers = []IssueTracker{
+	{Name: "bug", Pattern: `(?mi)^(?:Bug|Issue|Fixes|Closes):[ \t]*(.+)$`},
+}
+
+func init() {
+	for i := range defaultIssueTrackers {
+		if err := defaultIssueTrackers[i].compile(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// compile prepares the tracker's pattern
+func (t *IssueTracker) compile() error {
+	if t.Name == "" {
+		return fmt.Errorf("issue tracker without a name")
+	}
+	re, err := regexp.Compile(t.Pattern)
+	if err != nil {
+		return fmt.Errorf("issue tracker %s: %w", t.Name, err)
+	}
+	t.re = re
+	return nil
+}
+
+// issueRef is an issue referenced by a change
+type issueRef struct {
+	tracker string
+	id      string
+	url     string
+	sources []string
+}
+
+// extractIssues finds the issues referenced in text, adding them to refs in
+// order of first appearance
+func extractIssues(trackers []IssueTracker, text, source string, refs []*issueRef) []*issueRef {
+	for _, t := range trackers {
+		for _, m := range t.re.FindAllStringSubmatch(text, -1) {
+			value := m[0]
+			if len(m) > 1 {
+				value = m[1]
+			}
+			for _, id := range strings.Split(value, ",") {
+				id = strings.TrimPrefix(strings.TrimSpace(id), "#")
+				if id == "" {
+					continue
+				}
+				refs = addIssue(refs, t, id, source)
+			}
+		}
+	}
+	return refs
+}
+
+// addIssue records a reference, merging it with an earlier one to the same issue
+func addIssue(refs []*issueRef, t IssueTracker, id, source string) []*issueRef {
+	for _, ref := range refs {
+		if ref.tracker == t.Name && ref.id == id {
+			for _, s := range ref.sources {
+				if s == source {
+					return refs
+				}
+			}
+			ref.sources = append(ref.sources, source)
+			return refs
+		}
+	}
+	ref := &issueRef{tracker: t.Name, id: id, sources: []string{source}}
+	if t.URL != "" {
+		ref.url = strings.ReplaceAll(t.URL, "{id}", id)
+	}
+	return append(refs, ref)
+}
+
+// GetGerritChangeIssues lists the issues referenced by a change's commit
+// message and review messages, with links to the configured trackers
+func (h *Handler) GetGerritChangeIssues(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "CURRENT_COMMIT", "MESSAGES")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	trackers := h.cfg().IssueTrackers
+	if len(trackers) == 0 {
+		trackers = defaultIssueTrackers
+	}
+
+	refs := extractIssues(trackers, change.Revisions[change.CurrentRevision].Commit.Message, "commit message", nil)
+	for _, m := range change.Messages {
+		refs = extractIssues(trackers, m.Message, "review messages", refs)
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	if len(refs) == 0 {
+		fmt.Fprintf(&b, "Change %s references no issues", changeID)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	fmt.Fprintf(&b, "Issues referenced by change %s:\n", changeID)
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "%s %s", ref.tracker, ref.id)
+		if ref.url != "" {
+			fmt.Fprintf(&b, " %s", ref.url)
+		}
+		fmt.Fprintf(&b, " (%s)\n", strings.Join(ref.sources, ", "))
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
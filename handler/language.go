@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"path"
+	"strings"
+)
+
+// languagesByName maps well-known file names to their language
+var languagesByName = map[string]string{
+	"Makefile":       "Makefile",
+	"GNUmakefile":    "Makefile",
+	"Dockerfile":     "Dockerfile",
+	"BUILD":          "Starlark",
+	"BUILD.bazel":    "Starlark",
+	"WORKSPACE":      "Starlark",
+	"CMakeLists.txt": "CMake",
+	"go.mod":         "Go module",
+	"go.sum":         "Go module",
+	"Jenkinsfile":    "Groovy",
+	"OWNERS":         "OWNERS",
+	"/COMMIT_MSG":    "Commit message",
+}
+
+// languagesByExt maps file extensions to their language
+var languagesByExt = map[string]string{
+	".go":     "Go",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hh":     "C++",
+	".hpp":    "C++",
+	".cs":     "C#",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".kts":    "Kotlin",
+	".scala":  "Scala",
+	".groovy": "Groovy",
+	".gradle": "Gradle",
+	".py":     "Python",
+	".rb":     "Ruby",
+	".rs":     "Rust",
+	".swift":  "Swift",
+	".m":      "Objective-C",
+	".mm":     "Objective-C++",
+	".js":     "JavaScript",
+	".mjs":    "JavaScript",
+	".cjs":    "JavaScript",
+	".jsx":    "JavaScript",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".php":    "PHP",
+	".pl":     "Perl",
+	".lua":    "Lua",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".zsh":    "Shell",
+	".ps1":    "PowerShell",
+	".sql":    "SQL",
+	".proto":  "Protocol Buffers",
+	".bzl":    "Starlark",
+	".html":   "HTML",
+	".htm":    "HTML",
+	".css":    "CSS",
+	".scss":   "SCSS",
+	".json":   "JSON",
+	".yaml":   "YAML",
+	".yml":    "YAML",
+	".toml":   "TOML",
+	".xml":    "XML",
+	".md":     "Markdown",
+	".rst":    "reStructuredText",
+	".txt":    "Text",
+	".tf":     "Terraform",
+}
+
+// DetectLanguage guesses the language of a file from its name, returning "" if unknown
+func DetectLanguage(filePath string) string {
+	if lang, ok := languagesByName[filePath]; ok {
+		return lang
+	}
+	base := path.Base(filePath)
+	if lang, ok := languagesByName[base]; ok {
+		return lang
+	}
+	if strings.HasPrefix(base, "Dockerfile.") {
+		return "Dockerfile"
+	}
+	return languagesByExt[strings.ToLower(path.Ext(base))]
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// CallLimiter protects a shared server from bursts of calls against huge
+// changes. It bounds how many tool calls run at once and how much memory
+// their patches may take, together and per call. Calls over a limit wait for
+// a while and then fail with an error saying why, rather than the server
+// running out of memory.
+type CallLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+	// memory and callMemory are in bytes; zero disables the limit
+	memory     int64
+	callMemory int64
+
+	mu   sync.Mutex
+	used int64
+	// released is closed and replaced whenever memory is released, waking the waiters
+	released chan struct{}
+}
+
+// NewCallLimiter creates a limiter running at most maxCalls calls at once,
+// with memory bytes for all calls and callMemory bytes for each. Zero
+// disables a limit. Calls wait at most queueTimeout for a slot or memory.
+func NewCallLimiter(maxCalls int, queueTimeout time.Duration, memory, callMemory int64) *CallLimiter {
+	l := &CallLimiter{queueTimeout: queueTimeout, memory: memory, callMemory: callMemory, released: make(chan struct{})}
+	if maxCalls > 0 {
+		l.slots = make(chan struct{}, maxCalls)
+	}
+	return l
+}
+
+type callBudgetKey struct{}
+
+// callBudget is the memory reserved by one tool call
+type callBudget struct {
+	limiter  *CallLimiter
+	reserved int64
+}
+
+// Middleware queues calls while all slots are taken and releases the call's
+// memory when it returns
+func (l *CallLimiter) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if l.slots != nil {
+			wait := l.wait(ctx)
+			select {
+			case l.slots <- struct{}{}:
+			case <-wait.Done():
+				wait.cancel()
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return mcp.NewToolResultError(fmt.Sprintf("server busy: %d tool calls are running and %s waited %s for its turn; try again shortly",
+					cap(l.slots), request.Params.Name, l.queueTimeout)), nil
+			}
+			wait.cancel()
+			defer func() { <-l.slots }()
+		}
+
+		budget := &callBudget{limiter: l}
+		defer l.release(budget)
+		return next(context.WithValue(ctx, callBudgetKey{}, budget), request)
+	}
+}
+
+// queueWait is a context ending when a call has waited long enough
+type queueWait struct {
+	context.Context
+	cancel context.CancelFunc
+}
+
+func (l *CallLimiter) wait(ctx context.Context) queueWait {
+	if l.queueTimeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return queueWait{ctx, cancel}
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.queueTimeout)
+	return queueWait{ctx, cancel}
+}
+
+// reserve takes n bytes of the memory budget for the call of ctx, waiting
+// for other calls to release memory if needed
+func (l *CallLimiter) reserve(ctx context.Context, budget *callBudget, n int64) error {
+	if l.callMemory > 0 && budget.reserved+n > l.callMemory {
+		return fmt.Errorf("this needs about %s, more than the %s a single call may use", formatBytes(budget.reserved+n), formatBytes(l.callMemory))
+	}
+	if l.memory <= 0 {
+		budget.reserved += n
+		return nil
+	}
+
+	wait := l.wait(ctx)
+	defer wait.cancel()
+	for {
+		l.mu.Lock()
+		if l.used+n <= l.memory || l.used == 0 {
+			l.used += n
+			l.mu.Unlock()
+			budget.reserved += n
+			return nil
+		}
+		released := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-wait.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("server busy: other calls are using the %s memory budget and this call waited %s for %s; try again shortly",
+				formatBytes(l.memory), l.queueTimeout, formatBytes(n))
+		}
+	}
+}
+
+// release returns the memory reserved by a call
+func (l *CallLimiter) release(budget *callBudget) {
+	if budget.reserved == 0 || l.memory <= 0 {
+		return
+	}
+	l.mu.Lock()
+	l.used -= budget.reserved
+	close(l.released)
+	l.released = make(chan struct{})
+	l.mu.Unlock()
+	budget.reserved = 0
+}
+
+// reserveMemory accounts n bytes to the current tool call, if it runs under
+// a CallLimiter, and fails if the call may not use that much
+func reserveMemory(ctx context.Context, n int64) error {
+	budget, ok := ctx.Value(callBudgetKey{}).(*callBudget)
+	if !ok {
+		return nil
+	}
+	return budget.limiter.reserve(ctx, budget, n)
+}
+
+// formatBytes formats a size in MB, or KB for small sizes
+func formatBytes(n int64) string {
+	if n < 1<<20 {
+		return fmt.Sprintf("%d KB", (n+1<<10-1)>>10)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
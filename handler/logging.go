@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// loggerName is reported as the logger of every notification sent by this server
+const loggerName = "gerrit-code-review-mcp"
+
+// logLevelSeverity orders the MCP logging levels from least to most severe
+var logLevelSeverity = map[mcp.LoggingLevel]int{
+	mcp.LoggingLevelDebug:     0,
+	mcp.LoggingLevelInfo:      1,
+	mcp.LoggingLevelNotice:    2,
+	mcp.LoggingLevelWarning:   3,
+	mcp.LoggingLevelError:     4,
+	mcp.LoggingLevelCritical:  5,
+	mcp.LoggingLevelAlert:     6,
+	mcp.LoggingLevelEmergency: 7,
+}
+
+// ParseLogLevel converts a level name such as "info" or "warning" to an MCP logging level
+func ParseLogLevel(level string) (mcp.LoggingLevel, error) {
+	l := mcp.LoggingLevel(strings.ToLower(strings.TrimSpace(level)))
+	if _, ok := logLevelSeverity[l]; !ok {
+		return "", fmt.Errorf("unknown log level %q", level)
+	}
+	return l, nil
+}
+
+// Notifier sends MCP logging notifications describing tool activity to the
+// client of the session found in the request context, so clients can show
+// what the server is doing without access to its stderr
+type Notifier struct {
+	mu    sync.Mutex
+	level mcp.LoggingLevel
+	// generation counts the level changes made with SetLevel; applied holds
+	// the generation each session last had the level applied at
+	generation int
+	applied    map[string]int
+}
+
+// NewNotifier creates a Notifier whose sessions start at the given minimum level.
+// Clients can still change the level of their session with logging/setLevel.
+func NewNotifier(level mcp.LoggingLevel) *Notifier {
+	return &Notifier{level: level, applied: make(map[string]int)}
+}
+
+// Level returns the minimum level sessions start at
+func (n *Notifier) Level() mcp.LoggingLevel {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.level
+}
+
+// SetLevel changes the minimum level at runtime. Sessions that already exist
+// switch to it with their next notification; clients can still change it
+// again afterwards.
+func (n *Notifier) SetLevel(level mcp.LoggingLevel) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.level = level
+	n.generation++
+}
+
+// AfterInitialize is a server hook applying the configured level to new sessions
+func (n *Notifier) AfterInitialize(ctx context.Context, id any, message *mcp.InitializeRequest, result *mcp.InitializeResult) {
+	if session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithLogging); ok {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		session.SetLogLevel(n.level)
+		n.applied[session.SessionID()] = n.generation
+	}
+}
+
+// OnUnregisterSession is a server hook forgetting sessions that ended
+func (n *Notifier) OnUnregisterSession(ctx context.Context, session server.ClientSession) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.applied, session.SessionID())
+}
+
+// threshold returns the minimum level of a session, first applying a level
+// set with SetLevel since the session last saw one
+func (n *Notifier) threshold(session server.ClientSession) mcp.LoggingLevel {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	s, ok := session.(server.SessionWithLogging)
+	if !ok {
+		return n.level
+	}
+	if n.generation > 0 && n.applied[s.SessionID()] < n.generation {
+		s.SetLogLevel(n.level)
+		n.applied[s.SessionID()] = n.generation
+	}
+	return s.GetLogLevel()
+}
+
+// Log sends a notification if the session's level lets it through.
+// It is a no-op outside of an MCP request.
+func (n *Notifier) Log(ctx context.Context, level mcp.LoggingLevel, data map[string]any) {
+	srv := server.ServerFromContext(ctx)
+	session := server.ClientSessionFromContext(ctx)
+	if srv == nil || session == nil {
+		return
+	}
+
+	if logLevelSeverity[level] < logLevelSeverity[n.threshold(session)] {
+		return
+	}
+
+	// Delivery is best effort, a blocked notification channel must not fail the call
+	_ = srv.SendNotificationToClient(ctx, "notifications/message", map[string]any{
+		"level":  level,
+		"logger": loggerName,
+		"data":   data,
+	})
+}
+
+// Middleware reports each tool invocation and how long it took
+func (n *Notifier) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		n.Log(ctx, mcp.LoggingLevelInfo, map[string]any{
+			"event":     "tool_call",
+			"tool":      request.Params.Name,
+			"arguments": request.GetArguments(),
+		})
+
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		data := map[string]any{
+			"event":       "tool_result",
+			"tool":        request.Params.Name,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}
+		level := mcp.LoggingLevelInfo
+		switch {
+		case err != nil:
+			level = mcp.LoggingLevelError
+			data["error"] = err.Error()
+		case result != nil && result.IsError:
+			level = mcp.LoggingLevelWarning
+			data["error"] = true
+		}
+		n.Log(ctx, level, data)
+
+		return result, err
+	}
+}
+
+// Transport wraps base so every Gerrit REST request is reported with its
+// status and timing. go-gerrit builds requests from the tool call context,
+// which is how the notification finds its session.
+func (n *Notifier) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &notifyingTransport{notifier: n, base: base}
+}
+
+type notifyingTransport struct {
+	notifier *Notifier
+	base     http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *notifyingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	data := map[string]any{
+		"event":       "gerrit_request",
+		"method":      req.Method,
+		"url":         req.URL.Redacted(),
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	level := mcp.LoggingLevelInfo
+	if err != nil {
+		level = mcp.LoggingLevelWarning
+		data["error"] = err.Error()
+	} else {
+		data["status"] = resp.StatusCode
+	}
+	t.notifier.Log(req.Context(), level, data)
+
+	return resp, err
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Middleware wraps the handler of a tool to add cross-cutting behaviour such
+// as logging, timeouts or access checks. It is given the tool it wraps so
+// that it can act on the tool's name or category.
+type Middleware func(tool Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc
+
+// ForAllTools adapts a middleware that behaves the same for every tool
+func ForAllTools(mw server.ToolHandlerMiddleware) Middleware {
+	return func(_ Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return mw(next)
+	}
+}
+
+// Chain composes middlewares into one; the first one runs outermost
+func Chain(mws ...Middleware) Middleware {
+	return func(tool Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](tool, next)
+		}
+		return next
+	}
+}
+
+// EnforceReadOnly rejects write and admin tools while the connection used
+// for the call is read-only, e.g. after falling back to anonymous access
+func (h *Handler) EnforceReadOnly(tool Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if tool.Category == CategoryRead {
+		return next
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if h.connectionStatus(ctx).ReadOnly {
+			return mcp.NewToolResultError(fmt.Sprintf("%s is not available: the server is connected to Gerrit in read-only mode", tool.Tool.Name)), nil
+		}
+		return next(ctx, request)
+	}
+}
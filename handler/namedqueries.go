@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RunGerritNamedQuery runs one of the configured queries, optionally narrowed
+// by extra search terms
+func (h *Handler) RunGerritNamedQuery(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	query, ok := h.cfg().Queries[name]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown query %q, available: %s", name, strings.Join(sortedKeys(h.cfg().Queries), ", "))), nil
+	}
+	if filters := strings.TrimSpace(request.GetString("filters", "")); filters != "" {
+		query = fmt.Sprintf("(%s) %s", query, filters)
+	}
+
+	args := maps.Clone(request.GetArguments())
+	if args == nil {
+		args = make(map[string]any)
+	}
+	args["query"] = query
+	request.Params.Arguments = args
+	return h.QueryGerritChanges(ctx, request)
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// normalization selects the clean-ups applied to file and diff content
+// before it is returned, for content that would otherwise render as garbage
+type normalization struct {
+	lineEndings bool
+	bom         bool
+	utf8        bool
+}
+
+// parseNormalization parses a comma-separated list of eol, bom and utf8, or all
+func parseNormalization(value string) (normalization, error) {
+	var n normalization
+	for _, item := range strings.Split(value, ",") {
+		switch strings.TrimSpace(item) {
+		case "":
+		case "all":
+			n = normalization{lineEndings: true, bom: true, utf8: true}
+		case "eol":
+			n.lineEndings = true
+		case "bom":
+			n.bom = true
+		case "utf8":
+			n.utf8 = true
+		default:
+			return n, fmt.Errorf("unknown normalization %q, expected eol, bom, utf8 or all", item)
+		}
+	}
+	return n, nil
+}
+
+// apply normalizes text and describes what it changed, if anything
+func (n normalization) apply(text string) (string, string) {
+	var c normalizationCounts
+	text = n.normalize(text, &c)
+	return text, c.note()
+}
+
+// normalizationCounts counts what a normalization changed
+type normalizationCounts struct {
+	invalid, boms, crs int
+}
+
+// normalize normalizes text and adds what it changed to c
+func (n normalization) normalize(text string, c *normalizationCounts) string {
+	if n.utf8 {
+		if invalid := countInvalidUTF8(text); invalid > 0 {
+			text = strings.ToValidUTF8(text, "\ufffd")
+			c.invalid += invalid
+		}
+	}
+	if n.bom {
+		if boms := strings.Count(text, "\ufeff"); boms > 0 {
+			text = strings.ReplaceAll(text, "\ufeff", "")
+			c.boms += boms
+		}
+	}
+	if n.lineEndings {
+		if crs := strings.Count(text, "\r"); crs > 0 {
+			text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+			c.crs += crs
+		}
+	}
+	return text
+}
+
+// note describes the changes, or returns "" if there were none
+func (c normalizationCounts) note() string {
+	var changes []string
+	if c.invalid > 0 {
+		changes = append(changes, "replaced "+plural(c.invalid, "invalid UTF-8 sequence"))
+	}
+	if c.boms > 0 {
+		changes = append(changes, "stripped "+plural(c.boms, "byte order mark"))
+	}
+	if c.crs > 0 {
+		changes = append(changes, "converted "+plural(c.crs, "CR line ending")+" to LF")
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	return "NOTE: content was normalized: " + strings.Join(changes, ", ")
+}
+
+// normalizingReader normalizes what it reads from r line by line, so that
+// a patch can be normalized while it is parsed
+type normalizingReader struct {
+	n       normalization
+	r       *bufio.Reader
+	pending string
+	counts  normalizationCounts
+	err     error
+}
+
+// reader returns r normalized; its counts are final once r is exhausted
+func (n normalization) reader(r io.Reader) *normalizingReader {
+	return &normalizingReader{n: n, r: bufio.NewReader(r)}
+}
+
+func (nr *normalizingReader) Read(p []byte) (int, error) {
+	for nr.pending == "" {
+		if nr.err != nil {
+			return 0, nr.err
+		}
+		// A line ending in CR stays whole, as the LF after it is part of the same line
+		var line string
+		line, nr.err = nr.r.ReadString('\n')
+		nr.pending = nr.n.normalize(line, &nr.counts)
+	}
+	n := copy(p, nr.pending)
+	nr.pending = nr.pending[n:]
+	return n, nil
+}
+
+// countInvalidUTF8 counts the runs of bytes that are not valid UTF-8
+func countInvalidUTF8(text string) int {
+	count := 0
+	inRun := false
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		invalid := r == utf8.RuneError && size == 1
+		if invalid && !inRun {
+			count++
+		}
+		inRun = invalid
+		i += size
+	}
+	return count
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxOverlapFiles bounds the path: terms of the overlap search, which Gerrit
+// would otherwise reject as too long for large changes
+const maxOverlapFiles = 30
+
+// overlap is an open change touching some of the same files as another
+type overlap struct {
+	change gerrit.ChangeInfo
+	files  []string
+	// conflicts is set if Gerrit reports that the changes can't both be merged
+	conflicts bool
+}
+
+// GetGerritChangeOverlaps finds open changes in the same project that touch
+// the same files as a change, to warn about likely merge conflicts or
+// duplicate work
+func (h *Handler) GetGerritChangeOverlaps(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	limit := request.GetInt("limit", 25)
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "CURRENT_FILES")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	files := changedFiles(change)
+	paths := sortedKeys(files)
+
+	if len(paths) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("Change %s touches no files", changeID)), nil
+	}
+
+	searched := paths
+	if len(searched) > maxOverlapFiles {
+		searched = searched[:maxOverlapFiles]
+		header = append(header, fmt.Sprintf("WARNING: only changes touching the first %d of %d files were searched", maxOverlapFiles, len(paths)))
+	}
+	terms := make([]string, len(searched))
+	for i, path := range searched {
+		terms[i] = "path:" + strconv.Quote(path)
+	}
+	query := fmt.Sprintf("status:open project:%s -change:%d (%s)", strconv.Quote(change.Project), change.Number, strings.Join(terms, " OR "))
+
+	others, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{query}, Limit: limit},
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: []string{"CURRENT_REVISION", "CURRENT_FILES", "DETAILED_ACCOUNTS"}},
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to query changes touching the same files: %v", err)), nil
+	}
+
+	// Gerrit knows which changes actually conflict with this one
+	conflicting := make(map[int]bool)
+	conflicts, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions: gerrit.QueryOptions{Query: []string{fmt.Sprintf("status:open conflicts:%d", change.Number)}, Limit: limit},
+	})
+	if err != nil {
+		header = append(header, fmt.Sprintf("WARNING: could not check for merge conflicts: %v", err))
+	} else {
+		for _, c := range *conflicts {
+			conflicting[c.Number] = true
+		}
+	}
+
+	var overlaps []overlap
+	for _, other := range *others {
+		o := overlap{change: other, conflicts: conflicting[other.Number]}
+		for path := range changedFiles(&other) {
+			if _, ok := files[path]; ok {
+				o.files = append(o.files, path)
+			}
+		}
+		if len(o.files) == 0 {
+			continue
+		}
+		sort.Strings(o.files)
+		overlaps = append(overlaps, o)
+	}
+	sort.SliceStable(overlaps, func(i, j int) bool {
+		if overlaps[i].conflicts != overlaps[j].conflicts {
+			return overlaps[i].conflicts
+		}
+		return len(overlaps[i].files) > len(overlaps[j].files)
+	})
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	if len(overlaps) == 0 {
+		fmt.Fprintf(&b, "No open changes in %s touch the files of change %s", change.Project, changeID)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	fmt.Fprintf(&b, "%s in %s touching files of change %s (%s), most overlap first:\n", plural(len(overlaps), "open change"), change.Project, changeID, plural(len(paths), "file"))
+	for _, o := range overlaps {
+		fmt.Fprintf(&b, "\n%d [%s] %s\n", o.change.Number, o.change.Branch, o.change.Subject)
+		fmt.Fprintf(&b, "  owner %s, shares %d of %s\n", formatAccount(o.change.Owner), len(o.files), plural(len(paths), "file"))
+		var warnings []string
+		switch {
+		case o.conflicts:
+			warnings = append(warnings, "merge conflict: Gerrit reports the changes can't both be merged as they are")
+		case o.change.Branch != change.Branch:
+			warnings = append(warnings, fmt.Sprintf("on branch %s, so a conflict would only show when cherry-picking", o.change.Branch))
+		}
+		// Changing mostly the same files suggests the same work done twice
+		if 2*len(o.files) >= len(paths) && 2*len(o.files) >= len(changedFiles(&o.change)) {
+			warnings = append(warnings, "possible duplicate: most files of both changes are shared")
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(&b, "  WARNING: %s\n", w)
+		}
+		for _, path := range o.files {
+			fmt.Fprintf(&b, "  %s\n", path)
+		}
+	}
+	if n := len(*others); n > 0 && (*others)[n-1].MoreChanges {
+		fmt.Fprintf(&b, "\nWARNING: more changes may overlap; raise limit to see them\n")
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
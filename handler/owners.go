@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxOwnerSuggestions bounds the per-file code owner lookups done for one change
+const maxOwnerSuggestions = 20
+
+// codeOwnerStatusInfo is the code-owners plugin's CodeOwnerStatusInfo
+type codeOwnerStatusInfo struct {
+	FileCodeOwnerStatuses []fileCodeOwnerStatusInfo     `json:"file_code_owner_statuses"`
+	Accounts              map[string]gerrit.AccountInfo `json:"accounts"`
+}
+
+type fileCodeOwnerStatusInfo struct {
+	ChangeType    string                   `json:"change_type"`
+	NewPathStatus *pathCodeOwnerStatusInfo `json:"new_path_status"`
+	OldPathStatus *pathCodeOwnerStatusInfo `json:"old_path_status"`
+}
+
+type pathCodeOwnerStatusInfo struct {
+	Path    string   `json:"path"`
+	Status  string   `json:"status"`
+	Reasons []string `json:"reasons"`
+}
+
+// codeOwnersInfo is the code-owners plugin's CodeOwnersInfo
+type codeOwnersInfo struct {
+	CodeOwners []struct {
+		Account gerrit.AccountInfo `json:"account"`
+	} `json:"code_owners"`
+}
+
+var accountPlaceholderRegexp = regexp.MustCompile(`<GERRIT_ACCOUNT_(\d+)>`)
+
+// GetGerritChangeOwners reports per changed file whether the required code
+// owner approval is present and who can give it. It uses the code-owners
+// plugin if installed and otherwise reads OWNERS files from the target branch.
+func (h *Handler) GetGerritChangeOwners(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "CURRENT_FILES", "DETAILED_LABELS")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+
+	var status codeOwnerStatusInfo
+	resp, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("changes/%s/code_owners.status", changeID), nil, &status)
+	switch {
+	case err == nil:
+		h.renderCodeOwnerStatus(ctx, &b, changeID, status)
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		if err := h.renderOwnersFiles(ctx, &b, change); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get code owner status of change %s: %v", changeID, err)), nil
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// renderCodeOwnerStatus writes the status reported by the code-owners plugin,
+// suggesting owners for the files that still lack an approval
+func (h *Handler) renderCodeOwnerStatus(ctx context.Context, b *strings.Builder, changeID string, status codeOwnerStatusInfo) {
+	accountName := func(m string) string {
+		id := accountPlaceholderRegexp.FindStringSubmatch(m)[1]
+		if account, ok := status.Accounts[id]; ok {
+			return formatAccount(account)
+		}
+		return "account " + id
+	}
+
+	approved := 0
+	suggestions := 0
+	var lines []string
+	for _, file := range status.FileCodeOwnerStatuses {
+		// Renames need approval for both paths, the new one is shown first
+		for _, ps := range []*pathCodeOwnerStatusInfo{file.NewPathStatus, file.OldPathStatus} {
+			if ps == nil {
+				continue
+			}
+			line := fmt.Sprintf("%s: %s", ps.Path, ps.Status)
+			for _, reason := range ps.Reasons {
+				line += "\n  " + accountPlaceholderRegexp.ReplaceAllStringFunc(reason, accountName)
+			}
+			if ps.Status == "APPROVED" {
+				approved++
+			} else if suggestions < maxOwnerSuggestions {
+				suggestions++
+				var owners codeOwnersInfo
+				ownersPath := fmt.Sprintf("changes/%s/revisions/current/code_owners/%s?limit=5", changeID, url.PathEscape(strings.TrimPrefix(ps.Path, "/")))
+				if _, err := h.client.Call(ctx, http.MethodGet, ownersPath, nil, &owners); err != nil {
+					line += fmt.Sprintf("\n  could not look up code owners: %v", err)
+				} else if len(owners.CodeOwners) > 0 {
+					var names []string
+					for _, o := range owners.CodeOwners {
+						names = append(names, formatAccount(o.Account))
+					}
+					line += "\n  code owners: " + strings.Join(names, ", ")
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	fmt.Fprintf(b, "Code owners (code-owners plugin): %d of %d paths approved\n\n", approved, len(lines))
+	b.WriteString(strings.Join(lines, "\n"))
+}
+
+// ownersFile is the parsed content of an OWNERS file
+type ownersFile struct {
+	owners   []string
+	anyone   bool
+	noParent bool
+	perFile  []perFileOwners
+}
+
+// perFileOwners is a "per-file glob=owners" rule
+type perFileOwners struct {
+	glob   string
+	owners []string
+}
+
+// parseOwnersFile understands the common subset of the OWNERS syntax: one
+// email per line, "*", "set noparent" and "per-file". Includes are ignored.
+func parseOwnersFile(content string) *ownersFile {
+	f := &ownersFile{}
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case line == "*":
+			f.anyone = true
+		case line == "set noparent":
+			f.noParent = true
+		case strings.HasPrefix(line, "per-file "):
+			globs, owners, ok := strings.Cut(strings.TrimPrefix(line, "per-file "), "=")
+			if !ok {
+				continue
+			}
+			var list []string
+			for _, o := range strings.Split(owners, ",") {
+				list = append(list, strings.TrimSpace(o))
+			}
+			for _, glob := range strings.Split(globs, ",") {
+				f.perFile = append(f.perFile, perFileOwners{glob: strings.TrimSpace(glob), owners: list})
+			}
+		case strings.Contains(line, "@"):
+			f.owners = append(f.owners, line)
+		}
+	}
+	return f
+}
+
+// renderOwnersFiles determines owners from the OWNERS files on the change's
+// target branch and checks for positive Code-Review votes from them
+func (h *Handler) renderOwnersFiles(ctx context.Context, b *strings.Builder, change *gerrit.ChangeInfo) error {
+	// Files are read from the target branch, since the change must not be
+	// able to approve itself by editing OWNERS
+	cache := make(map[string]*ownersFile)
+	readOwners := func(dir string) (*ownersFile, error) {
+		if f, ok := cache[dir]; ok {
+			return f, nil
+		}
+		filePath := path.Join(dir, "OWNERS")
+		contentPath := fmt.Sprintf("projects/%s/branches/%s/files/%s/content", url.PathEscape(change.Project), url.PathEscape(change.Branch), url.PathEscape(filePath))
+		content, resp, err := h.fileContent(ctx, contentPath)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			cache[dir] = nil
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", filePath, err)
+		}
+		cache[dir] = parseOwnersFile(content)
+		return cache[dir], nil
+	}
+
+	voters := make(map[string]gerrit.AccountInfo)
+	for _, approval := range change.Labels["Code-Review"].All {
+		if approval.Value > 0 && approval.Email != "" {
+			voters[strings.ToLower(approval.Email)] = approval.AccountInfo
+		}
+	}
+
+	files := changedFiles(change)
+	approved := 0
+	var lines []string
+	for _, filePath := range sortedKeys(files) {
+		owners := make(map[string]bool)
+		anyone := false
+		found := false
+		for dir := path.Dir(filePath); ; dir = path.Dir(dir) {
+			if dir == "." {
+				dir = ""
+			}
+			f, err := readOwners(dir)
+			if err != nil {
+				return err
+			}
+			if f != nil {
+				found = true
+				for _, o := range f.owners {
+					owners[strings.ToLower(o)] = true
+				}
+				// Globs are relative to the directory of the OWNERS file
+				rel := filePath
+				if dir != "" {
+					rel = strings.TrimPrefix(filePath, dir+"/")
+				}
+				for _, rule := range f.perFile {
+					if ok, _ := path.Match(rule.glob, rel); ok {
+						for _, o := range rule.owners {
+							owners[strings.ToLower(o)] = true
+						}
+					}
+				}
+				anyone = anyone || f.anyone
+				if f.noParent {
+					break
+				}
+			}
+			if dir == "" {
+				break
+			}
+		}
+
+		var approvers []string
+		for email, account := range voters {
+			if anyone || owners[email] {
+				approvers = append(approvers, formatAccount(account))
+			}
+		}
+		sort.Strings(approvers)
+
+		switch {
+		case !found:
+			lines = append(lines, fmt.Sprintf("%s: NO OWNERS FILE", filePath))
+		case len(approvers) > 0:
+			approved++
+			lines = append(lines, fmt.Sprintf("%s: APPROVED by %s", filePath, strings.Join(approvers, ", ")))
+		case anyone:
+			lines = append(lines, fmt.Sprintf("%s: PENDING, anyone may approve", filePath))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: PENDING, owners: %s", filePath, strings.Join(sortedKeys(owners), ", ")))
+		}
+	}
+
+	fmt.Fprintf(b, "Code owners (from OWNERS files; the code-owners plugin is not installed): %d of %d files approved\n\n", approved, len(lines))
+	b.WriteString(strings.Join(lines, "\n"))
+	return nil
+}
+
+// formatAccount renders an account as "Name <email>", falling back to what is known
+func formatAccount(account gerrit.AccountInfo) string {
+	switch {
+	case account.Name != "" && account.Email != "":
+		return fmt.Sprintf("%s <%s>", account.Name, account.Email)
+	case account.Name != "":
+		return account.Name
+	case account.Email != "":
+		return account.Email
+	case account.Username != "":
+		return account.Username
+	default:
+		return fmt.Sprintf("account %d", account.AccountID)
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Page sizes of the paginated tools
+const (
+	DefaultPageSize = 25
+	MaxPageSize     = 500
+)
+
+// cursor is the content of an opaque pagination cursor: where the next page
+// starts and a hash of the request it belongs to
+type cursor struct {
+	Hash  string `json:"h"`
+	Start int    `json:"s"`
+}
+
+// page is the window of results requested from a paginated tool
+type page struct {
+	hash  string
+	start int
+	// limit is the page size, 0 for everything
+	limit int
+}
+
+// requestHash identifies a request by the arguments that select its results
+func requestHash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// newPage reads the limit, start and cursor parameters of a paginated tool.
+// A cursor overrides start. The scope identifies the results so that a
+// cursor can't be applied to the results of a different request.
+func newPage(request mcp.CallToolRequest, defaultLimit int, scope ...string) (page, error) {
+	p := page{hash: requestHash(scope...), limit: request.GetInt("limit", defaultLimit), start: request.GetInt("start", 0)}
+	if p.limit < 0 {
+		return p, fmt.Errorf("limit must not be negative")
+	}
+	if p.limit > MaxPageSize {
+		p.limit = MaxPageSize
+	}
+	if p.start < 0 {
+		return p, fmt.Errorf("start must not be negative")
+	}
+
+	value := request.GetString("cursor", "")
+	if value == "" {
+		return p, nil
+	}
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.Start < 0 {
+		return p, fmt.Errorf("invalid cursor %q", value)
+	}
+	if c.Hash != p.hash {
+		return p, fmt.Errorf("the cursor belongs to a different request; repeat the request it came from with the same arguments")
+	}
+	p.start = c.Start
+	return p, nil
+}
+
+// window returns the bounds of the page within n results
+func (p page) window(n int) (from, to int) {
+	from = min(p.start, n)
+	to = n
+	if p.limit > 0 {
+		to = min(from+p.limit, n)
+	}
+	return from, to
+}
+
+// footer describes whether more results follow and how to get them, given
+// how many results the page showed
+func (p page) footer(shown int, more bool) string {
+	if !more {
+		return "has_more: false"
+	}
+	data, _ := json.Marshal(cursor{Hash: p.hash, Start: p.start + shown})
+	return "has_more: true\nnext_cursor: " + base64.RawURLEncoding.EncodeToString(data)
+}
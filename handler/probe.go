@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// CheckGerritChange reports whether a change exists and is visible, fetching
+// only its summary
+func (h *Handler) CheckGerritChange(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := h.checkHost(changeURL); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	changeID, err := extractChangeID(changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to parse change URL: %v", err)), nil
+	}
+
+	change, resp, err := h.client.GetChange(ctx, changeID, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		// Gerrit answers 404 for changes the account can't see too
+		return mcp.NewToolResultText(fmt.Sprintf("exists: false\nChange %s does not exist or is not visible to the current account", changeID)), nil
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get change %s: %v", changeID, err)), nil
+	}
+
+	lines := []string{
+		"exists: true",
+		fmt.Sprintf("change: %d", change.Number),
+		"status: " + change.Status,
+		"project: " + change.Project,
+		"branch: " + change.Branch,
+		"subject: " + change.Subject,
+	}
+	if webURL := h.canonicalChangeURL(change, resp); webURL != "" {
+		lines = append(lines, "url: "+webURL)
+	}
+	if host := changeHost(changeURL); !h.isKnownHost(host) {
+		lines = append(lines, fmt.Sprintf("WARNING: host %s is not the configured Gerrit server; change %s was looked up on %s instead", host, changeID, h.baseURL.Host))
+	}
+	return mcp.NewToolResultText(strings.Join(lines, "\n")), nil
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ProjectSettings override server-wide settings for the changes of one project
+type ProjectSettings struct {
+	// ResponseBudgets override the server's response budgets, by tool name or "default"
+	ResponseBudgets map[string]int `json:"response_budgets"`
+	// DisabledTools lists tool names or categories refused for the project,
+	// e.g. "write" for a protected repository
+	DisabledTools []string `json:"disabled_tools"`
+	// ReviewSLAHours overrides the server's review SLA
+	ReviewSLAHours int `json:"review_sla_hours"`
+	// CommitMessage overrides the set fields of the server's commit
+	// message conventions
+	CommitMessage *CommitMessageConventions `json:"commit_message"`
+	// ProjectGuidelines replaces the server's list of guideline files
+	ProjectGuidelines []string `json:"project_guidelines"`
+}
+
+// disables reports whether the settings refuse a tool
+func (s ProjectSettings) disables(tool Tool) bool {
+	for _, name := range s.DisabledTools {
+		if name == tool.Tool.Name || name == tool.Category {
+			return true
+		}
+	}
+	return false
+}
+
+var projectURLRegexp = regexp.MustCompile(`/c/(.+?)/\+/\d+`)
+
+// projectFromURL returns the project named in a modern change URL, or ""
+func projectFromURL(changeURL string) string {
+	m := projectURLRegexp.FindStringSubmatch(changeURL)
+	if m == nil {
+		return ""
+	}
+	project, err := url.PathUnescape(m[1])
+	if err != nil {
+		return ""
+	}
+	return project
+}
+
+type projectKey struct{}
+
+// projectFromContext returns the project of the change a tool call is about, if known
+func projectFromContext(ctx context.Context) string {
+	project, _ := ctx.Value(projectKey{}).(string)
+	return project
+}
+
+// changeProject finds the project of the change a tool call is about, from
+// the change URL if it names the project and from Gerrit otherwise
+func (h *Handler) changeProject(ctx context.Context, request mcp.CallToolRequest) string {
+	changeURL := request.GetString("change_url", "")
+	if changeURL == "" || h.checkHost(changeURL) != nil {
+		return ""
+	}
+	if project := projectFromURL(changeURL); project != "" {
+		return project
+	}
+	changeID, err := extractChangeID(changeURL)
+	if err != nil {
+		return ""
+	}
+	change, _, err := h.client.GetChange(ctx, changeID, nil)
+	if err != nil {
+		// The tool itself will report that the change can't be found
+		return ""
+	}
+	return change.Project
+}
+
+// EnforceProjectSettings applies the configured per-project overrides to
+// tool calls about a change: it refuses tools disabled for the change's
+// project and makes the project known to later middlewares
+func (h *Handler) EnforceProjectSettings(tool Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		projects := h.cfg().Projects
+		if len(projects) == 0 {
+			return next(ctx, request)
+		}
+		project := h.changeProject(ctx, request)
+		if project == "" {
+			return next(ctx, request)
+		}
+		if settings, ok := projects[project]; ok && settings.disables(tool) {
+			return mcp.NewToolResultError(fmt.Sprintf("%s is disabled for changes in project %s", tool.Tool.Name, project)), nil
+		}
+		return next(context.WithValue(ctx, projectKey{}, project), request)
+	}
+}
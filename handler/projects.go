@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// projectInfo is Gerrit's ProjectInfo
+type projectInfo struct {
+	Description string `json:"description"`
+	State       string `json:"state"`
+}
+
+// groupInfo is Gerrit's GroupInfo
+type groupInfo struct {
+	GroupID     int    `json:"group_id"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+}
+
+// listQuery builds the query string of Gerrit's list endpoints, asking for
+// one entry more than the page holds to learn whether more follow
+func listQuery(p page, values url.Values) string {
+	values.Set("n", strconv.Itoa(p.limit+1))
+	if p.start > 0 {
+		values.Set("S", strconv.Itoa(p.start))
+	}
+	return values.Encode()
+}
+
+// ListGerritProjects lists the projects visible to the user, one page at a time
+func (h *Handler) ListGerritProjects(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	match := request.GetString("match", "")
+	p, err := newPage(request, DefaultPageSize, "list-gerrit-projects", match)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if p.limit == 0 {
+		p.limit = MaxPageSize
+	}
+
+	values := url.Values{}
+	if match != "" {
+		values.Set("m", match)
+	}
+	var projects map[string]projectInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "projects/?d&"+listQuery(p, values), nil, &projects); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list projects: %v", err)), nil
+	}
+
+	names := sortedKeys(projects)
+	more := len(names) > p.limit
+	names = names[:min(len(names), p.limit)]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n\n", plural(len(names), "project"))
+	for _, name := range names {
+		line := name
+		if info := projects[name]; info.State != "" && info.State != "ACTIVE" {
+			line += " [" + info.State + "]"
+		}
+		if description := projects[name].Description; description != "" {
+			line += ": " + strings.SplitN(description, "\n", 2)[0]
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n" + p.footer(len(names), more))
+
+	return mcp.NewToolResultText(b.String()), nil
+}
+
+// ListGerritGroups lists the groups visible to the user, one page at a time
+func (h *Handler) ListGerritGroups(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	match := request.GetString("match", "")
+	p, err := newPage(request, DefaultPageSize, "list-gerrit-groups", match)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if p.limit == 0 {
+		p.limit = MaxPageSize
+	}
+
+	values := url.Values{}
+	if match != "" {
+		values.Set("m", match)
+	}
+	var groups map[string]groupInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "groups/?"+listQuery(p, values), nil, &groups); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list groups: %v", err)), nil
+	}
+
+	names := sortedKeys(groups)
+	more := len(names) > p.limit
+	names = names[:min(len(names), p.limit)]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n\n", plural(len(names), "group"))
+	for _, name := range names {
+		line := name
+		if owner := groups[name].Owner; owner != "" && owner != name {
+			line += " (owned by " + owner + ")"
+		}
+		if description := groups[name].Description; description != "" {
+			line += ": " + strings.SplitN(description, "\n", 2)[0]
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n" + p.footer(len(names), more))
+
+	return mcp.NewToolResultText(b.String()), nil
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// QueryGerritChanges searches changes with Gerrit's query syntax, one page at a time
+func (h *Handler) QueryGerritChanges(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fields, options, err := parseFields(request.GetString("fields", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	sortBy := request.GetString("sort", "updated")
+	less, ok := changeOrders[sortBy]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown sort %q, expected one of %s", sortBy, strings.Join(sortedKeys(changeOrders), ", "))), nil
+	}
+	ascending := request.GetBool("ascending", false)
+	p, err := newPage(request, DefaultPageSize, "query-gerrit-changes", query, sortBy, fmt.Sprint(ascending))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if p.limit == 0 {
+		p.limit = MaxPageSize
+	}
+
+	// Gerrit returns the most recently updated changes first; any other
+	// order needs all matches, so it is applied to the first
+	// maxSortedChanges of them
+	native := sortBy == "updated" && !ascending
+	opt := &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{query}, Limit: p.limit},
+		Start:         p.start,
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: options},
+	}
+	if !native {
+		opt.Limit = maxSortedChanges
+		opt.Start = 0
+	}
+	changes, _, err := h.client.QueryChanges(ctx, opt)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to query changes: %v", err)), nil
+	}
+	results := *changes
+	more := len(results) > 0 && results[len(results)-1].MoreChanges
+	truncated := false
+	if !native {
+		truncated = more
+		sort.SliceStable(results, func(i, j int) bool {
+			if ascending {
+				return less(results[i], results[j])
+			}
+			return less(results[j], results[i])
+		})
+		from, to := p.window(len(results))
+		more = to < len(results)
+		results = results[from:to]
+	}
+
+	var b strings.Builder
+	if len(results) == 0 {
+		if p.start > 0 {
+			fmt.Fprintf(&b, "No changes match %s beyond the first %d", query, p.start)
+		} else {
+			fmt.Fprintf(&b, "No changes match %s", query)
+		}
+		return mcp.NewToolResultText(b.String()), nil
+	}
+	if truncated {
+		fmt.Fprintf(&b, "WARNING: more than %d changes match, only the first %d by last update were sorted\n\n", maxSortedChanges, maxSortedChanges)
+	}
+	fmt.Fprintf(&b, "Changes %d-%d matching %s:\n\n", p.start+1, p.start+len(results), query)
+	for _, change := range results {
+		b.WriteString(formatChangeLine(change) + "\n")
+		renderFields(&b, change, fields, "  ")
+	}
+	b.WriteString("\n" + p.footer(len(results), more))
+
+	return mcp.NewToolResultText(b.String()), nil
+}
+
+// maxSortedChanges bounds how many matches are fetched to sort them in an
+// order Gerrit doesn't support
+const maxSortedChanges = 500
+
+// changeOrders are the sort orders of query results, each comparing changes
+// in ascending order
+var changeOrders = map[string]func(a, b gerrit.ChangeInfo) bool{
+	"updated": func(a, b gerrit.ChangeInfo) bool { return a.Updated.Before(b.Updated.Time) },
+	"created": func(a, b gerrit.ChangeInfo) bool { return a.Created.Before(b.Created.Time) },
+	"size": func(a, b gerrit.ChangeInfo) bool {
+		return a.Insertions+a.Deletions < b.Insertions+b.Deletions
+	},
+	"unresolved_comments": func(a, b gerrit.ChangeInfo) bool {
+		return a.UnresolvedCommentCount < b.UnresolvedCommentCount
+	},
+}
+
+// formatChangeLine summarizes a change on one line
+func formatChangeLine(change gerrit.ChangeInfo) string {
+	line := fmt.Sprintf("%d %s [%s] %s: %s", change.Number, change.Project, change.Branch, change.Status, change.Subject)
+	if change.WorkInProgress {
+		line += " (WIP)"
+	}
+	return line
+}
+
+// GetGerritChangeDetails describes a change with the selected blocks
+func (h *Handler) GetGerritChangeDetails(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fields, options, err := parseFields(request.GetString("fields", "owner,labels,reviewers,files"))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, options...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	b.WriteString(formatChangeLine(*change) + "\n")
+	if h.compat().assignee {
+		assignee, err := h.changeAssignee(ctx, changeID)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		fmt.Fprintf(&b, "assignee: %s\n", formatAssignee(assignee))
+	}
+	renderFields(&b, *change, fields, "")
+
+	if h.prefetcher != nil && !h.exceedsPatchLimits(change) {
+		h.prefetcher.Prefetch(ctx, changeID, change)
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultQuotaMaxWait is how long a bulk tool waits for quota before giving
+// up on its remaining items
+const DefaultQuotaMaxWait = time.Minute
+
+// quotaLowFraction is the share of the quota left below which bulk tools
+// spread their requests over the time until the quota resets
+const quotaLowFraction = 10
+
+// QuotaTracker follows how much of its request quota the server has left,
+// from 429 Too Many Requests answers and the rate limit headers of Gerrit's
+// quota plugin or a proxy in front of Gerrit. Bulk tools ask it to pace them
+// when the quota runs low, rather than failing halfway through a batch.
+type QuotaTracker struct {
+	maxWait time.Duration
+	now     func() time.Time
+
+	mu sync.Mutex
+	// limit and remaining are -1 until Gerrit reports them
+	limit     int
+	remaining int
+	reset     time.Time
+	// retryAt is when Gerrit allows requests again after a 429
+	retryAt       time.Time
+	throttled     int
+	lastThrottled time.Time
+}
+
+// NewQuotaTracker creates a tracker letting bulk tools wait up to maxWait
+// for quota before each of their items
+func NewQuotaTracker(maxWait time.Duration) *QuotaTracker {
+	return &QuotaTracker{maxWait: maxWait, now: time.Now, limit: -1, remaining: -1}
+}
+
+// WithQuotaTracker makes bulk tools wait for quota as q says, and the status
+// tool report it
+func WithQuotaTracker(q *QuotaTracker) Option {
+	return func(h *Handler) {
+		h.quota = q
+	}
+}
+
+// QuotaStatus is what the tracker knows about the server's quota
+type QuotaStatus struct {
+	// Limit and Remaining are -1 if Gerrit sent no rate limit headers
+	Limit     int
+	Remaining int
+	Reset     time.Time
+	// Throttled counts the 429 answers, the last one at LastThrottled
+	Throttled     int
+	LastThrottled time.Time
+	RetryAt       time.Time
+}
+
+// Transport wraps base so that every Gerrit answer updates the tracker
+func (q *QuotaTracker) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &quotaTransport{quota: q, base: base}
+}
+
+type quotaTransport struct {
+	quota *QuotaTracker
+	base  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *quotaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.quota.observe(resp)
+	}
+	return resp, err
+}
+
+// headerInt returns the first of the named headers holding an integer
+func headerInt(header http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		if n, err := strconv.Atoi(header.Get(name)); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// observe records the quota reported by an answer
+func (q *QuotaTracker) observe(resp *http.Response) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+
+	if limit, ok := headerInt(resp.Header, "X-RateLimit-Limit", "RateLimit-Limit"); ok {
+		q.limit = limit
+	}
+	if remaining, ok := headerInt(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining"); ok {
+		q.remaining = remaining
+	}
+	if reset, ok := headerInt(resp.Header, "X-RateLimit-Reset", "RateLimit-Reset"); ok {
+		// Either seconds from now or a Unix time
+		if reset > 1_000_000_000 {
+			q.reset = time.Unix(int64(reset), 0)
+		} else {
+			q.reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	q.throttled++
+	q.lastThrottled = now
+	q.retryAt = now.Add(time.Second)
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		q.retryAt = now.Add(time.Duration(seconds) * time.Second)
+	} else if at, err := http.ParseTime(resp.Header.Get("Retry-After")); err == nil {
+		q.retryAt = at
+	} else if q.reset.After(now) {
+		q.retryAt = q.reset
+	}
+}
+
+// Status returns what the tracker knows about the quota
+func (q *QuotaTracker) Status() QuotaStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QuotaStatus{
+		Limit:         q.limit,
+		Remaining:     q.remaining,
+		Reset:         q.reset,
+		Throttled:     q.throttled,
+		LastThrottled: q.lastThrottled,
+		RetryAt:       q.retryAt,
+	}
+}
+
+// delay returns how long to wait before the next request of a bulk tool
+func (q *QuotaTracker) delay() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+
+	if q.retryAt.After(now) {
+		return q.retryAt.Sub(now)
+	}
+	if q.remaining < 0 || !q.reset.After(now) {
+		// Unknown, or the quota has been renewed since
+		return 0
+	}
+	low := max(q.limit/quotaLowFraction, 1)
+	switch {
+	case q.remaining == 0:
+		return q.reset.Sub(now)
+	case q.remaining <= low:
+		// Spread what is left over the rest of the window
+		return q.reset.Sub(now) / time.Duration(q.remaining+1)
+	}
+	return 0
+}
+
+// Wait paces bulk tools: it returns at once while enough quota is left, and
+// otherwise waits for it. It fails if that would take longer than the
+// tracker's maximum wait, so that the tool can report what it didn't do.
+func (q *QuotaTracker) Wait(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+	d := q.delay()
+	if d <= 0 {
+		return nil
+	}
+	if d > q.maxWait {
+		return fmt.Errorf("the request quota is exhausted for another %s", d.Round(time.Second))
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttle waits until a bulk tool may make the requests of its next item
+func (h *Handler) throttle(ctx context.Context) error {
+	return h.quota.Wait(ctx)
+}
+
+// writeQuota adds the quota to the server status
+func (h *Handler) writeQuota(b *strings.Builder) {
+	if h.quota == nil {
+		return
+	}
+	status := h.quota.Status()
+	now := h.quota.now()
+	switch {
+	case status.Remaining < 0:
+		b.WriteString("Quota: unknown, Gerrit sent no rate limit headers\n")
+	case status.Limit >= 0:
+		fmt.Fprintf(b, "Quota: %d of %d requests left", status.Remaining, status.Limit)
+	default:
+		fmt.Fprintf(b, "Quota: %d requests left", status.Remaining)
+	}
+	if status.Remaining >= 0 {
+		if status.Reset.After(now) {
+			fmt.Fprintf(b, ", renewed in %s", status.Reset.Sub(now).Round(time.Second))
+		}
+		b.WriteString("\n")
+	}
+	if status.Throttled > 0 {
+		fmt.Fprintf(b, "Throttled: Gerrit answered 429 Too Many Requests %s, last at %s\n",
+			plural(status.Throttled, "time"), status.LastThrottled.Format(time.RFC3339))
+	}
+	if d := h.quota.delay(); d > 0 {
+		fmt.Fprintf(b, "WARNING: the request quota is running out; bulk tools wait %s before each item\n", d.Round(time.Second))
+	}
+}
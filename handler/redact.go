@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RedactionRule masks content before it leaves the server: text matching
+// Pattern anywhere, and the diffs of files matching Paths
+type RedactionRule struct {
+	// Pattern is a regular expression, e.g. `[\w.-]+\.corp\.example\.com`
+	Pattern string `json:"pattern"`
+	// Paths are globs in the same syntax as component paths
+	Paths []string `json:"paths"`
+	// Replacement replaces matches of Pattern, by default [REDACTED]
+	Replacement string `json:"replacement"`
+
+	re           *regexp.Regexp
+	pathPatterns []*regexp.Regexp
+}
+
+// compile prepares the rule's pattern and path patterns
+func (r *RedactionRule) compile() error {
+	if r.Pattern == "" && len(r.Paths) == 0 {
+		return fmt.Errorf("redaction rule has neither a pattern nor paths")
+	}
+	if r.Pattern != "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("redaction rule: %w", err)
+		}
+		r.re = re
+	}
+	if r.Replacement == "" {
+		r.Replacement = "[REDACTED]"
+	}
+	var err error
+	if r.pathPatterns, err = globRegexps(r.Paths); err != nil {
+		return fmt.Errorf("redaction rule: %w", err)
+	}
+	return nil
+}
+
+// redactedPath reports whether a path's content must not be returned
+func (c *Config) redactedPath(path string) bool {
+	for _, rule := range c.Redactions {
+		if len(rule.pathPatterns) > 0 && matchesAny(rule.pathPatterns, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact applies the redaction rules to text, replacing the diffs of
+// redacted files with a note
+func (c *Config) redact(text string) string {
+	if strings.Contains(text, "diff --git ") {
+		preamble, files := splitPatch(text)
+		var b strings.Builder
+		b.WriteString(preamble)
+		for _, file := range files {
+			diffLine, _, _ := strings.Cut(file, "\n")
+			if f := ParsePatch(diffLine); len(f) > 0 && (c.redactedPath(f[0].OldPath) || c.redactedPath(f[0].NewPath)) {
+				fmt.Fprintf(&b, "%s\n[diff redacted by policy]\n", diffLine)
+				continue
+			}
+			b.WriteString(file)
+		}
+		text = b.String()
+	}
+
+	for _, rule := range c.Redactions {
+		if rule.re != nil {
+			text = rule.re.ReplaceAllString(text, rule.Replacement)
+		}
+	}
+	return text
+}
+
+// Redact applies the configured redaction rules to every tool's results,
+// and refuses calls about a file whose content is redacted
+func (h *Handler) Redact(tool Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		cfg := h.cfg()
+		if len(cfg.Redactions) == 0 {
+			return next(ctx, request)
+		}
+		if file := request.GetString("file", ""); file != "" && cfg.redactedPath(file) {
+			return mcp.NewToolResultError(fmt.Sprintf("the content of %s is redacted by policy", file)), nil
+		}
+
+		result, err := next(ctx, request)
+		if err != nil || result == nil {
+			return result, err
+		}
+		for i, content := range result.Content {
+			switch c := content.(type) {
+			case mcp.TextContent:
+				c.Text = cfg.redact(c.Text)
+				result.Content[i] = c
+			case mcp.EmbeddedResource:
+				result.Content[i] = cfg.redactResource(c)
+			}
+		}
+		return result, nil
+	}
+}
+
+// redactResource redacts text encoded in a blob, and withholds blobs that
+// can't be inspected
+func (c *Config) redactResource(resource mcp.EmbeddedResource) mcp.Content {
+	blob, ok := resource.Resource.(mcp.BlobResourceContents)
+	if !ok {
+		return resource
+	}
+	data, err := base64.StdEncoding.DecodeString(blob.Blob)
+	if err != nil || !strings.HasPrefix(blob.MIMEType, "text/") {
+		return mcp.NewTextContent(fmt.Sprintf("%s (%s) withheld: redaction rules can't be applied to it", blob.URI, blob.MIMEType))
+	}
+	blob.Blob = base64.StdEncoding.EncodeToString([]byte(c.redact(string(data))))
+	resource.Resource = blob
+	return resource
+}
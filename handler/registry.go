@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Tool categories, usable in the enable/disable lists alongside tool names
+const (
+	CategoryRead  = "read"
+	CategoryWrite = "write"
+	CategoryAdmin = "admin"
+)
+
+// Tool is an MCP tool with its implementation and category
+type Tool struct {
+	server.ServerTool
+	Category string
+	// Requires is what the tool needs from the Gerrit server; tools whose
+	// requirements the probed server doesn't meet are not served
+	Requires Requirement
+	// Untrusted tools return content written by change authors, reviewers
+	// or CI, such as patches, files, comments and logs, which may try to
+	// steer the model reading it
+	Untrusted bool
+	// OptIn tools can lock users out or hand out credentials, so they are
+	// only served when named, or their category is, in SetOptIn
+	OptIn bool
+}
+
+// optIn reports whether t is only served when opted in. Admin tools always
+// are: they change who may do what on the whole server.
+func (t Tool) optIn() bool {
+	return t.OptIn || t.Category == CategoryAdmin
+}
+
+// Registry holds every known tool and decides which of them are served,
+// based on allow and deny lists of tool names or categories
+type Registry struct {
+	mu          sync.RWMutex
+	tools       []Tool
+	enabled     map[string]bool
+	disabled    map[string]bool
+	optIn       map[string]bool
+	caps        *Capabilities
+	middlewares []Middleware
+}
+
+// NewRegistry creates a registry serving all of the given tools
+func NewRegistry(tools ...Tool) *Registry {
+	r := &Registry{}
+	r.Register(tools...)
+	return r
+}
+
+// Register adds tools to the registry. A tool replaces an earlier one with the same name.
+func (r *Registry) Register(tools ...Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.register(tools)
+}
+
+// register adds tools, replacing those with the same name; callers hold r.mu
+func (r *Registry) register(tools []Tool) {
+	for _, t := range tools {
+		replaced := false
+		for i := range r.tools {
+			if r.tools[i].Tool.Name == t.Tool.Name {
+				r.tools[i] = t
+				replaced = true
+			}
+		}
+		if !replaced {
+			r.tools = append(r.tools, t)
+		}
+	}
+}
+
+// SetTools replaces all tools of the registry, e.g. after a configuration
+// reload changed which tools exist. The filter and middlewares are kept.
+// Readers see either the old tools or the new ones, never an empty list.
+func (r *Registry) SetTools(tools ...Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tools = nil
+	r.register(tools)
+}
+
+// SetFilter restricts the served tools. A tool is served if enabled is empty
+// or names it or its category, and disabled names neither. The entries that
+// match no tool or category are returned so that typos can be reported.
+func (r *Registry) SetFilter(enabled, disabled []string) (unknown []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.enabled = r.nameSet(enabled, &unknown)
+	r.disabled = r.nameSet(disabled, &unknown)
+	sort.Strings(unknown)
+	return unknown
+}
+
+// SetOptIn turns on the opt-in tools named, by tool name or category, in
+// names; they are still subject to the filter. Entries that match no tool or
+// category are returned.
+func (r *Registry) SetOptIn(names []string) (unknown []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.optIn = r.nameSet(names, &unknown)
+	sort.Strings(unknown)
+	return unknown
+}
+
+// nameSet turns a list of tool names and categories into a set, adding the
+// entries that match no tool or category to unknown; callers hold r.mu
+func (r *Registry) nameSet(names []string, unknown *[]string) map[string]bool {
+	known := map[string]bool{CategoryRead: true, CategoryWrite: true, CategoryAdmin: true}
+	for _, t := range r.tools {
+		known[t.Tool.Name] = true
+		known[t.Category] = true
+	}
+
+	set := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			*unknown = append(*unknown, name)
+		}
+		set[name] = true
+	}
+	return set
+}
+
+// SetCapabilities hides the tools whose requirements caps doesn't meet
+func (r *Registry) SetCapabilities(caps *Capabilities) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.caps = caps
+}
+
+// Use appends middlewares wrapping every served tool, outermost first
+func (r *Registry) Use(mws ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middlewares = append(r.middlewares, mws...)
+}
+
+// allows reports whether t passes the filter and the server supports it;
+// callers hold r.mu
+func (r *Registry) allows(t Tool) bool {
+	if ok, _ := r.caps.Supports(t.Requires); !ok {
+		return false
+	}
+	if r.disabled[t.Tool.Name] || r.disabled[t.Category] {
+		return false
+	}
+	if t.optIn() && !r.optIn[t.Tool.Name] && !r.optIn[t.Category] {
+		return false
+	}
+	return len(r.enabled) == 0 || r.enabled[t.Tool.Name] || r.enabled[t.Category]
+}
+
+// ServerTools returns the tools to advertise and serve, with their handlers
+// wrapped in the registered middlewares
+func (r *Registry) ServerTools() []server.ServerTool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pipeline := Chain(r.middlewares...)
+	var tools []server.ServerTool
+	for _, t := range r.tools {
+		if r.allows(t) {
+			tools = append(tools, server.ServerTool{Tool: t.Tool, Handler: pipeline(t, t.Handler)})
+		}
+	}
+	return tools
+}
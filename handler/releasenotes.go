@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxReleaseNoteChanges bounds the merged changes collected for release notes
+const maxReleaseNoteChanges = 500
+
+// gerritCommitTime is the format of timestamps in Gerrit's REST responses
+const gerritCommitTime = "2006-01-02 15:04:05.000000000"
+
+// subjectPrefixRegexp matches a conventional "area: summary" subject
+var subjectPrefixRegexp = regexp.MustCompile(`^([\w./-]+(?:\([\w./-]+\))?)!?:\s+(.+)$`)
+
+// releaseNote is one merged change in the release notes
+type releaseNote struct {
+	Number  int    `json:"number"`
+	Subject string `json:"subject"`
+	Owner   string `json:"owner"`
+	URL     string `json:"url,omitempty"`
+}
+
+// releaseNoteGroup is a section of the release notes
+type releaseNoteGroup struct {
+	Name    string        `json:"name"`
+	Changes []releaseNote `json:"changes"`
+}
+
+// resolveReleaseBound turns a tag, a branch or a time into the time a
+// release was cut. Tags and branches are dated by the commit they point at.
+func (h *Handler) resolveReleaseBound(ctx context.Context, project, value string, now time.Time) (time.Time, error) {
+	var ref struct {
+		Revision string `json:"revision"`
+		// Object is the commit an annotated tag points at
+		Object string `json:"object"`
+	}
+	commit := ""
+	for _, kind := range []string{"tags", "branches"} {
+		path := fmt.Sprintf("projects/%s/%s/%s", url.PathEscape(project), kind, url.PathEscape(value))
+		if _, err := h.client.Call(ctx, http.MethodGet, path, nil, &ref); err == nil {
+			commit = ref.Object
+			if commit == "" {
+				commit = ref.Revision
+			}
+			break
+		}
+	}
+	if commit == "" {
+		t, err := parseTimeBound(value, now)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q is neither a tag nor a branch of %s, nor a time", value, project)
+		}
+		return t, nil
+	}
+
+	var info struct {
+		Committer struct {
+			Date string `json:"date"`
+		} `json:"committer"`
+	}
+	if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("projects/%s/commits/%s", url.PathEscape(project), commit), nil, &info); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get commit %s of %s: %v", commit, value, err)
+	}
+	t, err := time.Parse(gerritCommitTime, info.Committer.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("commit %s of %s has an invalid date %q", commit, value, info.Committer.Date)
+	}
+	return t, nil
+}
+
+// releaseNoteGroups returns the sections a merged change belongs in
+func releaseNoteGroups(change gerrit.ChangeInfo, groupBy string) (groups []string, subject string) {
+	subject = change.Subject
+	switch groupBy {
+	case "topic":
+		if change.Topic != "" {
+			return []string{change.Topic}, subject
+		}
+	case "hashtag":
+		if len(change.Hashtags) > 0 {
+			return change.Hashtags, subject
+		}
+	default:
+		if m := subjectPrefixRegexp.FindStringSubmatch(change.Subject); m != nil {
+			return []string{m[1]}, m[2]
+		}
+	}
+	return []string{"Other"}, subject
+}
+
+// GetGerritReleaseNotes drafts release notes from the changes merged into a
+// project between two tags, branches or times, grouped by topic, hashtag or
+// subject prefix
+func (h *Handler) GetGerritReleaseNotes(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	project, err := request.RequireString("project")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	from, err := request.RequireString("from")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	to := request.GetString("to", "")
+	groupBy := request.GetString("group_by", "prefix")
+	if groupBy != "prefix" && groupBy != "topic" && groupBy != "hashtag" {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown group_by %q, expected prefix, topic or hashtag", groupBy)), nil
+	}
+	format := request.GetString("format", "markdown")
+	if format != "markdown" && format != "json" {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown format %q, expected markdown or json", format)), nil
+	}
+
+	now := time.Now()
+	since, err := h.resolveReleaseBound(ctx, project, from, now)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	until := now
+	if to != "" {
+		if until, err = h.resolveReleaseBound(ctx, project, to, now); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	if !since.Before(until) {
+		return mcp.NewToolResultError(fmt.Sprintf("%s must be before %s", from, cmp.Or(to, "now"))), nil
+	}
+
+	terms := []string{"status:merged", "project:" + project,
+		fmt.Sprintf(`mergedafter:"%s"`, since.UTC().Format(gerritQueryTime)),
+		fmt.Sprintf(`mergedbefore:"%s"`, until.UTC().Format(gerritQueryTime))}
+	if branch := request.GetString("branch", ""); branch != "" {
+		terms = append(terms, "branch:"+branch)
+	}
+	query := strings.Join(terms, " ")
+	changes, resp, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{query}, Limit: maxReleaseNoteChanges},
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: []string{"DETAILED_ACCOUNTS"}},
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to query merged changes: %v", err)), nil
+	}
+
+	byName := make(map[string]*releaseNoteGroup)
+	for _, change := range *changes {
+		names, subject := releaseNoteGroups(change, groupBy)
+		for _, name := range names {
+			group, ok := byName[name]
+			if !ok {
+				group = &releaseNoteGroup{Name: name}
+				byName[name] = group
+			}
+			group.Changes = append(group.Changes, releaseNote{
+				Number:  change.Number,
+				Subject: subject,
+				Owner:   formatAccount(change.Owner),
+				URL:     h.canonicalChangeURL(&change, resp),
+			})
+		}
+	}
+	// Named groups alphabetically, the catch-all last
+	groups := make([]releaseNoteGroup, 0, len(byName))
+	for _, name := range sortedKeys(byName) {
+		if name != "Other" {
+			groups = append(groups, *byName[name])
+		}
+	}
+	if other, ok := byName["Other"]; ok {
+		groups = append(groups, *other)
+	}
+	for _, g := range groups {
+		sort.Slice(g.Changes, func(i, j int) bool { return g.Changes[i].Number < g.Changes[j].Number })
+	}
+	more := len(*changes) > 0 && (*changes)[len(*changes)-1].MoreChanges
+
+	title := fmt.Sprintf("%s..%s", from, cmp.Or(to, "now"))
+	if format == "json" {
+		data, err := json.MarshalIndent(struct {
+			Project  string             `json:"project"`
+			Range    string             `json:"range"`
+			Groups   []releaseNoteGroup `json:"groups"`
+			Complete bool               `json:"complete"`
+		}{project, title, groups, !more}, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to encode release notes: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Release notes for %s %s\n", project, title)
+	if len(groups) == 0 {
+		fmt.Fprintf(&b, "\nNo changes were merged between %s and %s.\n", since.UTC().Format("2006-01-02 15:04"), until.UTC().Format("2006-01-02 15:04"))
+	}
+	for _, g := range groups {
+		fmt.Fprintf(&b, "\n## %s\n\n", g.Name)
+		for _, note := range g.Changes {
+			if note.URL != "" {
+				fmt.Fprintf(&b, "- %s ([%d](%s))\n", note.Subject, note.Number, note.URL)
+			} else {
+				fmt.Fprintf(&b, "- %s (%d)\n", note.Subject, note.Number)
+			}
+		}
+	}
+	if more {
+		fmt.Fprintf(&b, "\nWARNING: only the first %d merged changes are included; narrow the range to see the rest\n", maxReleaseNoteChanges)
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+)
+
+var (
+	replayChangePathRegexp = regexp.MustCompile(`^changes/(\d+)(?:/(comments))?(?:\?.*)?$`)
+	replayQueryTermRegexp  = regexp.MustCompile(`^(project|branch|status|change):(.+)$`)
+)
+
+// ReplayClient is a GerritClient serving changes from bundles written by
+// export-gerrit-change instead of a live Gerrit, for tests, demos and
+// air-gapped environments. Anything a bundle doesn't hold is reported as not
+// found, and nothing can be written.
+type ReplayClient struct {
+	bundles map[string]changeExport
+	source  string
+}
+
+// LoadReplayClient reads the bundle at path, or every *.json bundle in the
+// directory at path
+func LoadReplayClient(path string) (*ReplayClient, error) {
+	files := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &ReplayClient{bundles: make(map[string]changeExport), source: path}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var bundle changeExport
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		if bundle.SchemaVersion != exportSchemaVersion {
+			return nil, fmt.Errorf("%s: unsupported schema_version %d, expected %d", file, bundle.SchemaVersion, exportSchemaVersion)
+		}
+		var change struct {
+			Number int `json:"_number"`
+		}
+		if err := json.Unmarshal(bundle.Change, &change); err != nil || change.Number == 0 {
+			return nil, fmt.Errorf("%s: the bundle holds no change", file)
+		}
+		c.bundles[strconv.Itoa(change.Number)] = bundle
+	}
+	if len(c.bundles) == 0 {
+		return nil, fmt.Errorf("no change bundles found in %s", path)
+	}
+	return c, nil
+}
+
+// Status reports the replay as a read-only connection
+func (c *ReplayClient) Status(ctx context.Context) ConnectionStatus {
+	return ConnectionStatus{
+		State:    "replaying",
+		AuthMode: "anonymous",
+		ReadOnly: true,
+		Warnings: []string{fmt.Sprintf("serving %s exported to %s instead of a live Gerrit", plural(len(c.bundles), "change"), c.source)},
+	}
+}
+
+// replayNotFound is the response to a request the bundles can't answer
+func replayNotFound(what string) (*gerrit.Response, error) {
+	return &gerrit.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}, fmt.Errorf("%s is not available offline", what)
+}
+
+// change decodes the ChangeInfo of a bundle
+func (c *ReplayClient) change(changeID string) (*gerrit.ChangeInfo, *gerrit.Response, error) {
+	bundle, ok := c.bundles[changeID]
+	if !ok {
+		resp, err := replayNotFound("change " + changeID)
+		return nil, resp, err
+	}
+	var change gerrit.ChangeInfo
+	if err := json.Unmarshal(bundle.Change, &change); err != nil {
+		return nil, nil, fmt.Errorf("decode change %s: %w", changeID, err)
+	}
+	return &change, nil, nil
+}
+
+// GetChange implements GerritClient interface; the bundle holds every field,
+// whichever were asked for
+func (c *ReplayClient) GetChange(ctx context.Context, changeID string, opt *gerrit.ChangeOptions) (*gerrit.ChangeInfo, *gerrit.Response, error) {
+	return c.change(changeID)
+}
+
+// GetPatch implements GerritClient interface
+func (c *ReplayClient) GetPatch(ctx context.Context, changeID, revisionID string, opt *gerrit.PatchOptions) (*string, *gerrit.Response, error) {
+	patch, ok := c.bundles[changeID].Patches[revisionID]
+	if !ok || opt != nil && (opt.Zip || opt.Path != "") {
+		resp, err := replayNotFound(fmt.Sprintf("the patch of change %s revision %s", changeID, revisionID))
+		return nil, resp, err
+	}
+	return &patch, nil, nil
+}
+
+// QueryChanges implements GerritClient interface. Only project:, branch:,
+// status: and change: terms and change numbers are understood; other terms
+// are ignored.
+func (c *ReplayClient) QueryChanges(ctx context.Context, opt *gerrit.QueryChangeOptions) (*[]gerrit.ChangeInfo, *gerrit.Response, error) {
+	var terms []string
+	for _, q := range opt.Query {
+		terms = append(terms, strings.Fields(q)...)
+	}
+
+	var changes []gerrit.ChangeInfo
+	for id := range c.bundles {
+		change, _, err := c.change(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if replayMatches(change, terms) {
+			changes = append(changes, *change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Number > changes[j].Number })
+
+	from := min(opt.Start, len(changes))
+	to := len(changes)
+	if opt.Limit > 0 {
+		to = min(from+opt.Limit, to)
+	}
+	more := to < len(changes)
+	changes = changes[from:to]
+	if more {
+		changes[len(changes)-1].MoreChanges = true
+	}
+	return &changes, nil, nil
+}
+
+// replayMatches applies the query terms a replay understands to a change
+func replayMatches(change *gerrit.ChangeInfo, terms []string) bool {
+	for _, term := range terms {
+		if _, err := strconv.Atoi(term); err == nil {
+			term = "change:" + term
+		}
+		m := replayQueryTermRegexp.FindStringSubmatch(term)
+		if m == nil {
+			continue
+		}
+		var value string
+		switch m[1] {
+		case "project":
+			value = change.Project
+		case "branch":
+			value = change.Branch
+		case "status":
+			// Gerrit's "open" covers the NEW status
+			value = strings.ToLower(change.Status)
+			if value == "new" && m[2] == "open" {
+				value = "open"
+			}
+		case "change":
+			value = strconv.Itoa(change.Number)
+		}
+		if !strings.EqualFold(value, m[2]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetReview implements GerritClient interface; bundles are read-only
+func (c *ReplayClient) SetReview(ctx context.Context, changeID, revisionID string, input *gerrit.ReviewInput) (*gerrit.ReviewResult, *gerrit.Response, error) {
+	resp, err := replayNotFound("posting a review")
+	return nil, resp, err
+}
+
+// Call implements GerritClient interface for reading changes and their comments
+func (c *ReplayClient) Call(ctx context.Context, method, path string, body, v any) (*gerrit.Response, error) {
+	m := replayChangePathRegexp.FindStringSubmatch(path)
+	if method != http.MethodGet || m == nil {
+		return replayNotFound(method + " " + path)
+	}
+	bundle, ok := c.bundles[m[1]]
+	if !ok {
+		return replayNotFound("change " + m[1])
+	}
+
+	data := []byte(bundle.Change)
+	if m[2] == "comments" {
+		data = bundle.Comments
+		if len(data) == 0 {
+			data = []byte("{}")
+		}
+	}
+	if w, ok := v.(io.Writer); ok {
+		_, err := w.Write(data)
+		return nil, err
+	}
+	return nil, json.Unmarshal(data, v)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Limits for the quote of the parent comment in a reply
+const (
+	maxQuoteLines = 6
+	maxQuoteRunes = 400
+)
+
+// quoteComment quotes a comment the way Gerrit does ("> " per line),
+// shortening long comments to their beginning
+func quoteComment(message string) string {
+	message = strings.TrimSpace(message)
+	truncated := false
+	if r := []rune(message); len(r) > maxQuoteRunes {
+		message = strings.TrimSpace(string(r[:maxQuoteRunes]))
+		truncated = true
+	}
+	lines := strings.Split(message, "\n")
+	if len(lines) > maxQuoteLines {
+		lines = lines[:maxQuoteLines]
+		truncated = true
+	}
+	if truncated {
+		lines = append(lines, "[...]")
+	}
+	for i, l := range lines {
+		lines[i] = strings.TrimRight("> "+l, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatGerritCommentReply drafts a reply to a comment following Gerrit
+// conventions: the parent is quoted, and the reply is attached to the end of
+// its thread so that the conversation stays in one place
+func (h *Handler) FormatGerritCommentReply(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	commentID, err := request.RequireString("comment_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	reply, err := request.RequireString("reply")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	threads, warnings, err := h.commentThreads(ctx, changeID, change, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	header = append(header, warnings...)
+
+	var thread *commentThread
+	var parent commentInfo
+	for _, t := range threads {
+		for _, c := range t.comments {
+			if c.ID == commentID {
+				thread, parent = t, c
+			}
+		}
+	}
+	if thread == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("comment %s not found on change %s", commentID, changeID)), nil
+	}
+	last := thread.comments[len(thread.comments)-1]
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Reply to %s in thread %s (%s):\n\n", formatAccount(parent.Author), thread.root().ID, thread.anchor(change.Revisions[change.CurrentRevision].Number))
+	b.WriteString(quoteComment(parent.Message) + "\n\n")
+	b.WriteString(strings.TrimSpace(reply) + "\n\n")
+
+	b.WriteString("Post it as a comment with:\n")
+	fmt.Fprintf(&b, "path: %s\n", thread.path)
+	fmt.Fprintf(&b, "patch_set: %d\n", thread.root().PatchSet)
+	if thread.root().Line > 0 {
+		fmt.Fprintf(&b, "line: %d\n", thread.root().Line)
+	}
+	fmt.Fprintf(&b, "in_reply_to: %s\n", last.ID)
+	fmt.Fprintf(&b, "unresolved: %t", !request.GetBool("resolve", false))
+	if last.ID != parent.ID {
+		fmt.Fprintf(&b, "\n\nNOTE: comment %s is not the latest in its thread; the reply is attached after %s by %s", parent.ID, last.ID, formatAccount(last.Author))
+	}
+	return mcp.NewToolResultText(b.String()), nil
+}
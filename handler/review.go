@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// checkVotes verifies that the user may cast votes on a change, so that a
+// mistyped label is reported with the labels that exist rather than by a
+// bare error from Gerrit. The change must have been fetched with
+// DETAILED_LABELS.
+func checkVotes(change *gerrit.ChangeInfo, votes map[string]int) error {
+	for _, label := range sortedKeys(votes) {
+		if _, ok := change.Labels[label]; !ok {
+			return fmt.Errorf("change %d has no label %s; its labels are %s", change.Number, label, strings.Join(sortedKeys(change.Labels), ", "))
+		}
+		permitted, ok := change.PermittedLabels[label]
+		if !ok {
+			return fmt.Errorf("you may not vote on %s on change %d", label, change.Number)
+		}
+		allowed := false
+		values := make([]string, 0, len(permitted))
+		for _, value := range permitted {
+			value = strings.TrimSpace(value)
+			if n, err := strconv.Atoi(value); err == nil && n == votes[label] {
+				allowed = true
+			}
+			values = append(values, value)
+		}
+		if !allowed {
+			return fmt.Errorf("you may not vote %s%+d on change %d; permitted: %s", label, votes[label], change.Number, strings.Join(values, ", "))
+		}
+	}
+	return nil
+}
+
+// PostGerritReview votes on the labels of a change and posts a review
+// message on its current patchset, or posts a message on an earlier one
+func (h *Handler) PostGerritReview(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	message := strings.TrimSpace(request.GetString("message", ""))
+	votes, err := parseVotes(request.GetString("labels", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if message == "" && len(votes) == 0 {
+		return mcp.NewToolResultError("a review needs a message, labels or both"), nil
+	}
+	patchSet := request.GetInt("patchset", 0)
+	if patchSet < 0 {
+		return mcp.NewToolResultError("patchset must be positive"), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "ALL_REVISIONS", "DETAILED_LABELS")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	latest := change.Revisions[change.CurrentRevision].Number
+	revision := change.CurrentRevision
+	if patchSet > 0 && patchSet != latest {
+		view, err := atPatchSet(change, patchSet)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		// Gerrit only accepts votes on the current patchset
+		if len(votes) > 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("votes can only be cast on the current patch set %d; post a message alone on patch set %d", latest, patchSet)), nil
+		}
+		revision = view.CurrentRevision
+	}
+	if patchSet == 0 {
+		patchSet = latest
+	}
+	if err := checkVotes(change, votes); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	input := &gerrit.ReviewInput{Message: message, Labels: votes}
+	result, _, err := h.client.SetReview(ctx, changeID, revision, input)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to post review on change %s: %v", changeID, err)), nil
+	}
+	if result != nil && result.Error != "" {
+		return mcp.NewToolResultError(fmt.Sprintf("Gerrit refused the review on change %s: %s", changeID, result.Error)), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Posted review on patch set %d of change %s", patchSet, changeID)
+	if patchSet != latest {
+		fmt.Fprintf(&b, " (the current patch set is %d)", latest)
+	}
+	for _, label := range sortedKeys(votes) {
+		fmt.Fprintf(&b, "\n%s: %+d", label, votes[label])
+	}
+	if message != "" {
+		fmt.Fprintf(&b, "\nMessage:\n%s", message)
+	}
+	return mcp.NewToolResultText(b.String()), nil
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// reviewerInput is Gerrit's ReviewerInput
+type reviewerInput struct {
+	Reviewer  string `json:"reviewer"`
+	State     string `json:"state,omitempty"`
+	Confirmed bool   `json:"confirmed,omitempty"`
+}
+
+// reviewerResult is Gerrit's ReviewerResult
+type reviewerResult struct {
+	Reviewers []gerrit.AccountInfo `json:"reviewers"`
+	CCs       []gerrit.AccountInfo `json:"ccs"`
+	Error     string               `json:"error"`
+	// Confirm is set when the reviewer is a group big enough that Gerrit
+	// wants the addition confirmed
+	Confirm bool `json:"confirm"`
+}
+
+// groupMembers lists the accounts of a group, including those of its
+// subgroups. ok is false if there is no such group.
+func (h *Handler) groupMembers(ctx context.Context, group string) (members []gerrit.AccountInfo, ok bool, err error) {
+	resp, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("groups/%s/members/?recursive", url.PathEscape(group)), nil, &members)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	return members, err == nil, err
+}
+
+// AddGerritReviewer adds an account or group as reviewer or CC of a change.
+// Groups can be expanded first to see who would be notified.
+func (h *Handler) AddGerritReviewer(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	reviewer, err := request.RequireString("reviewer")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	state := request.GetString("state", "REVIEWER")
+	if state != "REVIEWER" && state != "CC" {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown state %q, expected REVIEWER or CC", state)), nil
+	}
+
+	changeID, _, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+
+	// Look before leaping: list who a group would notify without adding it
+	if request.GetBool("expand_group", false) {
+		members, ok, err := h.groupMembers(ctx, reviewer)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to list members of group %s: %v", reviewer, err)), nil
+		}
+		if !ok {
+			fmt.Fprintf(&b, "%s is not a group; call again without expand_group to add it", reviewer)
+			return mcp.NewToolResultText(b.String()), nil
+		}
+		sort.Slice(members, func(i, j int) bool { return formatAccount(members[i]) < formatAccount(members[j]) })
+		fmt.Fprintf(&b, "Adding group %s to change %s would notify %s:\n", reviewer, changeID, plural(len(members), "account"))
+		for _, m := range members {
+			fmt.Fprintf(&b, "  %s\n", formatAccount(m))
+		}
+		b.WriteString("\nNothing was added. Call again with confirmed=true to add the group.")
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	input := reviewerInput{Reviewer: reviewer, State: state, Confirmed: request.GetBool("confirmed", false)}
+	var result reviewerResult
+	if _, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/reviewers", changeID), input, &result); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to add %s to change %s: %v", reviewer, changeID, err)), nil
+	}
+	if result.Confirm {
+		fmt.Fprintf(&b, "Gerrit asks for confirmation: %s\n", result.Error)
+		b.WriteString("Nothing was added. Call again with expand_group=true to see who would be notified, or with confirmed=true to add the group.")
+		return mcp.NewToolResultText(b.String()), nil
+	}
+	if result.Error != "" {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to add %s to change %s: %s", reviewer, changeID, result.Error)), nil
+	}
+
+	added := append(result.Reviewers, result.CCs...)
+	fmt.Fprintf(&b, "Added %s to change %s as %s:\n", plural(len(added), "account"), changeID, strings.ToLower(state))
+	for _, a := range added {
+		fmt.Fprintf(&b, "  %s\n", formatAccount(a))
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
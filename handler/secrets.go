@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// WithSecretScanning enables flagging likely credentials added by a patch
+func WithSecretScanning(enabled bool) Option {
+	return func(h *Handler) {
+		h.scanSecrets = enabled
+	}
+}
+
+// secretRule recognises one kind of credential
+type secretRule struct {
+	name string
+	re   *regexp.Regexp
+	// minEntropy, if set, is the Shannon entropy in bits per character the
+	// first capture group must reach, to tell real secrets from placeholders
+	minEntropy float64
+}
+
+var secretRules = []secretRule{
+	{name: "private key", re: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY( BLOCK)?-----`)},
+	{name: "AWS access key ID", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "GitHub token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{name: "GitLab token", re: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{name: "Slack token", re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{name: "Google API key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{name: "JSON web token", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{name: "credentials in URL", re: regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:([^/\s:@]{6,})@`), minEntropy: 3},
+	{
+		name:       "hard-coded secret",
+		re:         regexp.MustCompile(`(?i)(?:password|passwd|secret|token|api_?key|access_?key|auth)[a-z_]*["']?\s*[:=]+\s*["']([^"'\s]{16,})["']`),
+		minEntropy: 3.5,
+	},
+}
+
+// SecretFinding is a likely credential added by a patch
+type SecretFinding struct {
+	File string
+	Line int
+	Rule string
+}
+
+// ScanSecrets looks for likely credentials on the lines a patch adds. Removed
+// lines are ignored: taking a secret out is what the reviewer wants to see.
+func ScanSecrets(files []FileDiff) []SecretFinding {
+	var findings []SecretFinding
+	for _, f := range files {
+		for _, hunk := range f.Hunks {
+			line := hunk.NewStart
+			for _, l := range hunk.Lines {
+				switch {
+				case strings.HasPrefix(l, "+"):
+					if rule := matchSecret(l[1:]); rule != "" {
+						findings = append(findings, SecretFinding{File: f.Path(), Line: line, Rule: rule})
+					}
+					line++
+				case strings.HasPrefix(l, " "):
+					line++
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// matchSecret returns the name of the first rule matching line, if any
+func matchSecret(line string) string {
+	for _, rule := range secretRules {
+		m := rule.re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if rule.minEntropy > 0 && (len(m) < 2 || shannonEntropy(m[1]) < rule.minEntropy) {
+			continue
+		}
+		return rule.name
+	}
+	return ""
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var entropy float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// patchSecretWarnings scans patch if enabled and returns a warning line per finding
+func (h *Handler) patchSecretWarnings(patch string) []string {
+	if !h.scanSecrets {
+		return nil
+	}
+	return secretWarnings(ScanSecrets(ParsePatch(patch)))
+}
+
+// secretWarnings returns a warning line per finding. The secrets themselves
+// are not repeated, so that they don't spread further.
+func secretWarnings(findings []SecretFinding) []string {
+	var warnings []string
+	for _, f := range findings {
+		warnings = append(warnings, fmt.Sprintf("WARNING: possible %s added at %s:%d; make sure no credential is being committed", f.Rule, f.File, f.Line))
+	}
+	return warnings
+}
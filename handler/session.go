@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxSessions bounds the sessions whose state is kept; the least recently
+// used one is dropped first, for transports that don't report session ends
+const maxSessions = 1000
+
+// SessionStore keeps state that belongs to one MCP session, such as
+// pagination cursors, so that concurrent sessions served over the network
+// transports never see each other's state
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*SessionState
+}
+
+// SessionState is the state of one session. It is safe for concurrent use
+// by the tool calls of the session.
+type SessionState struct {
+	mu       sync.Mutex
+	values   map[string]any
+	lastUsed time.Time
+}
+
+// NewSessionStore creates an empty SessionStore
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[string]*SessionState)}
+}
+
+// sessionID identifies the session of a tool call; calls outside a session,
+// such as those of the run subcommand, share the empty ID
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// State returns the state of the session of a tool call, creating it on first use
+func (s *SessionStore) State(ctx context.Context) *SessionState {
+	id := sessionID(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.sessions[id]
+	if !ok {
+		if len(s.sessions) >= maxSessions {
+			s.evictLocked()
+		}
+		state = &SessionState{values: make(map[string]any)}
+		s.sessions[id] = state
+	}
+	state.mu.Lock()
+	state.lastUsed = time.Now()
+	state.mu.Unlock()
+	return state
+}
+
+// evictLocked drops the least recently used session
+func (s *SessionStore) evictLocked() {
+	var oldestID string
+	var oldest time.Time
+	for id, state := range s.sessions {
+		state.mu.Lock()
+		used := state.lastUsed
+		state.mu.Unlock()
+		if oldest.IsZero() || used.Before(oldest) {
+			oldestID, oldest = id, used
+		}
+	}
+	delete(s.sessions, oldestID)
+}
+
+// OnUnregisterSession is a server hook dropping the state of ended sessions
+func (s *SessionStore) OnUnregisterSession(ctx context.Context, session server.ClientSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, session.SessionID())
+}
+
+// Load returns the value stored under key
+func (st *SessionState) Load(key string) (any, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	v, ok := st.values[key]
+	return v, ok
+}
+
+// Store sets the value stored under key
+func (st *SessionState) Store(key string, v any) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.values[key] = v
+}
+
+// Delete removes the value stored under key
+func (st *SessionState) Delete(key string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.values, key)
+}
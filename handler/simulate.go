@@ -0,0 +1,604 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var (
+	// voteArgRegexp matches a vote argument such as "Code-Review+2" or "Verified=-1"
+	voteArgRegexp = regexp.MustCompile(`^([\w-]+?)\s*=?\s*([+-]?\d+)$`)
+	// labelAtomRegexp matches the label atoms of a submit requirement, such
+	// as "label:Code-Review=MAX,user=non_uploader" or "label:Verified+1"
+	labelAtomRegexp = regexp.MustCompile(`(?i)^label:([\w-]+?)(>=|<=|=|>|<)?([+-]?\d+|MAX|MIN|ANY)((?:,[\w=]+)*)$`)
+)
+
+// parseVotes parses a comma-separated list of votes
+func parseVotes(list string) (map[string]int, error) {
+	votes := make(map[string]int)
+	for _, vote := range splitList(list) {
+		m := voteArgRegexp.FindStringSubmatch(vote)
+		if m == nil {
+			return nil, fmt.Errorf("%q is not a vote such as Code-Review+2", vote)
+		}
+		value, _ := strconv.Atoi(m[2])
+		votes[m[1]] = value
+	}
+	return votes, nil
+}
+
+// labelRange returns the lowest and highest values of a label
+func labelRange(label gerrit.LabelInfo) (low, high int) {
+	first := true
+	for key := range label.Values {
+		value, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil {
+			continue
+		}
+		if first || value < low {
+			low = value
+		}
+		if first || value > high {
+			high = value
+		}
+		first = false
+	}
+	return low, high
+}
+
+// labelAtom is a parsed label atom of a submit requirement expression
+type labelAtom struct {
+	label, op, value string
+	nonUploader      bool
+}
+
+// parseLabelAtom parses a label atom, reporting false for any other atom and
+// for label atoms whose arguments can't be simulated
+func parseLabelAtom(atom string) (labelAtom, bool) {
+	m := labelAtomRegexp.FindStringSubmatch(atom)
+	if m == nil {
+		return labelAtom{}, false
+	}
+	a := labelAtom{label: m[1], op: m[2], value: strings.ToUpper(m[3])}
+	if a.op == "" {
+		a.op = "="
+	}
+	if a.op == "=" && (a.value == "0" || a.value == "+0" || a.value == "-0") {
+		// Also matches changes nobody voted on
+		return labelAtom{}, false
+	}
+	for arg := range strings.SplitSeq(strings.TrimPrefix(m[4], ","), ",") {
+		switch strings.ToLower(arg) {
+		case "":
+		case "user=non_uploader", "user=non_contributor":
+			a.nonUploader = true
+		default:
+			return labelAtom{}, false
+		}
+	}
+	return a, true
+}
+
+// submitExpr is a node of a parsed submit requirement expression
+type submitExpr struct {
+	// op is "and", "or", "not" or "atom"
+	op       string
+	atom     string
+	children []*submitExpr
+}
+
+// tokenizeSubmitExpr splits an expression into parentheses, operators and
+// atoms, keeping quoted values within their atom
+func tokenizeSubmitExpr(expr string) []string {
+	var tokens []string
+	var token strings.Builder
+	quoted := false
+	flush := func() {
+		if token.Len() > 0 {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+	}
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			token.WriteRune(r)
+		case quoted:
+			token.WriteRune(r)
+		case r == '(' || r == ')':
+			if r == '(' && token.String() == "-" {
+				// A negated group
+				token.Reset()
+				tokens = append(tokens, "NOT")
+			}
+			flush()
+			tokens = append(tokens, string(r))
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		default:
+			token.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// parseSubmitExpr parses a submit requirement expression in Gerrit's query
+// syntax: atoms combined with AND, OR, NOT, "-" and parentheses, where
+// adjacent terms are joined by AND
+func parseSubmitExpr(expr string) (*submitExpr, error) {
+	p := &submitExprParser{tokens: tokenizeSubmitExpr(expr)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos], expr)
+	}
+	return node, nil
+}
+
+type submitExprParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *submitExprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *submitExprParser) or() (*submitExpr, error) {
+	node, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "OR" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		node = &submitExpr{op: "or", children: []*submitExpr{node, right}}
+	}
+	return node, nil
+}
+
+func (p *submitExprParser) and() (*submitExpr, error) {
+	node, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "", ")", "OR":
+			return node, nil
+		case "AND":
+			p.pos++
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		node = &submitExpr{op: "and", children: []*submitExpr{node, right}}
+	}
+}
+
+func (p *submitExprParser) unary() (*submitExpr, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "NOT":
+		p.pos++
+		child, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &submitExpr{op: "not", children: []*submitExpr{child}}, nil
+	case token == "(":
+		p.pos++
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	case token == ")" || token == "AND" || token == "OR":
+		return nil, fmt.Errorf("unexpected %q", token)
+	case strings.HasPrefix(token, "-") && len(token) > 1:
+		p.pos++
+		return &submitExpr{op: "not", children: []*submitExpr{{op: "atom", atom: token[1:]}}}, nil
+	default:
+		p.pos++
+		return &submitExpr{op: "atom", atom: token}, nil
+	}
+}
+
+// submitSimulation evaluates submit rules against a set of votes
+type submitSimulation struct {
+	labels map[string]gerrit.LabelInfo
+	// votes holds the votes of each label by account ID
+	votes    map[string]map[int]gerrit.ApprovalInfo
+	uploader int
+	// atoms holds what Gerrit reported for the atoms that don't depend on votes
+	atoms map[string]bool
+}
+
+// newSubmitSimulation takes the current votes on a change and replaces those
+// of voter with the simulated votes
+func newSubmitSimulation(change *gerrit.ChangeInfo, voter gerrit.AccountInfo, simulated map[string]int) *submitSimulation {
+	s := &submitSimulation{
+		labels: change.Labels,
+		votes:  make(map[string]map[int]gerrit.ApprovalInfo),
+		atoms:  make(map[string]bool),
+	}
+	if revision, ok := change.Revisions[change.CurrentRevision]; ok {
+		s.uploader = revision.Uploader.AccountID
+	}
+	for name, label := range change.Labels {
+		s.votes[name] = make(map[int]gerrit.ApprovalInfo)
+		for _, approval := range label.All {
+			if approval.Value != 0 {
+				s.votes[name][approval.AccountID] = approval
+			}
+		}
+	}
+	for name, value := range simulated {
+		if value == 0 {
+			delete(s.votes[name], voter.AccountID)
+		} else {
+			s.votes[name][voter.AccountID] = gerrit.ApprovalInfo{AccountInfo: voter, Value: value}
+		}
+	}
+	return s
+}
+
+// resolve turns MAX and MIN into the values of a label
+func (s *submitSimulation) resolve(label, value string) (int, bool) {
+	low, high := labelRange(s.labels[label])
+	switch value {
+	case "MAX":
+		return high, true
+	case "MIN":
+		return low, true
+	case "ANY":
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	return n, err == nil
+}
+
+// matching returns the votes a label atom matches
+func (s *submitSimulation) matching(a labelAtom) []gerrit.ApprovalInfo {
+	target, ok := s.resolve(a.label, a.value)
+	var matches []gerrit.ApprovalInfo
+	for _, id := range slices.Sorted(maps.Keys(s.votes[a.label])) {
+		approval := s.votes[a.label][id]
+		if a.nonUploader && id == s.uploader {
+			continue
+		}
+		v := approval.Value
+		matched := !ok // ANY
+		if ok {
+			switch a.op {
+			case "=":
+				matched = v == target
+			case ">=":
+				matched = v >= target
+			case "<=":
+				matched = v <= target
+			case ">":
+				matched = v > target
+			case "<":
+				matched = v < target
+			}
+		}
+		if matched {
+			matches = append(matches, approval)
+		}
+	}
+	return matches
+}
+
+// eval evaluates an expression, taking atoms that don't depend on votes as
+// Gerrit reported them
+func (s *submitSimulation) eval(node *submitExpr) bool {
+	switch node.op {
+	case "not":
+		return !s.eval(node.children[0])
+	case "and":
+		return s.eval(node.children[0]) && s.eval(node.children[1])
+	case "or":
+		return s.eval(node.children[0]) || s.eval(node.children[1])
+	}
+	if a, ok := parseLabelAtom(node.atom); ok {
+		return len(s.matching(a)) > 0
+	}
+	return s.atoms[node.atom]
+}
+
+// explain lists what would have to change for an expression to evaluate to
+// want, choosing the alternative needing the fewest changes
+func (s *submitSimulation) explain(node *submitExpr, want bool) []string {
+	if s.eval(node) == want {
+		return nil
+	}
+	switch node.op {
+	case "not":
+		return s.explain(node.children[0], !want)
+	case "and", "or":
+		left, right := s.explain(node.children[0], want), s.explain(node.children[1], want)
+		if (node.op == "and") == want {
+			// Both sides must change
+			return append(left, right...)
+		}
+		// Changing either side is enough
+		if len(right) > 0 && (len(left) == 0 || len(right) < len(left)) {
+			return right
+		}
+		return left
+	}
+	return []string{s.describe(node.atom, want)}
+}
+
+// describe says what would make an atom evaluate to want
+func (s *submitSimulation) describe(atom string, want bool) string {
+	a, ok := parseLabelAtom(atom)
+	if !ok {
+		if want {
+			return fmt.Sprintf("%q to match", atom)
+		}
+		return fmt.Sprintf("%q to no longer match", atom)
+	}
+	if !want {
+		var votes []string
+		for _, approval := range s.matching(a) {
+			votes = append(votes, fmt.Sprintf("the %s%+d of %s", a.label, approval.Value, formatAccount(approval.AccountInfo)))
+		}
+		return strings.Join(votes, " and ") + " to be removed"
+	}
+
+	var vote string
+	target, resolved := s.resolve(a.label, a.value)
+	switch {
+	case !resolved:
+		vote = fmt.Sprintf("any %s vote", a.label)
+	case a.op == "=":
+		vote = fmt.Sprintf("a %s%+d vote", a.label, target)
+	case a.op == ">=" || a.op == ">":
+		if a.op == ">" {
+			target++
+		}
+		vote = fmt.Sprintf("a %s vote of %+d or higher", a.label, target)
+	default:
+		if a.op == "<" {
+			target--
+		}
+		vote = fmt.Sprintf("a %s vote of %+d or lower", a.label, target)
+	}
+	if a.nonUploader {
+		vote += " from someone other than the uploader"
+	}
+	return vote
+}
+
+// requirement evaluates a submit requirement under the simulated votes
+func (s *submitSimulation) requirement(req submitRequirementResult) simulatedRequirement {
+	result := simulatedRequirement{name: req.Name, before: req.Status, status: req.Status}
+	switch req.Status {
+	case "NOT_APPLICABLE", "FORCED", "ERROR":
+		return result
+	}
+	for _, expr := range []*submitRequirementExpression{req.Submittability, req.Override} {
+		if expr == nil {
+			continue
+		}
+		for _, atom := range expr.PassingAtoms {
+			s.atoms[atom] = true
+		}
+		for _, atom := range expr.FailingAtoms {
+			s.atoms[atom] = false
+		}
+	}
+
+	if req.Override != nil && req.Override.Expression != "" {
+		if override, err := parseSubmitExpr(req.Override.Expression); err == nil && s.eval(override) {
+			result.status = "OVERRIDDEN"
+			return result
+		}
+	}
+	if req.Submittability == nil {
+		return result
+	}
+	node, err := parseSubmitExpr(req.Submittability.Expression)
+	if err != nil {
+		result.missing = []string{fmt.Sprintf("can't simulate %q: %v", req.Submittability.Expression, err)}
+		return result
+	}
+	result.status = "SATISFIED"
+	if !s.eval(node) {
+		result.status = "UNSATISFIED"
+		result.missing = s.explain(node, true)
+	}
+	return result
+}
+
+// legacyLabel evaluates a label under the MaxWithBlock function, which
+// servers without submit requirements apply by default
+func (s *submitSimulation) legacyLabel(name string) simulatedRequirement {
+	low, high := labelRange(s.labels[name])
+	result := simulatedRequirement{name: name, status: "SATISFIED"}
+	if low < 0 {
+		if rejected := s.matching(labelAtom{label: name, op: "=", value: strconv.Itoa(low)}); len(rejected) > 0 {
+			result.status = "UNSATISFIED"
+			result.missing = append(result.missing, s.describe(fmt.Sprintf("label:%s=%d", name, low), false))
+		}
+	}
+	if len(s.matching(labelAtom{label: name, op: "=", value: strconv.Itoa(high)})) == 0 {
+		result.status = "UNSATISFIED"
+		result.missing = append(result.missing, s.describe(fmt.Sprintf("label:%s=%d", name, high), true))
+	}
+	return result
+}
+
+// simulatedRequirement is the outcome of a submit requirement under the
+// simulated votes
+type simulatedRequirement struct {
+	name    string
+	before  string
+	status  string
+	missing []string
+}
+
+// SimulateGerritSubmitRequirements evaluates the submit requirements of a
+// change as if the calling user cast some votes, and lists the votes and
+// conditions still missing for the change to become submittable
+func (h *Handler) SimulateGerritSubmitRequirements(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	votes, err := parseVotes(request.GetString("votes", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "DETAILED_LABELS", "DETAILED_ACCOUNTS")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var notes []string
+	for _, name := range sortedKeys(votes) {
+		label, ok := change.Labels[name]
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("change %s has no label %s, available: %s", changeID, name, strings.Join(sortedKeys(change.Labels), ", "))), nil
+		}
+		if low, high := labelRange(label); votes[name] < low || votes[name] > high {
+			return mcp.NewToolResultError(fmt.Sprintf("%s%+d is out of range, %s votes go from %+d to %+d", name, votes[name], name, low, high)), nil
+		}
+		if permitted, ok := change.PermittedLabels[name]; change.PermittedLabels != nil && (!ok || !slices.ContainsFunc(permitted, func(v string) bool {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			return err == nil && n == votes[name]
+		})) {
+			notes = append(notes, fmt.Sprintf("you may not vote %s%+d on this change, so the simulation assumes a vote you can't cast", name, votes[name]))
+		}
+	}
+
+	var voter gerrit.AccountInfo
+	if len(votes) > 0 {
+		if _, err := h.client.Call(ctx, http.MethodGet, "accounts/self", nil, &voter); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get the calling account, simulating your votes requires authentication: %v", err)), nil
+		}
+	}
+	simulation := newSubmitSimulation(change, voter, votes)
+
+	var requirements []simulatedRequirement
+	if h.compat().submitRequirements {
+		var info struct {
+			SubmitRequirements []submitRequirementResult `json:"submit_requirements"`
+		}
+		if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("changes/%s?o=SUBMIT_REQUIREMENTS", changeID), nil, &info); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get the submit requirements of change %s: %v", changeID, err)), nil
+		}
+		for _, req := range info.SubmitRequirements {
+			requirements = append(requirements, simulation.requirement(req))
+		}
+	} else {
+		current := newSubmitSimulation(change, voter, nil)
+		for _, name := range sortedKeys(change.Labels) {
+			if change.Labels[name].Optional {
+				continue
+			}
+			req := simulation.legacyLabel(name)
+			req.before = current.legacyLabel(name).status
+			requirements = append(requirements, req)
+		}
+	}
+
+	switch {
+	case change.Status != "NEW":
+		notes = append(notes, fmt.Sprintf("the change is %s, so it can't be submitted whatever the votes", change.Status))
+	case change.WorkInProgress:
+		notes = append(notes, "the change is work in progress and must be marked ready for review before it can be submitted")
+	}
+
+	var missing, satisfied, other []string
+	for _, req := range requirements {
+		switch req.status {
+		case "SATISFIED", "OVERRIDDEN", "FORCED":
+			line := req.name
+			if req.status != "SATISFIED" {
+				line += " (" + strings.ToLower(req.status) + ")"
+			}
+			if req.before != req.status && req.before != "" {
+				line += ", newly " + strings.ToLower(req.status)
+			}
+			satisfied = append(satisfied, line)
+		case "UNSATISFIED":
+			line := req.name
+			if len(req.missing) > 0 {
+				line += ": needs " + strings.Join(req.missing, ", and ")
+			}
+			missing = append(missing, line)
+		case "NOT_APPLICABLE":
+		default:
+			line := fmt.Sprintf("%s is %s", req.name, strings.ToLower(req.status))
+			if req.status == "ERROR" {
+				line += " and fails to evaluate; ask a project owner to fix it"
+			}
+			other = append(other, line)
+		}
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	if len(votes) == 0 {
+		fmt.Fprintf(&b, "Change %s with its current votes ", changeID)
+	} else {
+		var cast []string
+		for _, name := range sortedKeys(votes) {
+			cast = append(cast, fmt.Sprintf("%s%+d", name, votes[name]))
+		}
+		fmt.Fprintf(&b, "If you (%s) vote %s, change %s ", formatAccount(voter), strings.Join(cast, ", "), changeID)
+	}
+	if len(missing) == 0 && len(other) == 0 && change.Status == "NEW" && !change.WorkInProgress {
+		b.WriteString("would be submittable\n")
+	} else {
+		b.WriteString("would not be submittable yet\n")
+	}
+	for _, section := range []struct {
+		title string
+		items []string
+	}{{"Still missing", missing}, {"Blocked", other}, {"Satisfied", satisfied}, {"Notes", notes}} {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		for _, item := range section.items {
+			fmt.Fprintf(&b, "  - %s\n", item)
+		}
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
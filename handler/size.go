@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+)
+
+// SizeClass names the changes with at most MaxFiles files and MaxLines changed
+// lines. Zero means no limit.
+type SizeClass struct {
+	Name     string `json:"name"`
+	MaxFiles int    `json:"max_files"`
+	MaxLines int    `json:"max_lines"`
+}
+
+// defaultSizeClasses are used when the configuration file defines none
+var defaultSizeClasses = []SizeClass{
+	{Name: "XS", MaxFiles: 2, MaxLines: 10},
+	{Name: "S", MaxFiles: 5, MaxLines: 50},
+	{Name: "M", MaxFiles: 15, MaxLines: 250},
+	{Name: "L", MaxFiles: 40, MaxLines: 1000},
+	{Name: "XL"},
+}
+
+// Review pace assumed by reviewEstimate: reviews slow down markedly beyond
+// a few hundred lines an hour, and every file costs some context switching
+const (
+	reviewLinesPerHour = 400
+	reviewMinutesFile  = 2
+)
+
+// sizeClass returns the first class that files and lines fit into, or the
+// last class if none does. files is zero when the number of files is unknown.
+func (c *Config) sizeClass(files, lines int) string {
+	classes := c.SizeClasses
+	if len(classes) == 0 {
+		classes = defaultSizeClasses
+	}
+	for _, class := range classes {
+		if (class.MaxFiles == 0 || files <= class.MaxFiles) && (class.MaxLines == 0 || lines <= class.MaxLines) {
+			return class.Name
+		}
+	}
+	return classes[len(classes)-1].Name
+}
+
+// reviewEstimate roughly estimates how long a careful review takes
+func reviewEstimate(files, lines int) string {
+	minutes := float64(lines)*60/reviewLinesPerHour + float64(files*reviewMinutesFile)
+	minutes = math.Max(5, math.Ceil(minutes/5)*5)
+	if minutes < 60 {
+		return fmt.Sprintf("~%.0f min", minutes)
+	}
+	return fmt.Sprintf("~%.1f h", minutes/60)
+}
+
+// sizeSummary classifies a change and estimates its review time
+func (c *Config) sizeSummary(files, lines int) string {
+	return fmt.Sprintf("size %s, %s to review", c.sizeClass(files, lines), reviewEstimate(files, lines))
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultReviewSLAHours is how long a reviewer may leave the current
+// patchset of a change without a response, unless configured otherwise
+const DefaultReviewSLAHours = 48
+
+// reviewSLAHours returns the review SLA of a project
+func (c *Config) reviewSLAHours(project string) int {
+	if hours := c.Projects[project].ReviewSLAHours; hours > 0 {
+		return hours
+	}
+	if c.ReviewSLAHours > 0 {
+		return c.ReviewSLAHours
+	}
+	return DefaultReviewSLAHours
+}
+
+// slaViolation is a change whose reviewers haven't responded in time
+type slaViolation struct {
+	change  gerrit.ChangeInfo
+	waiting time.Duration
+	silent  []gerrit.AccountInfo
+}
+
+// silentReviewers returns the reviewers of a change, other than its owner,
+// who posted nothing since its current patchset was uploaded
+func silentReviewers(change gerrit.ChangeInfo) []gerrit.AccountInfo {
+	since := change.Revisions[change.CurrentRevision].Created.Time
+	responded := make(map[int]bool)
+	for _, m := range change.Messages {
+		if !m.Date.Time.Before(since) {
+			responded[m.Author.AccountID] = true
+		}
+	}
+
+	var silent []gerrit.AccountInfo
+	for _, r := range change.Reviewers["REVIEWER"] {
+		if r.AccountID != change.Owner.AccountID && !responded[r.AccountID] {
+			silent = append(silent, r)
+		}
+	}
+	return silent
+}
+
+// CheckGerritReviewSLAs finds open changes whose reviewers haven't responded
+// to the current patchset within the review SLA, and optionally nudges them
+// with a reminder comment or by adding them to the attention set
+func (h *Handler) CheckGerritReviewSLAs(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	hours := request.GetInt("hours", 0)
+	if hours < 0 {
+		return mcp.NewToolResultError("hours must not be negative"), nil
+	}
+	action := request.GetString("action", "none")
+	switch action {
+	case "none", "comment":
+	case "attention":
+		if !h.compat().attentionSet {
+			return mcp.NewToolResultError(fmt.Sprintf("the attention set needs Gerrit 3.3 or later, the server runs %s; use action comment instead", h.compat().version)), nil
+		}
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("unknown action %q, expected none, comment or attention", action)), nil
+	}
+	dryRun := request.GetBool("dry_run", false)
+	limit := request.GetInt("limit", 25)
+
+	terms := []string{"status:open", "-is:wip"}
+	if project := request.GetString("project", ""); project != "" {
+		terms = append(terms, "project:"+project)
+	}
+	if request.GetBool("mine", false) {
+		terms = append(terms, "owner:self")
+	}
+	query := strings.Join(terms, " ")
+
+	changes, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{query}, Limit: 100},
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: []string{"CURRENT_REVISION", "MESSAGES", "DETAILED_ACCOUNTS", "DETAILED_LABELS"}},
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to query changes: %v", err)), nil
+	}
+
+	now := time.Now()
+	var violations []slaViolation
+	for _, change := range *changes {
+		revision, ok := change.Revisions[change.CurrentRevision]
+		if !ok {
+			continue
+		}
+		sla := hours
+		if sla == 0 {
+			sla = h.cfg().reviewSLAHours(change.Project)
+		}
+		waiting := now.Sub(revision.Created.Time)
+		if waiting < time.Duration(sla)*time.Hour {
+			continue
+		}
+		if silent := silentReviewers(change); len(silent) > 0 {
+			violations = append(violations, slaViolation{change: change, waiting: waiting, silent: silent})
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].waiting > violations[j].waiting
+	})
+	more := len(violations) > limit
+	violations = violations[:min(len(violations), limit)]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s matching %s with reviewers who haven't responded within the review SLA, longest waiting first", plural(len(violations), "change"), query)
+	if action != "none" && dryRun {
+		b.WriteString(" (dry run, nobody was nudged)")
+	}
+	b.WriteString("\n")
+	for _, v := range violations {
+		names := make([]string, len(v.silent))
+		for i, r := range v.silent {
+			names[i] = formatAccount(r)
+		}
+		fmt.Fprintf(&b, "\n%d %s: %s\n", v.change.Number, v.change.Project, v.change.Subject)
+		fmt.Fprintf(&b, "  owner %s, patchset %d waiting %s\n", formatAccount(v.change.Owner),
+			v.change.Revisions[v.change.CurrentRevision].Number, formatWaiting(v.waiting))
+		fmt.Fprintf(&b, "  no response from %s\n", strings.Join(names, ", "))
+
+		if action == "none" {
+			continue
+		}
+		if dryRun {
+			fmt.Fprintf(&b, "  would nudge by %s\n", map[string]string{"comment": "a reminder comment", "attention": "adding them to the attention set"}[action])
+			continue
+		}
+		if err := h.throttle(ctx); err != nil {
+			fmt.Fprintf(&b, "  not nudged: %v\n", err)
+			continue
+		}
+		for _, line := range h.nudgeReviewers(ctx, v, action, request.GetString("message", "")) {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	if more {
+		fmt.Fprintf(&b, "\nWARNING: more changes violate the review SLA; raise limit or narrow the criteria to see them\n")
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// nudgeReviewers reminds the silent reviewers of a change and describes the outcome
+func (h *Handler) nudgeReviewers(ctx context.Context, v slaViolation, action, message string) []string {
+	changeID := fmt.Sprint(v.change.Number)
+	if action == "comment" {
+		if message == "" {
+			names := make([]string, len(v.silent))
+			for i, r := range v.silent {
+				names[i] = reminderName(r)
+			}
+			message = fmt.Sprintf("Friendly reminder: this change has been waiting for review by %s for %s. Could you take a look when you get a chance? Thanks!",
+				strings.Join(names, ", "), formatWaiting(v.waiting))
+		}
+		if _, _, err := h.client.SetReview(ctx, changeID, v.change.CurrentRevision, &gerrit.ReviewInput{Message: message}); err != nil {
+			return []string{fmt.Sprintf("reminder comment FAILED: %v", err)}
+		}
+		return []string{"posted a reminder comment"}
+	}
+
+	reason := message
+	if reason == "" {
+		reason = fmt.Sprintf("Waiting for review for %s", formatWaiting(v.waiting))
+	}
+	var lines []string
+	for _, r := range v.silent {
+		input := attentionSetInput{User: fmt.Sprint(r.AccountID), Reason: reason}
+		if _, err := h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/attention", changeID), input, nil); err != nil {
+			lines = append(lines, fmt.Sprintf("%s: FAILED to add to the attention set: %v", formatAccount(r), err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: added to the attention set", formatAccount(r)))
+	}
+	return lines
+}
+
+// attentionSetInput is Gerrit's AttentionSetInput
+type attentionSetInput struct {
+	User   string `json:"user"`
+	Reason string `json:"reason"`
+}
+
+// reminderName is the name a reminder addresses a reviewer by
+func reminderName(account gerrit.AccountInfo) string {
+	if account.Name != "" {
+		return account.Name
+	}
+	return formatAccount(account)
+}
+
+// formatWaiting renders a waiting time in hours, or days once it exceeds two
+func formatWaiting(d time.Duration) string {
+	hours := int(d.Hours())
+	if hours < 48 {
+		return plural(hours, "hour")
+	}
+	return plural(hours/24, "day")
+}
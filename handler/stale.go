@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultStaleDays is how long an open change must go without updates to count as stale
+const DefaultStaleDays = 7
+
+// verificationFailed reports whether a change carries a rejecting Verified vote
+func verificationFailed(change gerrit.ChangeInfo) bool {
+	label, ok := change.Labels["Verified"]
+	if !ok {
+		return false
+	}
+	if label.Rejected.AccountID != 0 || label.Rejected.Name != "" {
+		return true
+	}
+	for _, approval := range label.All {
+		if approval.Value < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetGerritStaleChanges lists open changes that have gone without activity,
+// oldest first, so that rotting reviews can be chased up
+func (h *Handler) GetGerritStaleChanges(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	days := request.GetInt("days", DefaultStaleDays)
+	if days < 1 {
+		return mcp.NewToolResultError("days must be at least 1"), nil
+	}
+	limit := request.GetInt("limit", 25)
+
+	terms := []string{"status:open", "-is:wip", fmt.Sprintf("age:%dd", days)}
+	if project := request.GetString("project", ""); project != "" {
+		terms = append(terms, "project:"+project)
+	}
+	if request.GetBool("waiting_on_me", false) {
+		terms = append(terms, h.compat().waitingOnSelf())
+	}
+	if request.GetBool("failing_verification", false) {
+		terms = append(terms, "label:Verified-1")
+	}
+	query := strings.Join(terms, " ")
+
+	changes, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions:  gerrit.QueryOptions{Query: []string{query}, Limit: limit},
+		ChangeOptions: gerrit.ChangeOptions{AdditionalFields: []string{"LABELS", "DETAILED_ACCOUNTS"}},
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to query changes: %v", err)), nil
+	}
+
+	stale := *changes
+	more := len(stale) > 0 && stale[len(stale)-1].MoreChanges
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].Updated.Before(stale[j].Updated.Time)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s matching %s, least recently updated first\n", plural(len(stale), "stale change"), query)
+	now := time.Now()
+	for _, change := range stale {
+		idle := int(now.Sub(change.Updated.Time).Hours() / 24)
+		fmt.Fprintf(&b, "\n%d %s: %s\n", change.Number, change.Project, change.Subject)
+		fmt.Fprintf(&b, "  owner %s, idle %s, %s\n", formatAccount(change.Owner), plural(idle, "day"),
+			h.cfg().sizeSummary(0, change.Insertions+change.Deletions))
+		var problems []string
+		if verificationFailed(change) {
+			problems = append(problems, "verification failing")
+		}
+		if change.UnresolvedCommentCount > 0 {
+			problems = append(problems, plural(change.UnresolvedCommentCount, "unresolved comment"))
+		}
+		if len(problems) > 0 {
+			fmt.Fprintf(&b, "  %s\n", strings.Join(problems, ", "))
+		}
+	}
+	if more {
+		fmt.Fprintf(&b, "\nWARNING: more stale changes exist; raise limit or narrow the criteria to see them\n")
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ConnectionStatus describes how the server is connected to Gerrit
+type ConnectionStatus struct {
+	// State is "not connected yet", "connected", "reconnecting", "error" or
+	// "replaying" when serving exported changes instead of a live Gerrit
+	State     string
+	LastError string
+	BaseURL   string
+	Username  string
+	// AuthMode is "authenticated" or "anonymous"
+	AuthMode string
+	// ReadOnly is set when the server fell back to anonymous access and must not attempt writes
+	ReadOnly bool
+	Warnings []string
+}
+
+// WithConnectionStatus sets the function reporting the current connection status
+func WithConnectionStatus(status func(ctx context.Context) ConnectionStatus) Option {
+	return func(h *Handler) {
+		h.status = status
+	}
+}
+
+// connectionStatus returns the status of the connection used for ctx, or an
+// empty one if none was configured
+func (h *Handler) connectionStatus(ctx context.Context) ConnectionStatus {
+	if h.status == nil {
+		return ConnectionStatus{}
+	}
+	return h.status(ctx)
+}
+
+// GetServerStatus reports how the server is connected to Gerrit, including
+// any degraded mode it is running in. It never connects by itself, so it
+// works even while Gerrit is unreachable.
+func (h *Handler) GetServerStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	status := h.connectionStatus(ctx)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Gerrit: %s\n", status.BaseURL)
+	if status.State != "" {
+		fmt.Fprintf(&b, "Connection: %s\n", status.State)
+	}
+	if status.LastError != "" {
+		fmt.Fprintf(&b, "Last error: %s\n", status.LastError)
+	}
+	if status.Username != "" {
+		fmt.Fprintf(&b, "User: %s\n", status.Username)
+	}
+	if status.AuthMode != "" {
+		fmt.Fprintf(&b, "Authentication: %s\n", status.AuthMode)
+	}
+	fmt.Fprintf(&b, "Read-only: %t\n", status.ReadOnly)
+	h.writeCapabilities(&b)
+	h.writeQuota(&b)
+	for _, warning := range status.Warnings {
+		fmt.Fprintf(&b, "WARNING: %s\n", warning)
+	}
+
+	return mcp.NewToolResultText(b.String()), nil
+}
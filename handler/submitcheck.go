@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// submitRequirementResult is Gerrit's SubmitRequirementResultInfo
+type submitRequirementResult struct {
+	Name           string                       `json:"name"`
+	Status         string                       `json:"status"`
+	Submittability *submitRequirementExpression `json:"submittability_expression_result,omitempty"`
+	Override       *submitRequirementExpression `json:"override_expression_result,omitempty"`
+}
+
+// submitRequirementExpression is Gerrit's SubmitRequirementExpressionInfo
+type submitRequirementExpression struct {
+	Expression   string   `json:"expression"`
+	Fulfilled    bool     `json:"fulfilled"`
+	PassingAtoms []string `json:"passing_atoms,omitempty"`
+	FailingAtoms []string `json:"failing_atoms,omitempty"`
+}
+
+// ciLabel is the label CI systems vote on
+const ciLabel = "Verified"
+
+// submitVerdict collects why a change can or can't be submitted
+type submitVerdict struct {
+	blockers []string
+	warnings []string
+	passed   []string
+}
+
+// CheckGerritChangeSubmittable runs every check an agent should make before
+// recommending to submit a change: its state, mergeability, submit
+// requirements, unresolved comments and CI status, and returns a go/no-go
+// verdict with the reasons
+func (h *Handler) CheckGerritChangeSubmittable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "SUBMITTABLE", "DETAILED_LABELS", "DETAILED_ACCOUNTS")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var v submitVerdict
+	switch {
+	case change.Status != "NEW":
+		v.blockers = append(v.blockers, fmt.Sprintf("the change is %s", change.Status))
+	case change.WorkInProgress:
+		v.blockers = append(v.blockers, "the change is work in progress")
+	default:
+		v.passed = append(v.passed, "the change is open and ready for review")
+	}
+
+	var mergeable struct {
+		Mergeable bool `json:"mergeable"`
+	}
+	if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("changes/%s/revisions/current/mergeable", changeID), nil, &mergeable); err != nil {
+		v.warnings = append(v.warnings, fmt.Sprintf("could not check mergeability: %v", err))
+	} else if !mergeable.Mergeable {
+		v.blockers = append(v.blockers, "the change has merge conflicts with its target branch and must be rebased")
+	} else {
+		v.passed = append(v.passed, "merges cleanly into "+change.Branch)
+	}
+
+	h.checkSubmitRules(ctx, changeID, change, &v)
+
+	if change.UnresolvedCommentCount > 0 {
+		v.blockers = append(v.blockers, fmt.Sprintf("%s to address", plural(change.UnresolvedCommentCount, "unresolved comment")))
+	} else {
+		v.passed = append(v.passed, "no unresolved comments")
+	}
+
+	checkCI(change, &v)
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	if len(v.blockers) == 0 {
+		fmt.Fprintf(&b, "Verdict for change %s: GO, it can be submitted\n", changeID)
+	} else {
+		fmt.Fprintf(&b, "Verdict for change %s: NO-GO, %s\n", changeID, plural(len(v.blockers), "blocker"))
+	}
+	for _, section := range []struct {
+		title string
+		items []string
+	}{{"Blockers", v.blockers}, {"Warnings", v.warnings}, {"Passed", v.passed}} {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		for _, item := range section.items {
+			fmt.Fprintf(&b, "  - %s\n", item)
+		}
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// checkSubmitRules checks the submit requirements, or the label votes on
+// servers that predate them
+func (h *Handler) checkSubmitRules(ctx context.Context, changeID string, change *gerrit.ChangeInfo, v *submitVerdict) {
+	if h.compat().submitRequirements {
+		var info struct {
+			SubmitRequirements []submitRequirementResult `json:"submit_requirements"`
+		}
+		if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("changes/%s?o=SUBMIT_REQUIREMENTS", changeID), nil, &info); err != nil {
+			v.warnings = append(v.warnings, fmt.Sprintf("could not get the submit requirements: %v", err))
+		} else {
+			for _, req := range info.SubmitRequirements {
+				switch req.Status {
+				case "SATISFIED", "OVERRIDDEN", "FORCED":
+					v.passed = append(v.passed, fmt.Sprintf("submit requirement %s is %s", req.Name, strings.ToLower(req.Status)))
+				case "NOT_APPLICABLE":
+				case "ERROR":
+					v.blockers = append(v.blockers, fmt.Sprintf("submit requirement %s fails to evaluate; ask a project owner to fix it", req.Name))
+				default:
+					v.blockers = append(v.blockers, fmt.Sprintf("submit requirement %s is not satisfied", req.Name))
+				}
+			}
+		}
+	} else {
+		for _, name := range sortedKeys(change.Labels) {
+			label := change.Labels[name]
+			switch {
+			case label.Optional:
+			case label.Rejected.AccountID != 0 || label.Blocking:
+				v.blockers = append(v.blockers, fmt.Sprintf("%s is rejected by %s", name, formatAccount(label.Rejected)))
+			case label.Approved.AccountID == 0:
+				v.blockers = append(v.blockers, fmt.Sprintf("%s needs approval", name))
+			default:
+				v.passed = append(v.passed, fmt.Sprintf("%s approved by %s", name, formatAccount(label.Approved)))
+			}
+		}
+	}
+
+	// Gerrit's own verdict catches rules the checks above don't know about
+	if !change.Submittable && change.Status == "NEW" && len(v.blockers) == 0 {
+		v.blockers = append(v.blockers, "Gerrit reports the change as not submittable")
+	}
+}
+
+// checkCI reports the votes on the CI label
+func checkCI(change *gerrit.ChangeInfo, v *submitVerdict) {
+	label, ok := change.Labels[ciLabel]
+	if !ok {
+		v.warnings = append(v.warnings, fmt.Sprintf("the project has no %s label, so CI status is unknown", ciLabel))
+		return
+	}
+
+	var failing, passing []string
+	for _, approval := range label.All {
+		switch {
+		case approval.Value < 0:
+			failing = append(failing, fmt.Sprintf("%s %+d", formatAccount(approval.AccountInfo), approval.Value))
+		case approval.Value > 0:
+			passing = append(passing, fmt.Sprintf("%s %+d", formatAccount(approval.AccountInfo), approval.Value))
+		}
+	}
+	sort.Strings(failing)
+	sort.Strings(passing)
+	switch {
+	case len(failing) > 0:
+		v.blockers = append(v.blockers, fmt.Sprintf("CI is failing: %s", strings.Join(failing, ", ")))
+	case len(passing) > 0:
+		v.passed = append(v.passed, fmt.Sprintf("CI passed: %s", strings.Join(passing, ", ")))
+	default:
+		v.warnings = append(v.warnings, fmt.Sprintf("CI has not voted on %s yet", ciLabel))
+	}
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// starLabelsRemovedIn is the first Gerrit release that only keeps the
+// default star, so that private tags fall back to hashtags
+const starLabelsRemovedIn = "3.6"
+
+// starsInput is Gerrit's StarsInput
+type starsInput struct {
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+// tagStore keeps the calling user's tags on changes: as star labels, which
+// only the user can see, or on servers without them as hashtags prefixed
+// with the user's name, which everyone can see
+type tagStore struct {
+	h *Handler
+	// prefix marks the user's hashtags, empty when star labels are used
+	prefix string
+}
+
+// tags returns the tag store of the calling user
+func (h *Handler) tags(ctx context.Context) (*tagStore, error) {
+	if h.compat().starLabels {
+		return &tagStore{h: h}, nil
+	}
+	var self gerrit.AccountInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "accounts/self", nil, &self); err != nil {
+		return nil, fmt.Errorf("failed to get the calling account, tags require authentication: %v", err)
+	}
+	name := self.Username
+	if name == "" {
+		name = strconv.Itoa(self.AccountID)
+	}
+	return &tagStore{h: h, prefix: name + ":"}, nil
+}
+
+// private reports whether only the calling user can see the tags
+func (s *tagStore) private() bool { return s.prefix == "" }
+
+// publicNote warns that tags are visible to everyone, if they are
+func (s *tagStore) publicNote() string {
+	if s.private() {
+		return ""
+	}
+	return fmt.Sprintf("NOTE: Gerrit %s has no private star labels, so tags are hashtags starting with %q that everyone can see", s.h.compat().version, s.prefix)
+}
+
+// get returns the user's tags on a change
+func (s *tagStore) get(ctx context.Context, changeID string) ([]string, error) {
+	var labels []string
+	path := fmt.Sprintf("accounts/self/stars.changes/%s", changeID)
+	if !s.private() {
+		path = fmt.Sprintf("changes/%s/hashtags", changeID)
+	}
+	if _, err := s.h.client.Call(ctx, http.MethodGet, path, nil, &labels); err != nil {
+		return nil, fmt.Errorf("failed to get the tags of change %s: %v", changeID, err)
+	}
+	var tags []string
+	for _, label := range labels {
+		if tag, ok := strings.CutPrefix(label, s.prefix); ok {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// update adds and removes tags of the user on a change
+func (s *tagStore) update(ctx context.Context, changeID string, add, remove []string) error {
+	if s.private() {
+		_, err := s.h.client.Call(ctx, http.MethodPost, fmt.Sprintf("accounts/self/stars.changes/%s", changeID), starsInput{Add: add, Remove: remove}, nil)
+		return err
+	}
+	input := hashtagsInput{}
+	for _, tag := range add {
+		input.Add = append(input.Add, s.prefix+tag)
+	}
+	for _, tag := range remove {
+		input.Remove = append(input.Remove, s.prefix+tag)
+	}
+	_, err := s.h.client.Call(ctx, http.MethodPost, fmt.Sprintf("changes/%s/hashtags", changeID), input, nil)
+	return err
+}
+
+// query is the search term for changes with one of the user's tags
+func (s *tagStore) query(tag string) string {
+	if s.private() {
+		return "star:" + tag
+	}
+	return "hashtag:" + strconv.Quote(s.prefix+tag)
+}
+
+// parseTags splits a comma-separated list of tags, which can't contain spaces
+func parseTags(list string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if strings.ContainsAny(tag, " \t\n") {
+			return nil, fmt.Errorf("tag %q must not contain spaces", tag)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// GetGerritChangeTags lists the calling user's tags on a change, or the
+// changes carrying a tag, such as triage state kept by an agent
+func (h *Handler) GetGerritChangeTags(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL := request.GetString("change_url", "")
+	tag := strings.TrimSpace(request.GetString("tag", ""))
+	if changeURL == "" && tag == "" {
+		return mcp.NewToolResultError("either change_url or tag is required"), nil
+	}
+	store, err := h.tags(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	note := store.publicNote()
+
+	if changeURL != "" {
+		changeID, _, header, err := h.lookupChange(ctx, changeURL)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		tags, err := store.get(ctx, changeID)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if note != "" {
+			header = append(header, note)
+		}
+
+		var b strings.Builder
+		if len(header) > 0 {
+			b.WriteString(strings.Join(header, "\n") + "\n\n")
+		}
+		if len(tags) == 0 {
+			fmt.Fprintf(&b, "You have not tagged change %s", changeID)
+		} else {
+			fmt.Fprintf(&b, "Your tags on change %s: %s", changeID, strings.Join(tags, ", "))
+		}
+		return mcp.NewToolResultText(b.String()), nil
+	}
+
+	limit := request.GetInt("limit", 25)
+	changes, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+		QueryOptions: gerrit.QueryOptions{Query: []string{store.query(tag)}, Limit: limit},
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to query changes tagged %s: %v", tag, err)), nil
+	}
+
+	var b strings.Builder
+	if note != "" {
+		b.WriteString(note + "\n\n")
+	}
+	if len(*changes) == 0 {
+		fmt.Fprintf(&b, "You have not tagged any changes %s", tag)
+		return mcp.NewToolResultText(b.String()), nil
+	}
+	fmt.Fprintf(&b, "Changes you tagged %s:\n", tag)
+	for _, change := range *changes {
+		fmt.Fprintf(&b, "  %d [%s] %s: %s\n", change.Number, change.Status, change.Project, change.Subject)
+	}
+	if (*changes)[len(*changes)-1].MoreChanges {
+		b.WriteString("\nWARNING: more changes carry the tag; raise limit to see them\n")
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
+
+// SetGerritChangeTags adds and removes the calling user's tags on a change
+func (h *Handler) SetGerritChangeTags(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	add, err := parseTags(request.GetString("add", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	remove, err := parseTags(request.GetString("remove", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return mcp.NewToolResultError("add or remove must name at least one tag"), nil
+	}
+
+	changeID, _, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	store, err := h.tags(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := store.update(ctx, changeID, add, remove); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to tag change %s: %v", changeID, err)), nil
+	}
+	tags, err := store.get(ctx, changeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if note := store.publicNote(); note != "" {
+		header = append(header, note)
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Updated your tags on change %s: ", changeID)
+	if len(tags) == 0 {
+		b.WriteString("none left")
+	} else {
+		b.WriteString(strings.Join(tags, ", "))
+	}
+	return mcp.NewToolResultText(b.String()), nil
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ReviewTemplate is a named, reusable review: a message and votes
+type ReviewTemplate struct {
+	Message string         `json:"message"`
+	Labels  map[string]int `json:"labels"`
+}
+
+// ApplyGerritReviewTemplate posts a configured review template on the
+// current patchset of a change, optionally followed by a custom note
+func (h *Handler) ApplyGerritReviewTemplate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	name, err := request.RequireString("template")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	template, ok := h.cfg().ReviewTemplates[name]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown review template %q, available: %s", name, strings.Join(sortedKeys(h.cfg().ReviewTemplates), ", "))), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	input := &gerrit.ReviewInput{Message: template.Message, Labels: template.Labels}
+	if note := request.GetString("note", ""); note != "" {
+		input.Message = strings.TrimSpace(input.Message + "\n\n" + note)
+	}
+
+	result, _, err := h.client.SetReview(ctx, changeID, change.CurrentRevision, input)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to post review on change %s: %v", changeID, err)), nil
+	}
+	if result != nil && result.Error != "" {
+		return mcp.NewToolResultError(fmt.Sprintf("Gerrit refused the review on change %s: %s", changeID, result.Error)), nil
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Applied review template %s to change %s", name, changeID)
+	for _, label := range sortedKeys(input.Labels) {
+		fmt.Fprintf(&b, "\n%s: %+d", label, input.Labels[label])
+	}
+	return mcp.NewToolResultText(b.String()), nil
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// gerritTimestamp is the layout of timestamps in Gerrit's REST API, which are in UTC
+const gerritTimestamp = "2006-01-02 15:04:05.000000000"
+
+var removedVoteRegexp = regexp.MustCompile(`(?m)^Removed ([A-Za-z][\w-]*[+-]\d+) by (.+)$`)
+
+// attentionSetInfo is Gerrit's AttentionSetInfo
+type attentionSetInfo struct {
+	Account    gerrit.AccountInfo `json:"account"`
+	LastUpdate string             `json:"last_update"`
+	Reason     string             `json:"reason"`
+}
+
+// attentionInfo holds the attention set fields of Gerrit's ChangeInfo, which
+// only record the latest update for each account
+type attentionInfo struct {
+	AttentionSet            map[string]attentionSetInfo `json:"attention_set"`
+	RemovedFromAttentionSet map[string]attentionSetInfo `json:"removed_from_attention_set"`
+}
+
+// timelineEvent is one step in the history of a change
+type timelineEvent struct {
+	// when is a Gerrit timestamp, which sorts chronologically as a string
+	when        string
+	actor       string
+	description string
+}
+
+// messageEvents extracts votes, WIP toggles and status changes from a change message
+func messageEvents(m gerrit.ChangeMessageInfo) []string {
+	firstLine, _, _ := strings.Cut(m.Message, "\n")
+	var events []string
+	if rest, ok := strings.CutPrefix(firstLine, "Patch Set "); ok {
+		if _, labels, ok := strings.Cut(rest, ":"); ok {
+			for _, v := range voteRegexp.FindAllStringSubmatch(labels, -1) {
+				events = append(events, fmt.Sprintf("voted %s%s", v[1], v[2]))
+			}
+		}
+	}
+	for _, v := range removedVoteRegexp.FindAllStringSubmatch(m.Message, -1) {
+		events = append(events, fmt.Sprintf("removed vote %s by %s", v[1], v[2]))
+	}
+
+	switch tag := strings.TrimPrefix(m.Tag, "autogenerated:gerrit:"); {
+	case tag == "setWorkInProgress" || strings.Contains(firstLine, "Set Work In Progress"):
+		events = append(events, "marked work in progress")
+	case tag == "setReadyForReview" || strings.Contains(firstLine, "Set Ready For Review"):
+		events = append(events, "marked ready for review")
+	case tag == "merged":
+		events = append(events, "merged the change")
+	case tag == "abandon":
+		events = append(events, "abandoned the change")
+	case tag == "restore":
+		events = append(events, "restored the change")
+	}
+	return events
+}
+
+// GetGerritChangeTimeline lists the history of a change in order: patchset
+// uploads, votes added and removed, attention set updates and WIP toggles
+func (h *Handler) GetGerritChangeTimeline(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "ALL_REVISIONS", "MESSAGES", "DETAILED_ACCOUNTS")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var events []timelineEvent
+	for _, rev := range change.Revisions {
+		events = append(events, timelineEvent{
+			when:        rev.Created.UTC().Format(gerritTimestamp),
+			actor:       formatAccount(rev.Uploader),
+			description: fmt.Sprintf("uploaded patch set %d", rev.Number),
+		})
+	}
+	for _, m := range change.Messages {
+		actor := formatAccount(m.Author)
+		if m.Author.AccountID == 0 && m.Author.Name == "" {
+			actor = "Gerrit"
+		}
+		for _, description := range messageEvents(m) {
+			events = append(events, timelineEvent{
+				when:        m.Date.UTC().Format(gerritTimestamp),
+				actor:       actor,
+				description: description,
+			})
+		}
+	}
+
+	// The attention set is a newer feature, so older servers simply have none
+	var attention attentionInfo
+	if h.compat().attentionSet {
+		if _, err := h.client.Call(ctx, http.MethodGet, "changes/"+changeID, nil, &attention); err != nil {
+			header = append(header, fmt.Sprintf("WARNING: could not get the attention set: %v", err))
+		}
+	}
+	for _, a := range attention.AttentionSet {
+		events = append(events, timelineEvent{when: a.LastUpdate, description: fmt.Sprintf("%s added to the attention set: %s", formatAccount(a.Account), a.Reason)})
+	}
+	for _, a := range attention.RemovedFromAttentionSet {
+		events = append(events, timelineEvent{when: a.LastUpdate, description: fmt.Sprintf("%s removed from the attention set: %s", formatAccount(a.Account), a.Reason)})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].when < events[j].when })
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "Timeline of %d: %s\n\n", change.Number, change.Subject)
+	for _, e := range events {
+		if e.actor != "" {
+			fmt.Fprintf(&b, "%s %s %s\n", formatTimestamp(e.when), e.actor, e.description)
+		} else {
+			fmt.Fprintf(&b, "%s %s\n", formatTimestamp(e.when), e.description)
+		}
+	}
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
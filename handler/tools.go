@@ -0,0 +1,1325 @@
+package handler
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Tools returns the MCP tools served by the handler, each paired with its implementation
+func (h *Handler) Tools() []Tool {
+	tools := []Tool{
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change",
+					mcp.WithDescription("Get Gerrit change"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithNumber("patchset",
+						mcp.Description("Number of the patchset to get the patch of, e.g. to review an earlier revision (default: the current patchset)"),
+					),
+					mcp.WithBoolean("force",
+						mcp.Description("Return the patch even if the change exceeds the size limits, truncated if necessary"),
+					),
+					mcp.WithBoolean("split_files",
+						mcp.Description("Return the commit message and each file's diff as separate content blocks"),
+					),
+					mcp.WithString("format",
+						mcp.Description("text (default) returns the decoded patch; base64 and zip return it as an embedded resource, encoded as Gerrit serves it or as Gerrit's zip download"),
+						mcp.Enum("text", "base64", "zip"),
+					),
+					mcp.WithString("normalize",
+						mcp.Description("Comma-separated clean-ups of the diff content: eol (CRLF to LF), bom (strip byte order marks), utf8 (replace invalid UTF-8), or all"),
+					),
+				),
+				Handler: h.GetGerritChangePatch,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-hunks",
+					mcp.WithDescription("Get a Gerrit change's current patch piece by piece: lists the hunks of every file, or returns the diff of one file or a single hunk of it"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("file",
+						mcp.Description("Path of the file to return the diff of; omit to list all hunks"),
+					),
+					mcp.WithNumber("hunk",
+						mcp.Description("Index of the hunk within the file to return; omit for the whole file"),
+					),
+					mcp.WithString("normalize",
+						mcp.Description("Comma-separated clean-ups of the diff content: eol (CRLF to LF), bom (strip byte order marks), utf8 (replace invalid UTF-8), or all"),
+					),
+				),
+				Handler: h.GetGerritChangeHunks,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-diff-since-review",
+					mcp.WithDescription("Get only what changed in a Gerrit change since the calling user last reviewed it: the diff between the last patch set they voted or commented on and the current one. Files unchanged since then or only touched by a rebase are listed without a diff, and a change that was only rebased is reported as such"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritChangeDiffSinceReview,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-unseen-delta",
+					mcp.WithDescription("List exactly the files and hunks of a Gerrit change's current patchset a reviewer has not looked at yet, combining the files they marked as reviewed with the last patchset they voted or commented on"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("reviewer",
+						mcp.Description("Account of the reviewer: username, email or account ID (default: you). Gerrit only shows your own reviewed flags, so for others only their votes and comments count"),
+					),
+					mcp.WithBoolean("diff",
+						mcp.Description("Include the unseen hunks' content rather than only their line ranges (default false)"),
+					),
+				),
+				Handler: h.GetGerritChangeUnseenDelta,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-topic-interdiff",
+					mcp.WithDescription("After a topic-wide rebase, compare the two latest patchsets of every change in a Gerrit topic and report which changes were reworked, with the files that differ beyond the rebase, and which were only trivially rebased and need no new review"),
+					mcp.WithString("topic",
+						mcp.Description("Name of the topic"),
+					),
+					mcp.WithString("change_url",
+						mcp.Description("URL of a Gerrit change whose topic to compare, instead of topic"),
+					),
+					mcp.WithBoolean("diff",
+						mcp.Description("Include the interdiff of each reworked file (default false)"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Maximum number of changes of the topic to compare (default 50)"),
+					),
+				),
+				Handler: h.GetGerritTopicInterdiff,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("list-gerrit-change-files",
+					mcp.WithDescription("List the files changed by a Gerrit change's current patchset with inserted/deleted lines, detected language and the component each file belongs to"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.ListGerritChangeFiles,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-file-tree",
+					mcp.WithDescription("Show the files changed by a Gerrit change's current patchset as a directory tree with inserted/deleted lines per directory; easier to grasp than a flat list for large changes"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritChangeFileTree,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-owners",
+					mcp.WithDescription("Report for each file changed by a Gerrit change whether it has the required code owner approval, who approved it and who can approve the rest; uses the code-owners plugin or OWNERS files"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritChangeOwners,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-project-guidelines",
+					mcp.WithDescription("Read the README, contributing guidelines, CODEOWNERS and style guides of a Gerrit change's project from its target branch, so that a review can follow the project's conventions; the files read are configurable"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("files",
+						mcp.Description("Comma-separated paths to read instead of the configured guideline files, e.g. \"README.md,docs/STYLE.md\""),
+					),
+					mcp.WithNumber("max_chars",
+						mcp.Description("Characters to return per file before truncating it, 0 for no limit (default 20000)"),
+					),
+				),
+				Handler: h.GetGerritProjectGuidelines,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("search-gerrit-project-code",
+					mcp.WithDescription("Search the files of a Gerrit change's project at the tip of its target branch for a regular expression, e.g. to check whether a helper the change adds is already defined elsewhere. Reads one file per request, so narrow the search with paths"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("pattern",
+						mcp.Required(),
+						mcp.Description("Regular expression (RE2 syntax) matched against each line, e.g. \"func parseDuration\\(\""),
+					),
+					mcp.WithString("paths",
+						mcp.Description("Only search files whose path contains this text, e.g. \"handler/\" or \".go\""),
+					),
+					mcp.WithString("branch",
+						mcp.Description("Branch to search instead of the change's target branch"),
+					),
+					mcp.WithNumber("max_files",
+						mcp.Description("Maximum number of files to read (default 200, at most 1000)"),
+					),
+					mcp.WithNumber("max_matches",
+						mcp.Description("Stop after this many matching lines (default 50)"),
+					),
+				),
+				Handler: h.SearchGerritProjectCode,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("search-external-code",
+					mcp.WithDescription("Search the configured code search service (Sourcegraph or OpenGrok) across all repositories or one project, returning matching lines with links; faster and broader than search-gerrit-project-code"),
+					mcp.WithString("query",
+						mcp.Required(),
+						mcp.Description("Query in the service's syntax, e.g. \"parseDuration lang:go\" for Sourcegraph"),
+					),
+					mcp.WithString("project",
+						mcp.Description("Gerrit project to restrict the search to"),
+					),
+					mcp.WithString("change_url",
+						mcp.Description("URL of a Gerrit change whose project to restrict the search to, if project is not given"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Maximum number of matching lines (default 20, at most 100)"),
+					),
+				),
+				Handler: h.SearchExternalCode,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-dependencies",
+					mcp.WithDescription("Get the dependency graph of a Gerrit change: changes it depends on through Depends-On trailers (across repositories) and parent commits, with their status and an order in which to submit them"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritChangeDependencies,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-graph",
+					mcp.WithDescription("Get the full stack around a Gerrit change as JSON nodes and edges: its ancestors and descendants through parent commits and Depends-On trailers, with the order in which the unmerged changes can land. Use it to render a stack visualization or explain the landing order"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritChangeGraph,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-issues",
+					mcp.WithDescription("List the issues (bugs, tickets) a Gerrit change refers to in its commit message and review messages, with links to the issue trackers"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritChangeIssues,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-checklist",
+					mcp.WithDescription("Get a review checklist for a Gerrit change, built from the team's rules for the paths it touches (e.g. database migrations must stay backward compatible), along with its diffstat"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritChangeChecklist,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("check-gerrit-commit-message",
+					mcp.WithDescription("Check the current commit message of a Gerrit change against the conventions of its project: subject length and mood, blank line, body line length and required footers such as Change-Id and Bug. Returns the message, the conventions and each violation, to fix with set-gerrit-commit-message"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.CheckGerritCommitMessage,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("set-gerrit-commit-message",
+					mcp.WithDescription("Replace the commit message of a Gerrit change, creating a new patchset. The change's Change-Id footer is added if the message leaves it out. Messages missing the footers or subject pattern the project requires are refused with the violations"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("message",
+						mcp.Required(),
+						mcp.Description("The complete new commit message: subject, blank line, body and footers"),
+					),
+				),
+				Handler: h.SetGerritCommitMessage,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-comments",
+					mcp.WithDescription("List the comment threads of a Gerrit change by file, with their resolution state and where comments made on older patchsets land in the current patchset (or that they are outdated)"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithBoolean("unresolved_only",
+						mcp.Description("Only show threads that are still unresolved"),
+					),
+					mcp.WithBoolean("include_drafts",
+						mcp.Description("Include your own unpublished draft comments; requires authentication"),
+					),
+					mcp.WithBoolean("classify",
+						mcp.Description("Classify threads as requested-change, question, nit or fyi and list the action items of unresolved threads, as a to-do list for the change owner"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Number of threads per page; omit for all"),
+					),
+					mcp.WithString("cursor",
+						mcp.Description("next_cursor of the previous page, to continue a listing"),
+					),
+				),
+				Handler: h.GetGerritChangeComments,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-comment-coverage",
+					mcp.WithDescription("Report which changed files and hunks of a Gerrit change's current patchset have no review comments at all, to check that the whole change was looked at"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritCommentCoverage,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("apply-gerrit-fix-suggestion",
+					mcp.WithDescription("Apply a fix suggested by a robot comment (e.g. an analyzer) on a Gerrit change to a change edit, optionally publishing it as a new patchset; without fix_id, list the available fix suggestions"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("fix_id",
+						mcp.Description("ID of the fix suggestion to apply; omit to list the fix suggestions"),
+					),
+					mcp.WithBoolean("publish",
+						mcp.Description("Publish the change edit as a new patchset after applying the fix"),
+					),
+				),
+				Handler: h.ApplyGerritFixSuggestion,
+			},
+			Category:  CategoryWrite,
+			Untrusted: true,
+			// The Apply Fix endpoint
+			Requires: Requirement{MinVersion: "2.16"},
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("apply-suggested-edit",
+					mcp.WithDescription("Replace a range of lines of a file in a Gerrit change with new text, in the change edit (a draft of the next patchset), and return a preview diff; publish with publish-gerrit-change-edit"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("file",
+						mcp.Required(),
+						mcp.Description("Path of the file to edit"),
+					),
+					mcp.WithNumber("start_line",
+						mcp.Required(),
+						mcp.Description("First line to replace, counting from 1"),
+					),
+					mcp.WithNumber("end_line",
+						mcp.Required(),
+						mcp.Description("Last line to replace; start_line - 1 inserts before start_line without replacing anything"),
+					),
+					mcp.WithString("replacement",
+						mcp.Required(),
+						mcp.Description("Text replacing the lines; empty to delete them"),
+					),
+				),
+				Handler: h.ApplySuggestedEdit,
+			},
+			Category:  CategoryWrite,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("publish-gerrit-change-edit",
+					mcp.WithDescription("Publish the change edit of a Gerrit change as a new patchset, or discard it"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithBoolean("discard",
+						mcp.Description("Discard the change edit instead of publishing it"),
+					),
+				),
+				Handler: h.PublishGerritChangeEdit,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("format-gerrit-comment-reply",
+					mcp.WithDescription("Format a reply to a Gerrit comment the Gerrit way, quoting the parent comment with \"> \", and return the thread, path, line and in_reply_to to post it with"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("comment_id",
+						mcp.Required(),
+						mcp.Description("ID of the comment being replied to"),
+					),
+					mcp.WithString("reply",
+						mcp.Required(),
+						mcp.Description("Text of the reply"),
+					),
+					mcp.WithBoolean("resolve",
+						mcp.Description("Whether the reply resolves the thread"),
+					),
+				),
+				Handler: h.FormatGerritCommentReply,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-timeline",
+					mcp.WithDescription("Get the chronological history of a Gerrit change: patchset uploads, votes added and removed, attention set updates, WIP toggles and status changes, each with actor and time"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritChangeTimeline,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-activity",
+					mcp.WithDescription("List the review activity of an account within a time window, grouped by change: patch sets uploaded, votes cast and comments written; useful for \"what did I do this week\" summaries"),
+					mcp.WithString("account",
+						mcp.Description("Account ID, username or email; defaults to the authenticated user (self)"),
+					),
+					mcp.WithString("since",
+						mcp.Description("Start of the window, as an age (7d, 36h) or a date (2024-05-01, 2024-05-01 14:00); default 7d"),
+					),
+					mcp.WithString("until",
+						mcp.Description("End of the window, in the same formats; default now"),
+					),
+				),
+				Handler: h.GetGerritActivity,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-stale-changes",
+					mcp.WithDescription("List open changes without activity for a number of days, least recently updated first, to find rotting reviews worth a nudge"),
+					mcp.WithNumber("days",
+						mcp.Description("Minimum number of days since the last update (default 7)"),
+					),
+					mcp.WithString("project",
+						mcp.Description("Only consider changes in this project"),
+					),
+					mcp.WithBoolean("waiting_on_me",
+						mcp.Description("Only changes with the authenticated user in the attention set, or assigned to them before Gerrit 3.3"),
+					),
+					mcp.WithBoolean("failing_verification",
+						mcp.Description("Only changes with a negative Verified vote"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Maximum number of changes to return (default 25)"),
+					),
+				),
+				Handler: h.GetGerritStaleChanges,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-release-notes",
+					mcp.WithDescription("Draft release notes from the changes merged into a Gerrit project between two tags, branches or times, grouped by subject prefix, topic or hashtag"),
+					mcp.WithString("project",
+						mcp.Required(),
+						mcp.Description("Project the release is made from"),
+					),
+					mcp.WithString("from",
+						mcp.Required(),
+						mcp.Description("Tag or branch of the previous release, or a time such as 2024-05-01 or 30d"),
+					),
+					mcp.WithString("to",
+						mcp.Description("Tag or branch of the new release, or a time; default now"),
+					),
+					mcp.WithString("branch",
+						mcp.Description("Only changes merged into this branch"),
+					),
+					mcp.WithString("group_by",
+						mcp.Description("Group changes by the prefix of their subject before a colon (default), their topic or their hashtags"),
+						mcp.Enum("prefix", "topic", "hashtag"),
+					),
+					mcp.WithString("format",
+						mcp.Description("markdown (default) or json"),
+						mcp.Enum("markdown", "json"),
+					),
+				),
+				Handler: h.GetGerritReleaseNotes,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-overlaps",
+					mcp.WithDescription("Find open changes in the same project touching the same files as a Gerrit change, with the shared files per change, to warn about likely merge conflicts or duplicate work"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Maximum number of changes to consider (default 25)"),
+					),
+				),
+				Handler: h.GetGerritChangeOverlaps,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("check-gerrit-review-slas",
+					mcp.WithDescription("Find open changes whose reviewers haven't responded to the current patchset within the review SLA (48 hours unless configured), longest waiting first, and optionally nudge them"),
+					mcp.WithNumber("hours",
+						mcp.Description("Hours a reviewer may stay silent, overriding the configured SLA"),
+					),
+					mcp.WithString("project",
+						mcp.Description("Only consider changes in this project"),
+					),
+					mcp.WithBoolean("mine",
+						mcp.Description("Only changes owned by the authenticated user"),
+					),
+					mcp.WithString("action",
+						mcp.Description("How to nudge the silent reviewers: none (default, only list), comment (post a polite reminder) or attention (add them to the attention set)"),
+						mcp.Enum("none", "comment", "attention"),
+					),
+					mcp.WithString("message",
+						mcp.Description("Text of the reminder comment or attention set reason, replacing the default"),
+					),
+					mcp.WithBoolean("dry_run",
+						mcp.Description("Only list the nudges that would be made"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Maximum number of changes to return (default 25)"),
+					),
+				),
+				Handler: h.CheckGerritReviewSLAs,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("post-gerrit-review",
+					mcp.WithDescription("Post a review on a Gerrit change: vote on labels such as Code-Review or Verified and attach a review message. Votes go on the current patchset; a message alone may go on an earlier one"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("labels",
+						mcp.Description("Comma-separated votes, e.g. \"Code-Review+1,Verified-1\"; a 0 vote removes yours"),
+					),
+					mcp.WithString("message",
+						mcp.Description("Review message"),
+					),
+					mcp.WithNumber("patchset",
+						mcp.Description("Number of the patchset to post the message on (default: the current patchset)"),
+					),
+				),
+				Handler: h.PostGerritReview,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("add-gerrit-reviewer",
+					mcp.WithDescription("Add an account or group as reviewer or CC of a Gerrit change. Use expand_group first for groups to see who would be notified; large groups must be confirmed"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("reviewer",
+						mcp.Required(),
+						mcp.Description("Account (username, email or ID) or group name to add"),
+					),
+					mcp.WithString("state",
+						mcp.Description("Add as REVIEWER (default) or CC"),
+						mcp.Enum("REVIEWER", "CC"),
+					),
+					mcp.WithBoolean("expand_group",
+						mcp.Description("List the accounts of the group that would be notified, without adding anyone"),
+					),
+					mcp.WithBoolean("confirmed",
+						mcp.Description("Confirm adding a group Gerrit considers large"),
+					),
+				),
+				Handler: h.AddGerritReviewer,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("backport-gerrit-change",
+					mcp.WithDescription("Cherry-pick a merged Gerrit change to several branches, give the cherry-picks a common topic and hashtags, and add the original reviewers, reporting the outcome and any conflicts per branch"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("branches",
+						mcp.Required(),
+						mcp.Description("Comma-separated target branches, e.g. stable-3.9,stable-3.10"),
+					),
+					mcp.WithString("topic",
+						mcp.Description("Topic of the cherry-picks, where {number} and {branch} are replaced (default from the configuration, or backport-{number})"),
+					),
+					mcp.WithString("hashtags",
+						mcp.Description("Comma-separated hashtags of the cherry-picks, replacing the configured ones"),
+					),
+					mcp.WithBoolean("allow_conflicts",
+						mcp.Description("Create the cherry-pick with conflict markers instead of failing when it doesn't apply cleanly"),
+					),
+				),
+				Handler: h.BackportGerritChange,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("suggest-gerrit-reviewers-by-blame",
+					mcp.WithDescription("Suggest reviewers for a Gerrit change from git blame of the lines it modifies or deletes, ranked by lines written and how recently, and optionally add the best ones"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Number of reviewers to suggest (default 5)"),
+					),
+					mcp.WithNumber("add_top",
+						mcp.Description("Add this many of the suggested reviewers to the change (default 0, only suggest)"),
+					),
+				),
+				Handler: h.SuggestGerritReviewersByBlame,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-tags",
+					mcp.WithDescription("Read your own tags on Gerrit changes, e.g. triage state such as \"needs-deep-review\" kept between sessions: the tags of one change, or the changes carrying a tag. Tags are private star labels, or on Gerrit 3.6 and later hashtags prefixed with your username"),
+					mcp.WithString("change_url",
+						mcp.Description("URL of Gerrit change whose tags to list"),
+					),
+					mcp.WithString("tag",
+						mcp.Description("List the changes carrying this tag instead"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Maximum number of changes to list (default 25)"),
+					),
+				),
+				Handler: h.GetGerritChangeTags,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("set-gerrit-change-tags",
+					mcp.WithDescription("Add or remove your own tags on a Gerrit change, to keep triage state such as \"needs-deep-review\" in Gerrit"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("add",
+						mcp.Description("Comma-separated tags to add; tags can't contain spaces"),
+					),
+					mcp.WithString("remove",
+						mcp.Description("Comma-separated tags to remove"),
+					),
+				),
+				Handler: h.SetGerritChangeTags,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-assignee",
+					mcp.WithDescription("Get the account a Gerrit change is assigned to; only on Gerrit releases before the attention set replaced assignees"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.GetGerritChangeAssignee,
+			},
+			Category: CategoryRead,
+			Requires: Requirement{Before: assigneeRemovedIn},
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("set-gerrit-change-assignee",
+					mcp.WithDescription("Assign a Gerrit change to an account, or remove its assignee; only on Gerrit releases before the attention set replaced assignees"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("assignee",
+						mcp.Description("Account (username, email or ID) to assign the change to; omit to remove the assignee"),
+					),
+				),
+				Handler: h.SetGerritChangeAssignee,
+			},
+			Category: CategoryWrite,
+			Requires: Requirement{Before: assigneeRemovedIn},
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("query-gerrit-changes",
+					mcp.WithDescription("Search Gerrit changes with Gerrit's query syntax, e.g. \"status:open project:foo owner:self\", one page at a time"),
+					mcp.WithString("query",
+						mcp.Required(),
+						mcp.Description("Gerrit change query"),
+					),
+					mcp.WithString("fields",
+						mcp.Description("Comma-separated blocks to include: owner, labels, reviewers, messages, files, commit, submittable; by default changes are listed one line each"),
+					),
+					mcp.WithString("sort",
+						mcp.Description("Order of the results: updated (default), created, size or unresolved_comments, largest or newest first"),
+						mcp.Enum("updated", "created", "size", "unresolved_comments"),
+					),
+					mcp.WithBoolean("ascending",
+						mcp.Description("Sort smallest or oldest first instead"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Number of changes per page (default 25, at most 500)"),
+					),
+					mcp.WithNumber("start",
+						mcp.Description("Number of matching changes to skip, e.g. 50 for the third page of 25 (default 0); ignored with a cursor"),
+					),
+					mcp.WithString("cursor",
+						mcp.Description("next_cursor of the previous page, to continue a listing"),
+					),
+				),
+				Handler: h.QueryGerritChanges,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("check-gerrit-change",
+					mcp.WithDescription("Check cheaply whether a Gerrit change exists and is visible to the current account, returning only its status, project, branch and subject; use it to validate user-supplied links before fetching more"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL or number of Gerrit change"),
+					),
+				),
+				Handler: h.CheckGerritChange,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("check-gerrit-change-submittable",
+					mcp.WithDescription("Check in one call whether a Gerrit change is ready to submit: state, mergeability, submit requirements, unresolved comments and CI status, with a go/no-go verdict and the reasons. Run it before recommending to submit"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+				),
+				Handler: h.CheckGerritChangeSubmittable,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("simulate-gerrit-submit-requirements",
+					mcp.WithDescription("Evaluate the submit requirements of a Gerrit change as if you cast some votes, without voting, and list the votes and conditions still missing for it to become submittable, e.g. to answer \"if I vote Code-Review+2, what's still missing?\". Without votes, shows what is missing now"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("votes",
+						mcp.Description("Comma-separated votes to simulate as yours, e.g. \"Code-Review+2,Verified+1\"; a 0 vote simulates removing yours"),
+					),
+				),
+				Handler: h.SimulateGerritSubmitRequirements,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-ci-failure-log",
+					mcp.WithDescription("Explain why CI voted against a Gerrit change: finds the failing CI report on a patchset, lists the failures and links it reports, and downloads a size-limited excerpt of the failure's log around the lines reporting errors"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithNumber("patchset",
+						mcp.Description("Patchset whose CI results to explain (default: the current patchset)"),
+					),
+					mcp.WithString("link",
+						mcp.Description("Link of a CI message on the change to fetch instead of the failing job's, e.g. a specific log file"),
+					),
+					mcp.WithNumber("max_bytes",
+						mcp.Description("Bytes of the end of the log to search for failures (default 1 MiB, at most 10 MiB)"),
+					),
+					mcp.WithNumber("context",
+						mcp.Description("Lines to show before and after each failure line (default 5)"),
+					),
+					mcp.WithNumber("max_chars",
+						mcp.Description("Maximum size of the excerpt in characters, keeping the last failures (default 8000)"),
+					),
+				),
+				Handler: h.GetGerritCIFailureLog,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-topic-ci-failures",
+					mcp.WithDescription("Cluster the CI failures on the current patchsets of the changes of a topic, or of a change's relation chain, grouping identical failures to tell a failure shared by every change, e.g. a flaky test, from changes broken on their own"),
+					mcp.WithString("topic",
+						mcp.Description("Topic whose changes to examine"),
+					),
+					mcp.WithString("change_url",
+						mcp.Description("URL of a Gerrit change whose topic, or relation chain if it has no topic, to examine instead"),
+					),
+					mcp.WithBoolean("logs",
+						mcp.Description("Read the end of each failing job's log and cluster by its first failure line instead of the CI message (default false)"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Maximum number of changes to examine (default 50)"),
+					),
+				),
+				Handler: h.ClusterGerritCIFailures,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-change-details",
+					mcp.WithDescription("Describe a Gerrit change: status, owner, votes, reviewers, files and more, selectable with fields"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("fields",
+						mcp.Description("Comma-separated blocks to include: owner, labels, reviewers, messages, files, commit, submittable (default owner,labels,reviewers,files)"),
+					),
+				),
+				Handler: h.GetGerritChangeDetails,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("export-gerrit-change",
+					mcp.WithDescription("Export a Gerrit change as one JSON document with a schema_version: metadata, all revisions with their commits and files, votes, messages and comments, for archiving or analytics pipelines"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithBoolean("include_patch",
+						mcp.Description("Also include the patch of the current revision, needed to replay the bundle offline"),
+					),
+				),
+				Handler: h.ExportGerritChange,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("list-gerrit-projects",
+					mcp.WithDescription("List the Gerrit projects visible to the user with their descriptions, one page at a time"),
+					mcp.WithString("match",
+						mcp.Description("Only projects whose name contains this substring"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Number of projects per page (default 25, at most 500)"),
+					),
+					mcp.WithString("cursor",
+						mcp.Description("next_cursor of the previous page, to continue a listing"),
+					),
+				),
+				Handler: h.ListGerritProjects,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("list-gerrit-groups",
+					mcp.WithDescription("List the Gerrit groups visible to the user with their owners and descriptions, one page at a time"),
+					mcp.WithString("match",
+						mcp.Description("Only groups whose name contains this substring"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Number of groups per page (default 25, at most 500)"),
+					),
+					mcp.WithString("cursor",
+						mcp.Description("next_cursor of the previous page, to continue a listing"),
+					),
+				),
+				Handler: h.ListGerritGroups,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("create-gerrit-group",
+					mcp.WithDescription("Create a Gerrit group, e.g. a review group, with an optional owner group and initial members"),
+					mcp.WithString("name",
+						mcp.Required(),
+						mcp.Description("Name of the new group"),
+					),
+					mcp.WithString("description",
+						mcp.Description("Description of the group"),
+					),
+					mcp.WithString("owner",
+						mcp.Description("Name or ID of the group owning the new group; by default the group owns itself"),
+					),
+					mcp.WithBoolean("visible_to_all",
+						mcp.Description("Let all users see the group"),
+					),
+					mcp.WithString("members",
+						mcp.Description("Comma-separated accounts (usernames, emails or IDs) to add as members"),
+					),
+				),
+				Handler: h.CreateGerritGroup,
+			},
+			Category: CategoryAdmin,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("update-gerrit-group-members",
+					mcp.WithDescription("Add and remove members of a Gerrit group"),
+					mcp.WithString("group",
+						mcp.Required(),
+						mcp.Description("Name or ID of the group"),
+					),
+					mcp.WithString("add",
+						mcp.Description("Comma-separated accounts (usernames, emails or IDs) to add"),
+					),
+					mcp.WithString("remove",
+						mcp.Description("Comma-separated accounts to remove"),
+					),
+					mcp.WithBoolean("confirm",
+						mcp.Description("Set to true once the user agreed to remove the members; not needed to only add"),
+					),
+				),
+				Handler: h.UpdateGerritGroupMembers,
+			},
+			Category: CategoryAdmin,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("set-gerrit-group-owner",
+					mcp.WithDescription("Set the group owning a Gerrit group; its members may administrate the group"),
+					mcp.WithString("group",
+						mcp.Required(),
+						mcp.Description("Name or ID of the group"),
+					),
+					mcp.WithString("owner",
+						mcp.Required(),
+						mcp.Description("Name or ID of the new owner group"),
+					),
+					mcp.WithBoolean("confirm",
+						mcp.Description("Set to true once the user agreed to change the owner"),
+					),
+				),
+				Handler: h.SetGerritGroupOwner,
+			},
+			Category: CategoryAdmin,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("edit-gerrit-project-access",
+					mcp.WithDescription("Change one access rule of a Gerrit project through review: uploads a change to the project's refs/meta/config branch editing project.config, which takes effect once submitted. Returns the change and the new project.config"),
+					mcp.WithString("project",
+						mcp.Required(),
+						mcp.Description("Name of the project"),
+					),
+					mcp.WithString("ref",
+						mcp.Required(),
+						mcp.Description("Ref pattern the rule applies to, e.g. refs/heads/* or refs/tags/*, or GLOBAL_CAPABILITIES"),
+					),
+					mcp.WithString("permission",
+						mcp.Required(),
+						mcp.Description("Permission, e.g. read, push, submit or label-Code-Review"),
+					),
+					mcp.WithString("group",
+						mcp.Required(),
+						mcp.Description("Name or ID of the group the rule applies to"),
+					),
+					mcp.WithString("action",
+						mcp.Description("allow (default), deny, block, or remove the group's rule"),
+						mcp.Enum("allow", "deny", "block", "remove"),
+					),
+					mcp.WithString("range",
+						mcp.Description("Range of votes for label permissions, e.g. -2..+2"),
+					),
+					mcp.WithString("subject",
+						mcp.Description("Subject of the change; by default it describes the rule"),
+					),
+				),
+				Handler: h.EditGerritProjectAccess,
+			},
+			Category: CategoryAdmin,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("list-gerrit-account-emails",
+					mcp.WithDescription("List your Gerrit account's email addresses, which is preferred or awaiting confirmation, and your email notification strategy and format"),
+				),
+				Handler: h.ListGerritAccountEmails,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("add-gerrit-account-email",
+					mcp.WithDescription("Add an email address to your Gerrit account; Gerrit emails a confirmation link to it. Only for sessions connected with their own Gerrit credentials"),
+					mcp.WithString("email",
+						mcp.Required(),
+						mcp.Description("The email address to add"),
+					),
+					mcp.WithBoolean("preferred",
+						mcp.Description("Make it the preferred email once confirmed"),
+					),
+					mcp.WithBoolean("confirm",
+						mcp.Description("Set to true once the user agreed to make it the preferred email"),
+					),
+				),
+				Handler: h.AddGerritAccountEmail,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("delete-gerrit-account-email",
+					mcp.WithDescription("Remove an email address from your Gerrit account"),
+					mcp.WithString("email",
+						mcp.Required(),
+						mcp.Description("The email address to remove"),
+					),
+					mcp.WithBoolean("confirm",
+						mcp.Description("Set to true once the user agreed to remove the email"),
+					),
+				),
+				Handler: h.DeleteGerritAccountEmail,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("set-gerrit-email-preferences",
+					mcp.WithDescription("Change your Gerrit email notifications: which events you are emailed about, the email format and your preferred address. Only for sessions connected with their own Gerrit credentials"),
+					mcp.WithString("strategy",
+						mcp.Description("ENABLED for all, CC_ON_OWN_COMMENTS to also get your own comments, ATTENTION_SET_ONLY for changes needing your attention, or DISABLED"),
+						mcp.Enum(emailStrategies...),
+					),
+					mcp.WithString("format",
+						mcp.Description("PLAINTEXT or HTML_PLAINTEXT"),
+						mcp.Enum(emailFormats...),
+					),
+					mcp.WithString("preferred_email",
+						mcp.Description("A confirmed address of your account to make preferred"),
+					),
+					mcp.WithBoolean("confirm",
+						mcp.Description("Set to true once the user agreed to change the preferred email"),
+					),
+				),
+				Handler: h.SetGerritEmailPreferences,
+			},
+			Category: CategoryWrite,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("list-gerrit-ssh-keys",
+					mcp.WithDescription("List the SSH keys of your Gerrit account with their numbers, algorithms and comments"),
+				),
+				Handler: h.ListGerritSSHKeys,
+			},
+			Category: CategoryRead,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("add-gerrit-ssh-key",
+					mcp.WithDescription("Add an SSH public key to your Gerrit account, e.g. to set up git over SSH on a new machine. Only for sessions connected with their own Gerrit credentials"),
+					mcp.WithString("key",
+						mcp.Required(),
+						mcp.Description("The OpenSSH public key, e.g. the contents of ~/.ssh/id_ed25519.pub; never the private key"),
+					),
+					mcp.WithBoolean("confirm",
+						mcp.Description("Set to true once the user agreed to add the key"),
+					),
+				),
+				Handler: h.AddGerritSSHKey,
+			},
+			Category: CategoryWrite,
+			OptIn:    true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("delete-gerrit-ssh-key",
+					mcp.WithDescription("Remove an SSH key from your Gerrit account. Only for sessions connected with their own Gerrit credentials"),
+					mcp.WithNumber("seq",
+						mcp.Required(),
+						mcp.Description("Number of the key, as listed by list-gerrit-ssh-keys"),
+					),
+					mcp.WithBoolean("confirm",
+						mcp.Description("Set to true once the user agreed to delete the key"),
+					),
+				),
+				Handler: h.DeleteGerritSSHKey,
+			},
+			Category: CategoryWrite,
+			OptIn:    true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("generate-gerrit-http-password",
+					mcp.WithDescription("Generate a new HTTP password for your Gerrit account, for the REST API and git over HTTP. The old password stops working and the new one is only shown once. By default the user is pointed to Gerrit's settings to generate it there, so that it stays out of the conversation. Only for sessions connected with their own Gerrit credentials"),
+					mcp.WithBoolean("show_password",
+						mcp.Description("Generate the password here and return it, which puts it in the conversation (default false)"),
+					),
+					mcp.WithBoolean("confirm",
+						mcp.Description("Set to true once the user agreed to replace their password"),
+					),
+				),
+				Handler: h.GenerateGerritHTTPPassword,
+			},
+			Category: CategoryWrite,
+			OptIn:    true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("delete-gerrit-http-password",
+					mcp.WithDescription("Revoke the HTTP password of your Gerrit account. Only for sessions connected with their own Gerrit credentials"),
+					mcp.WithBoolean("confirm",
+						mcp.Description("Set to true once the user agreed to revoke their password"),
+					),
+				),
+				Handler: h.DeleteGerritHTTPPassword,
+			},
+			Category: CategoryWrite,
+			OptIn:    true,
+		},
+		{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-server-status",
+					mcp.WithDescription("Report how this server is connected to Gerrit: connection state and last error, base URL, user, authentication mode, Gerrit version and plugins, tools disabled because the server can't support them, and warnings such as running in anonymous read-only mode"),
+				),
+				Handler: h.GetServerStatus,
+			},
+			Category: CategoryRead,
+		},
+	}
+
+	if len(h.cfg().ReviewTemplates) > 0 {
+		tools = append(tools, Tool{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("apply-gerrit-review-template",
+					mcp.WithDescription("Post one of the team's configured review templates (message and votes) on the current patchset of a Gerrit change"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithString("template",
+						mcp.Required(),
+						mcp.Description("Name of the review template"),
+						mcp.Enum(sortedKeys(h.cfg().ReviewTemplates)...),
+					),
+					mcp.WithString("note",
+						mcp.Description("Text appended to the template's message"),
+					),
+				),
+				Handler: h.ApplyGerritReviewTemplate,
+			},
+			Category: CategoryWrite,
+		})
+	}
+	if len(h.cfg().DefaultReviewers) > 0 {
+		tools = append(tools, Tool{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("apply-gerrit-default-reviewers",
+					mcp.WithDescription("Add the reviewers and CCs configured for a Gerrit change's project and changed files"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithBoolean("dry_run",
+						mcp.Description("Only list the reviewers that would be added"),
+					),
+				),
+				Handler: h.ApplyGerritDefaultReviewers,
+			},
+			Category: CategoryWrite,
+		})
+	}
+	if len(h.cfg().Queries) > 0 {
+		tools = append(tools, Tool{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("run-gerrit-named-query",
+					mcp.WithDescription("Run one of the team's saved Gerrit change queries by name, a page at a time"),
+					mcp.WithString("name",
+						mcp.Required(),
+						mcp.Description("Name of the saved query"),
+						mcp.Enum(sortedKeys(h.cfg().Queries)...),
+					),
+					mcp.WithString("filters",
+						mcp.Description("Extra Gerrit search terms narrowing the saved query, e.g. owner:self"),
+					),
+					mcp.WithString("fields",
+						mcp.Description("Comma-separated blocks to include: owner, labels, reviewers, messages, files, commit, submittable; by default changes are listed one line each"),
+					),
+					mcp.WithString("sort",
+						mcp.Description("Order of the results: updated (default), created, size or unresolved_comments, largest or newest first"),
+						mcp.Enum("updated", "created", "size", "unresolved_comments"),
+					),
+					mcp.WithBoolean("ascending",
+						mcp.Description("Sort smallest or oldest first instead"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Number of changes per page (default 25, at most 500)"),
+					),
+					mcp.WithString("cursor",
+						mcp.Description("next_cursor of the previous page, to continue a listing"),
+					),
+				),
+				Handler: h.RunGerritNamedQuery,
+			},
+			Category: CategoryRead,
+		})
+	}
+	if h.effortLog != nil {
+		tools = append(tools, Tool{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("get-gerrit-review-log",
+					mcp.WithDescription("List the tools used on a Gerrit change and when, to reconstruct what was examined before signing off"),
+					mcp.WithString("change_url",
+						mcp.Required(),
+						mcp.Description("URL of Gerrit change"),
+					),
+					mcp.WithBoolean("all_sessions",
+						mcp.Description("Include the calls of other and past sessions, not just the current one"),
+					),
+				),
+				Handler: h.GetGerritReviewLog,
+			},
+			Category: CategoryRead,
+		})
+	}
+	if h.index != nil {
+		tools = append(tools, Tool{
+			ServerTool: server.ServerTool{
+				Tool: mcp.NewTool("search-gerrit-index",
+					mcp.WithDescription("Search the subjects, commit messages, review messages and comments of the Gerrit changes fetched through this server before, offline; e.g. to find a review where a pattern was discussed"),
+					mcp.WithString("query",
+						mcp.Required(),
+						mcp.Description("Words that must all appear, case-insensitively"),
+					),
+					mcp.WithString("project",
+						mcp.Description("Only search changes of this project"),
+					),
+					mcp.WithNumber("limit",
+						mcp.Description("Maximum number of changes to list (default 20)"),
+					),
+				),
+				Handler: h.SearchGerritIndex,
+			},
+			Category:  CategoryRead,
+			Untrusted: true,
+		})
+	}
+	tools = append(tools, aliasTool(tools, "get-gerrit-change-comments", "list-gerrit-comments"))
+	return withConditionalResults(tools)
+}
+
+// aliasTool returns the tool named name under another name, for clients
+// and prompts that know it by that one
+func aliasTool(tools []Tool, name, alias string) Tool {
+	var t Tool
+	for _, tool := range tools {
+		if tool.Tool.Name == name {
+			t = tool
+		}
+	}
+	t.Tool.Name = alias
+	t.Tool.Description += ". Same as " + name
+	return t
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// treeNode is a directory or file in the file tree of a change
+type treeNode struct {
+	name     string
+	children map[string]*treeNode
+	file     *gerrit.FileInfo
+
+	files             int
+	inserted, deleted int
+}
+
+// buildFileTree arranges the files of a change into directories, summing up
+// the changed lines of everything below each directory
+func buildFileTree(files map[string]gerrit.FileInfo) *treeNode {
+	root := &treeNode{children: make(map[string]*treeNode)}
+	for path, info := range files {
+		node := root
+		parts := strings.Split(path, "/")
+		for i, part := range parts {
+			node.files++
+			node.inserted += info.LinesInserted
+			node.deleted += info.LinesDeleted
+
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{name: part, children: make(map[string]*treeNode)}
+				node.children[part] = child
+			}
+			if i == len(parts)-1 {
+				child.file = &info
+				child.files = 1
+				child.inserted = info.LinesInserted
+				child.deleted = info.LinesDeleted
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+// render writes the children of n, indented by depth. Chains of directories
+// holding nothing but a single directory are collapsed into one line.
+func (n *treeNode) render(b *strings.Builder, depth int) {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	// Directories first, then files, each alphabetically
+	sort.Slice(names, func(i, j int) bool {
+		a, b := n.children[names[i]], n.children[names[j]]
+		if (a.file == nil) != (b.file == nil) {
+			return a.file == nil
+		}
+		return names[i] < names[j]
+	})
+
+	indent := strings.Repeat("  ", depth)
+	for _, name := range names {
+		child := n.children[name]
+		if child.file != nil {
+			status := child.file.Status
+			if status == "" {
+				status = "M"
+			}
+			if child.file.Binary {
+				fmt.Fprintf(b, "%s%s %s (binary)\n", indent, status, name)
+			} else {
+				fmt.Fprintf(b, "%s%s %s +%d -%d\n", indent, status, name, child.inserted, child.deleted)
+			}
+			continue
+		}
+
+		label := name
+		for len(child.children) == 1 {
+			var only *treeNode
+			for _, c := range child.children {
+				only = c
+			}
+			if only.file != nil {
+				break
+			}
+			label += "/" + only.name
+			child = only
+		}
+		fmt.Fprintf(b, "%s%s/ (%s, +%d -%d)\n", indent, label, plural(child.files, "file"), child.inserted, child.deleted)
+		child.render(b, depth+1)
+	}
+}
+
+// GetGerritChangeFileTree renders the files changed by the current patchset
+// as a directory tree with per-directory totals
+func (h *Handler) GetGerritChangeFileTree(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	_, change, header, err := h.lookupChange(ctx, changeURL, "CURRENT_FILES")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	tree := buildFileTree(changedFiles(change))
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	fmt.Fprintf(&b, "%s, +%d -%d lines\n\n", plural(tree.files, "file"), tree.inserted, tree.deleted)
+	tree.render(&b, 0)
+
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// unseenFile is a file of the current patchset a reviewer hasn't looked at
+type unseenFile struct {
+	path   string
+	reason string
+	diff   string
+}
+
+// hunkHeaders returns the "@@ ... @@" lines of a unified diff
+func hunkHeaders(diff string) []string {
+	var headers []string
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "@@ ") {
+			headers = append(headers, line)
+		}
+	}
+	return headers
+}
+
+// GetGerritChangeUnseenDelta combines the files a reviewer marked as reviewed
+// with the last patchset they voted or commented on, and lists exactly the
+// files and hunks of the current patchset they haven't looked at yet
+func (h *Handler) GetGerritChangeUnseenDelta(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	changeURL, err := request.RequireString("change_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	reviewer := strings.TrimSpace(request.GetString("reviewer", "self"))
+	withDiff := request.GetBool("diff", false)
+
+	changeID, change, header, err := h.lookupChange(ctx, changeURL, "MESSAGES", "ALL_REVISIONS")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	current := change.Revisions[change.CurrentRevision].Number
+
+	var account gerrit.AccountInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, "accounts/"+url.PathEscape(reviewer), nil, &account); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to look up account %s: %v", reviewer, err)), nil
+	}
+	var self gerrit.AccountInfo
+	if reviewer != "self" {
+		// Anonymous callers have no reviewed flags at all
+		_, _ = h.client.Call(ctx, http.MethodGet, "accounts/self", nil, &self)
+	}
+	// Gerrit only shows the calling user their own reviewed flags
+	ownFlags := reviewer == "self" || self.AccountID == account.AccountID
+
+	base, _ := lastReviewedPatchSet(change, account.AccountID)
+	if base > current {
+		base = current
+	}
+	_, codeChanged := patchSetKinds(change, base)
+
+	revisionPath := fmt.Sprintf("changes/%s/revisions/%s/files/", url.PathEscape(changeID), change.CurrentRevision)
+	var files map[string]gerrit.FileInfo
+	if _, err := h.client.Call(ctx, http.MethodGet, revisionPath, nil, &files); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list the files of patch set %d: %v", current, err)), nil
+	}
+	delete(files, "/MERGE_LIST")
+
+	var changedSinceBase map[string]gerrit.FileInfo
+	if base > 0 && base < current && codeChanged {
+		if _, err := h.client.Call(ctx, http.MethodGet, fmt.Sprintf("%s?base=%d", revisionPath, base), nil, &changedSinceBase); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to list files changed since patch set %d: %v", base, err)), nil
+		}
+	}
+
+	reviewed := make(map[string]bool)
+	if ownFlags {
+		var paths []string
+		if _, err := h.client.Call(ctx, http.MethodGet, revisionPath+"?reviewed", nil, &paths); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get the files you marked as reviewed: %v", err)), nil
+		}
+		for _, path := range paths {
+			reviewed[path] = true
+		}
+	}
+
+	var unseen []unseenFile
+	var seen []string
+	for _, path := range sortedKeys(files) {
+		escaped := strings.ReplaceAll(url.PathEscape(path), "/", "%2F")
+		switch {
+		case reviewed[path]:
+			seen = append(seen, path+": marked as reviewed")
+			continue
+		case base == current:
+			seen = append(seen, fmt.Sprintf("%s: reviewed in patch set %d", path, base))
+			continue
+		case base > 0 && !codeChanged:
+			seen = append(seen, fmt.Sprintf("%s: only rebased since patch set %d", path, base))
+			continue
+		}
+
+		f := unseenFile{path: path, reason: "never reviewed"}
+		diffPath := fmt.Sprintf("%s%s/diff?context=%d", revisionPath, escaped, diffContext)
+		if base > 0 {
+			if _, ok := changedSinceBase[path]; !ok {
+				seen = append(seen, fmt.Sprintf("%s: unchanged since patch set %d", path, base))
+				continue
+			}
+			f.reason = fmt.Sprintf("changed since patch set %d", base)
+			diffPath = fmt.Sprintf("%s%s/diff?base=%d&context=%d", revisionPath, escaped, base, diffContext)
+		}
+
+		var diff diffInfo
+		if _, err := h.client.Call(ctx, http.MethodGet, diffPath, nil, &diff); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get the diff of %s: %v", path, err)), nil
+		}
+		if added, removed := ownEdits(diff); base > 0 && added == 0 && removed == 0 && !diff.Binary && diff.ChangeType == "MODIFIED" {
+			seen = append(seen, fmt.Sprintf("%s: only changed by a rebase since patch set %d", path, base))
+			continue
+		}
+		if diff.Binary {
+			f.reason += ", binary"
+		} else {
+			f.diff = unifiedDiff(diff, diffContext)
+		}
+		unseen = append(unseen, f)
+	}
+
+	var b strings.Builder
+	if len(header) > 0 {
+		b.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	name := formatAccount(account)
+	switch {
+	case base == 0:
+		fmt.Fprintf(&b, "%s has not voted or commented on change %s yet", name, changeID)
+	default:
+		fmt.Fprintf(&b, "%s last voted or commented on patch set %d of change %s", name, base, changeID)
+	}
+	if ownFlags {
+		fmt.Fprintf(&b, " and marked %s as reviewed in patch set %d\n", plural(len(reviewed), "file"), current)
+	} else {
+		b.WriteString("\nNOTE: Gerrit only shows users their own reviewed flags, so files marked as reviewed are not taken into account\n")
+	}
+
+	if len(unseen) == 0 {
+		fmt.Fprintf(&b, "\n%s has seen everything in patch set %d\n", name, current)
+	} else {
+		fmt.Fprintf(&b, "\nNot seen yet in patch set %d (%s):\n", current, plural(len(unseen), "file"))
+		for _, f := range unseen {
+			headers := hunkHeaders(f.diff)
+			if len(headers) > 0 {
+				fmt.Fprintf(&b, "  %s: %s, %s\n", f.path, f.reason, plural(len(headers), "hunk"))
+			} else {
+				fmt.Fprintf(&b, "  %s: %s\n", f.path, f.reason)
+			}
+			if withDiff {
+				for line := range strings.SplitSeq(strings.TrimRight(f.diff, "\n"), "\n") {
+					if line != "" {
+						fmt.Fprintf(&b, "    %s\n", line)
+					}
+				}
+				continue
+			}
+			for _, hunk := range headers {
+				fmt.Fprintf(&b, "    %s\n", hunk)
+			}
+		}
+	}
+	if len(seen) > 0 {
+		b.WriteString("\nAlready seen:\n")
+		for _, s := range seen {
+			fmt.Fprintf(&b, "  %s\n", s)
+		}
+	}
+	return mcp.NewToolResultText(strings.TrimRight(b.String(), "\n")), nil
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/andygrunwald/go-gerrit"
+)
+
+// changeHost returns the lowercased hostname of a change URL, or "" if the
+// argument has no host (e.g. a bare change number or a path)
+func changeHost(changeURL string) string {
+	u, err := url.Parse(strings.TrimSpace(changeURL))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// isKnownHost reports whether host refers to the configured Gerrit server,
+// either directly or through one of the configured aliases
+func (h *Handler) isKnownHost(host string) bool {
+	if host == "" || h.baseURL == nil {
+		return true
+	}
+	if strings.EqualFold(host, h.baseURL.Hostname()) {
+		return true
+	}
+	return h.hostAliases[host]
+}
+
+// WithAllowedHosts accepts change URLs for hosts besides the base URL's and
+// the aliases, which are looked up on the configured server with a warning.
+// An entry of the form *.example.com allows all subdomains of example.com.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(h *Handler) {
+		for _, host := range hosts {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if host != "" {
+				h.allowedHosts = append(h.allowedHosts, host)
+			}
+		}
+	}
+}
+
+// checkHost returns an error if the host of a change URL is neither the
+// configured server's nor allowed, rather than looking the change up on a
+// server it doesn't belong to
+func (h *Handler) checkHost(changeURL string) error {
+	host := changeHost(changeURL)
+	if h.isKnownHost(host) {
+		return nil
+	}
+	for _, allowed := range h.allowedHosts {
+		if host == allowed {
+			return nil
+		}
+		// A wildcard only matches whole labels: *example.com allows
+		// sub.example.com but not evilexample.com
+		if domain, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasSuffix(host, "."+strings.TrimPrefix(domain, ".")) {
+			return nil
+		}
+	}
+	hosts := append([]string{h.baseURL.Hostname()}, slices.Sorted(maps.Keys(h.hostAliases))...)
+	return fmt.Errorf("host %s is not an allowed Gerrit host; change URLs must point at %s", host, strings.Join(append(hosts, h.allowedHosts...), ", "))
+}
+
+// canonicalChangeURL builds the web URL of a change. Gerrit may redirect the
+// configured base URL (http to https, old hostname to new one), and the HTTP
+// client follows those redirects, so the URL of the request that produced the
+// response is preferred over the configured one.
+func (h *Handler) canonicalChangeURL(change *gerrit.ChangeInfo, resp *gerrit.Response) string {
+	if change == nil || change.Number == 0 {
+		return ""
+	}
+
+	var scheme, host, prefix string
+	switch {
+	case resp != nil && resp.Request != nil && resp.Request.URL != nil:
+		u := resp.Request.URL
+		scheme, host = u.Scheme, u.Host
+		// Strip the REST part of the path, e.g. /r/a/changes/123 -> /r
+		if i := strings.Index(u.Path, "/changes/"); i >= 0 {
+			prefix = strings.TrimSuffix(u.Path[:i], "/a")
+		}
+	case h.baseURL != nil:
+		scheme, host = h.baseURL.Scheme, h.baseURL.Host
+		prefix = strings.TrimSuffix(h.baseURL.Path, "/")
+	default:
+		return ""
+	}
+
+	if change.Project == "" {
+		return fmt.Sprintf("%s://%s%s/c/%d", scheme, host, prefix, change.Number)
+	}
+	return fmt.Sprintf("%s://%s%s/c/%s/+/%d", scheme, host, prefix, change.Project, change.Number)
+}
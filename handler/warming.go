@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+)
+
+// CacheWarming runs named queries on a schedule and downloads the patches of
+// their top results, so that the first review session of the day doesn't wait
+// for them
+type CacheWarming struct {
+	// Schedule is a cron expression in local time: minute, hour, day of
+	// month, month and day of week, e.g. "30 7 * * 1-5"
+	Schedule string `json:"schedule"`
+	// Queries are names of configured queries
+	Queries []string `json:"queries"`
+	// Top is the number of changes warmed per query, 10 by default
+	Top int `json:"top"`
+
+	schedule *cronSchedule
+}
+
+// defaultWarmingTop is the number of changes warmed per query unless configured
+const defaultWarmingTop = 10
+
+func (w *CacheWarming) compile(queries map[string]string) error {
+	schedule, err := parseCronSchedule(w.Schedule)
+	if err != nil {
+		return fmt.Errorf("cache warming schedule: %w", err)
+	}
+	w.schedule = schedule
+	if len(w.Queries) == 0 {
+		return fmt.Errorf("cache warming has no queries")
+	}
+	for _, name := range w.Queries {
+		if _, ok := queries[name]; !ok {
+			return fmt.Errorf("cache warming: unknown query %q", name)
+		}
+	}
+	if w.Top < 0 {
+		return fmt.Errorf("cache warming: top must not be negative")
+	}
+	return nil
+}
+
+// RunCacheWarming warms the cache whenever the configured schedule is due,
+// until ctx is cancelled. The configuration is read at every minute, so
+// reloads take effect without a restart.
+func (h *Handler) RunCacheWarming(ctx context.Context) {
+	for {
+		now := time.Now()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(now.Truncate(time.Minute).Add(time.Minute).Sub(now)):
+		}
+
+		warming := h.cfg().CacheWarming
+		if warming == nil || !warming.schedule.matches(time.Now()) {
+			continue
+		}
+		start := time.Now()
+		warmed, err := h.WarmCache(ctx, warming)
+		if err != nil {
+			log.Printf("Warning: cache warming: %v", err)
+		}
+		log.Printf("Warmed the cache with %d patches in %s", warmed, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// WarmCache runs the queries of warming and fetches the current patch of
+// their top results through the client, which keeps them. It returns the
+// number of patches fetched and the first error met; a failing query or
+// patch doesn't stop the others.
+func (h *Handler) WarmCache(ctx context.Context, warming *CacheWarming) (int, error) {
+	top := warming.Top
+	if top == 0 {
+		top = defaultWarmingTop
+	}
+
+	var firstErr error
+	seen := make(map[string]bool)
+	warmed := 0
+	for _, name := range warming.Queries {
+		changes, _, err := h.client.QueryChanges(ctx, &gerrit.QueryChangeOptions{
+			QueryOptions:  gerrit.QueryOptions{Query: []string{h.cfg().Queries[name]}, Limit: top},
+			ChangeOptions: gerrit.ChangeOptions{AdditionalFields: []string{"CURRENT_REVISION"}},
+		})
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("query %s: %w", name, err)
+			}
+			continue
+		}
+		if changes == nil {
+			continue
+		}
+		for _, change := range *changes {
+			if change.CurrentRevision == "" || seen[change.CurrentRevision] {
+				continue
+			}
+			seen[change.CurrentRevision] = true
+			// Warming is optional, so it stops rather than waits for quota
+			if h.quota != nil && h.quota.delay() > 0 {
+				return warmed, fmt.Errorf("stopped warming to save the request quota")
+			}
+			// Tools look patches up by change number, and so must the cache
+			changeID := strconv.Itoa(change.Number)
+			if _, _, err := h.client.GetPatch(ctx, changeID, change.CurrentRevision, &gerrit.PatchOptions{}); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("patch of %s: %w", changeID, err)
+				}
+				continue
+			}
+			warmed++
+		}
+	}
+	return warmed, firstErr
+}
+
+// cronSchedule is a parsed five-field cron expression
+type cronSchedule struct {
+	minute, hour, dom, month, dow []bool
+	// anyDom and anyDow record a "*" day field: as in cron, when both day
+	// fields are restricted either one matching is enough
+	anyDom, anyDow bool
+}
+
+// parseCronSchedule parses "minute hour day-of-month month day-of-week",
+// where each field is "*" or a list of values and ranges with an optional
+// step, e.g. "*/15", "8-18" or "1,3,5". Day of week 0 and 7 are Sunday.
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	var s cronSchedule
+	var err error
+	for i, f := range []struct {
+		set         *[]bool
+		first, last int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	} {
+		if *f.set, err = parseCronField(fields[i], f.first, f.last); err != nil {
+			return nil, fmt.Errorf("%q: %w", spec, err)
+		}
+	}
+	s.dow[0] = s.dow[0] || s.dow[7]
+	s.anyDom = strings.HasPrefix(fields[2], "*")
+	s.anyDow = strings.HasPrefix(fields[4], "*")
+	return &s, nil
+}
+
+func parseCronField(field string, first, last int) ([]bool, error) {
+	set := make([]bool, last+1)
+	for part := range strings.SplitSeq(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		lo, hi := first, last
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("invalid value in %q", part)
+				}
+			} else if hasStep {
+				hi = last
+			}
+		}
+		if lo < first || hi > last || lo > hi {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, first, last)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// matches reports whether the schedule is due in the minute of t
+func (s *cronSchedule) matches(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	if s.anyDom || s.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}